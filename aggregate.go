@@ -0,0 +1,95 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// aggregate collapses identical error entries into one line per key and window
+type aggregate struct {
+	mu      sync.Mutex
+	window  time.Duration
+	reset   time.Time
+	counts  map[string]int
+	samples map[string]string
+}
+
+func newAggregate(window time.Duration) *aggregate {
+	return &aggregate{
+		window:  window,
+		counts:  make(map[string]int),
+		samples: make(map[string]string),
+	}
+}
+
+// add counts an entry for key and reports whether it is the first one in
+// the current window. Aggregate lines of the previous window are written to w
+func (a *aggregate) add(key, sample string, now time.Time, w summaryWriter) bool {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if now.After(a.reset) {
+		a.flush(w, now)
+		a.reset = now.Add(a.window)
+	}
+	a.counts[key]++
+	if a.counts[key] == 1 {
+		a.samples[key] = sample
+		return true
+	}
+	return false
+}
+
+// tick writes the aggregate lines of a window that elapsed without a later
+// entry, the next entry starts a new window. With all the current
+// one is written, as on Close
+func (a *aggregate) tick(now time.Time, all bool, w summaryWriter) {
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	if all || !a.reset.IsZero() && now.After(a.reset) {
+		a.flush(w, now)
+		a.reset = time.Time{}
+	}
+}
+
+// flush writes one line per key that occurred more than once
+func (a *aggregate) flush(w summaryWriter, now time.Time) {
+	keys := make([]string, 0, len(a.counts))
+	for key, count := range a.counts {
+		if count > 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		e := &Entry{Time: now, Fields: []Field{{"summary", "aggregate"}, {"key", key}, {"count", a.counts[key]}, {"window", a.window.String()}}}
+		message := fmt.Sprintf("%s x%d in last %s", key, a.counts[key], a.window)
+		if sample := a.samples[key]; sample != "" {
+			e.Fields = append(e.Fields, Field{"sample", sample})
+			message += ", sample trace-id=" + sample
+		}
+		w.writeSummary(e, message)
+	}
+	a.counts = make(map[string]int, len(a.counts))
+	a.samples = make(map[string]string, len(a.samples))
+}
+
+// traceID returns the request id or W3C trace id of the request
+func traceID(c *fiber.Ctx) string {
+	if id := RequestID(c); id != "" {
+		return id
+	}
+	if id := c.Get(fiber.HeaderXRequestID); id != "" {
+		return id
+	}
+	// traceparent: version-traceid-parentid-flags
+	parts := strings.Split(c.Get("traceparent"), "-")
+	if len(parts) == 4 {
+		return parts[1]
+	}
+	return ""
+}
@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAggregate_add(t *testing.T) {
+	buf := &strings.Builder{}
+	a := newAggregate(time.Minute)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i, id := range []string{"abc", "def", "ghi"} {
+		if first := a.add("/api/foo 502", id, now, textSummaries{buf, "15:04:05"}); first != (i == 0) {
+			t.Errorf("Has: %t, expected: %t", first, i == 0)
+		}
+	}
+	a.add("/api/foo 502", "", now.Add(time.Minute+time.Second), textSummaries{buf, "15:04:05"})
+
+	expectedOutput := "12:01:01 /api/foo 502 x3 in last 1m0s, sample trace-id=abc\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"net"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// AnalyticsFormat logs a privacy reduced schema for product analytics: no
+// full client address, user agent or referer
+const AnalyticsFormat = "${time} ${ipAnon} ${uaFamily} ${lang} ${refererHost} ${method} ${path} ${status} ${latencyBucket}\n"
+
+// anonIP zeroes the host part of ip, keeping the /24 of IPv4 and the /48 of
+// IPv6 addresses
+func anonIP(ip net.IP) string {
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return v4.Mask(net.CIDRMask(24, 32)).String()
+	}
+	return ip.Mask(net.CIDRMask(48, 128)).String()
+}
+
+// botTokens are user agent substrings of crawlers and scripted clients
+var botTokens = []string{"bot", "crawl", "spider", "slurp", "curl/", "wget/", "python-", "go-http-client", "headless"}
+
+func isBot(ua string) bool {
+	ua = strings.ToLower(ua)
+	for _, token := range botTokens {
+		if strings.Contains(ua, token) {
+			return true
+		}
+	}
+	return false
+}
+
+// uaFamily reduces a user agent to its browser family, the order matters as
+// most browsers claim to be others too
+func uaFamily(ua string) string {
+	switch {
+	case ua == "":
+		return ""
+	case isBot(ua):
+		return "Bot"
+	case strings.Contains(ua, "Edg/"), strings.Contains(ua, "Edge/"):
+		return "Edge"
+	case strings.Contains(ua, "OPR/"), strings.Contains(ua, "Opera"):
+		return "Opera"
+	case strings.Contains(ua, "Firefox/"), strings.Contains(ua, "FxiOS/"):
+		return "Firefox"
+	case strings.Contains(ua, "Chrome/"), strings.Contains(ua, "CriOS/"):
+		return "Chrome"
+	case strings.Contains(ua, "Safari/"):
+		return "Safari"
+	case strings.Contains(ua, "MSIE "), strings.Contains(ua, "Trident/"):
+		return "IE"
+	}
+	return "Other"
+}
+
+// refererHost is the host of the referer without path and query
+func refererHost(referer string) string {
+	u, err := url.Parse(referer)
+	if err != nil {
+		return ""
+	}
+	return u.Hostname()
+}
+
+// latencyBuckets are the upper bounds ${latencyBucket} reports
+var latencyBuckets = []time.Duration{
+	10 * time.Millisecond, 50 * time.Millisecond, 100 * time.Millisecond,
+	250 * time.Millisecond, 500 * time.Millisecond, time.Second, 5 * time.Second,
+}
+
+func latencyBucket(latency time.Duration) string {
+	for _, b := range latencyBuckets {
+		if latency < b {
+			return "<" + b.String()
+		}
+	}
+	return ">=" + latencyBuckets[len(latencyBuckets)-1].String()
+}
+
+// lang is the primary language of the first preferred language in an
+// Accept-Language header, e.g. "de" for "de-CH,de;q=0.9,en;q=0.8"
+func lang(acceptLanguage string) string {
+	first := acceptLanguage
+	if i := strings.IndexAny(first, ",;"); i >= 0 {
+		first = first[:i]
+	}
+	if i := strings.IndexByte(first, '-'); i >= 0 {
+		first = first[:i]
+	}
+	first = strings.ToLower(strings.TrimSpace(first))
+	if first == "*" {
+		return ""
+	}
+	return first
+}
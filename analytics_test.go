@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAnonIP(t *testing.T) {
+	for ip, expected := range map[string]string{
+		"203.0.113.77":               "203.0.113.0",
+		"2001:db8:85a3:8d3:1319::73": "2001:db8:85a3::",
+	} {
+		if s := anonIP(net.ParseIP(ip)); s != expected {
+			t.Errorf("Has: %s, expected: %s", s, expected)
+		}
+	}
+}
+
+func TestUAFamily(t *testing.T) {
+	for ua, expected := range map[string]string{
+		"Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36":                 "Chrome",
+		"Mozilla/5.0 (Windows NT 10.0) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/83.0.4103.116 Safari/537.36 Edg/83.0.478":    "Edge",
+		"Mozilla/5.0 (Macintosh; Intel Mac OS X 10_15_5) AppleWebKit/605.1.15 (KHTML, like Gecko) Version/13.1.1 Safari/605.1.15": "Safari",
+		"Mozilla/5.0 (X11; Linux x86_64; rv:78.0) Gecko/20100101 Firefox/78.0":                                                    "Firefox",
+		"Mozilla/5.0 (compatible; Googlebot/2.1; +http://www.google.com/bot.html)":                                                "Bot",
+		"curl/7.68.0": "Bot",
+		"":            "",
+	} {
+		if s := uaFamily(ua); s != expected {
+			t.Errorf("Has: %s, expected: %s", s, expected)
+		}
+	}
+}
+
+func TestAnalyticsTags(t *testing.T) {
+	if s := refererHost("https://news.example.com/item?id=1"); s != "news.example.com" {
+		t.Errorf("Has: %s, expected: news.example.com", s)
+	}
+	if s := latencyBucket(120 * time.Millisecond); s != "<250ms" {
+		t.Errorf("Has: %s, expected: <250ms", s)
+	}
+	if s := latencyBucket(time.Minute); s != ">=5s" {
+		t.Errorf("Has: %s, expected: >=5s", s)
+	}
+	if s := lang("de-CH,de;q=0.9,en;q=0.8"); s != "de" {
+		t.Errorf("Has: %s, expected: de", s)
+	}
+}
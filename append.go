@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// appendInt writes n to buf without an intermediate string
+func appendInt(buf *bytebufferpool.ByteBuffer, n int) (int, error) {
+	from := len(buf.B)
+	buf.B = strconv.AppendInt(buf.B, int64(n), 10)
+	return len(buf.B) - from, nil
+}
+
+// appendDuration writes d to buf like d.String(), without an intermediate
+// string
+func appendDuration(buf *bytebufferpool.ByteBuffer, d time.Duration) (int, error) {
+	// Largest value is "-2562047h47m16.854775808s"
+	var b [32]byte
+	w := len(b)
+	u := uint64(d)
+	neg := d < 0
+	if neg {
+		u = -u
+	}
+	if u < uint64(time.Second) {
+		// Less than a second is printed with a smaller unit, like "1.2ms"
+		var prec int
+		w--
+		b[w] = 's'
+		w--
+		switch {
+		case u == 0:
+			return buf.WriteString("0s")
+		case u < uint64(time.Microsecond):
+			b[w] = 'n'
+		case u < uint64(time.Millisecond):
+			prec = 3
+			// 'µ' takes two bytes
+			w--
+			copy(b[w:], "µ")
+		default:
+			prec = 6
+			b[w] = 'm'
+		}
+		w, u = appendFrac(b[:w], u, prec)
+		w = appendUint(b[:w], u)
+	} else {
+		w--
+		b[w] = 's'
+		w, u = appendFrac(b[:w], u, 9)
+		w = appendUint(b[:w], u%60)
+		u /= 60
+		if u > 0 {
+			w--
+			b[w] = 'm'
+			w = appendUint(b[:w], u%60)
+			u /= 60
+			if u > 0 {
+				w--
+				b[w] = 'h'
+				w = appendUint(b[:w], u)
+			}
+		}
+	}
+	if neg {
+		w--
+		b[w] = '-'
+	}
+	return buf.Write(b[w:])
+}
+
+// appendFrac writes the fraction of v/10^prec to the end of b, omitting
+// trailing zeros and the dot if the fraction is 0. It returns the index
+// where the output starts and v/10^prec
+func appendFrac(b []byte, v uint64, prec int) (int, uint64) {
+	w := len(b)
+	print := false
+	for i := 0; i < prec; i++ {
+		digit := v % 10
+		print = print || digit != 0
+		if print {
+			w--
+			b[w] = byte(digit) + '0'
+		}
+		v /= 10
+	}
+	if print {
+		w--
+		b[w] = '.'
+	}
+	return w, v
+}
+
+// appendUint writes v to the end of b and returns the index where the
+// output starts
+func appendUint(b []byte, v uint64) int {
+	w := len(b)
+	if v == 0 {
+		w--
+		b[w] = '0'
+		return w
+	}
+	for v > 0 {
+		w--
+		b[w] = byte(v%10) + '0'
+		v /= 10
+	}
+	return w
+}
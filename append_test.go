@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"math"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+func TestAppendDuration(t *testing.T) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	for _, d := range []time.Duration{
+		0, 1, 999, time.Microsecond, 1500 * time.Nanosecond, time.Millisecond, 1234567,
+		time.Second, 90 * time.Second, time.Hour + time.Nanosecond, 100 * time.Hour,
+		-1, -1500 * time.Millisecond, math.MaxInt64, math.MinInt64,
+	} {
+		buf.Reset()
+		n, _ := appendDuration(buf, d)
+		if buf.String() != d.String() || n != buf.Len() {
+			t.Errorf("Has: %s (%d), expected: %s", buf.String(), n, d.String())
+		}
+	}
+}
+
+func TestAppendInt(t *testing.T) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	for _, i := range []int{0, 7, -42, 200, math.MaxInt32} {
+		buf.Reset()
+		n, _ := appendInt(buf, i)
+		if buf.String() != strconv.Itoa(i) || n != buf.Len() {
+			t.Errorf("Has: %s (%d), expected: %d", buf.String(), n, i)
+		}
+	}
+}
@@ -0,0 +1,322 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errArchiverClosed is passed to OnError for files archived after Close
+var errArchiverClosed = errors.New("logger: archiver is closed")
+
+// ArchiveStore stores archived files, an adapter around the client of a
+// storage service, or one of S3Store and AzureBlobStore
+type ArchiveStore interface {
+	// Upload stores the size bytes of body under key
+	Upload(key string, body io.Reader, size int64) error
+}
+
+// ArchiveConfig configures an Archiver
+type ArchiveConfig struct {
+	// Store the files are uploaded to
+	// Required
+	Store ArchiveStore
+	// Prefix is put before the file names to form keys, ${host} is replaced
+	// with the hostname and ${date} with the date of archiving as
+	// 2006/01/02, e.g. "access/${host}/${date}/"
+	// Optional. Default: ""
+	Prefix string
+	// Manifest uploads an ArchiveManifest of every file next to it, under
+	// its key with ".manifest.json" appended
+	// Optional. Default: false
+	Manifest bool
+	// KeepLocal keeps uploaded files, leaving them to the MaxBackups and
+	// MaxAge of the FileWriter
+	// Optional. Default: false (removed once uploaded)
+	KeepLocal bool
+	// Retries is how often a failed upload is retried
+	// Optional. Default: 5
+	Retries int
+	// MinBackoff is the wait before the first retry, doubled per retry
+	// Optional. Default: 1 * time.Second
+	MinBackoff time.Duration
+	// MaxBackoff is the longest wait between retries
+	// Optional. Default: 1 * time.Minute
+	MaxBackoff time.Duration
+	// OnError is called when a file could not be compressed or uploaded,
+	// it is left on disk
+	// Optional. Default: nil
+	OnError func(path string, err error)
+}
+
+// Archiver compresses rotated files with gzip and uploads them to a store,
+// retrying with exponential backoff, then removes the local copies. Set
+// Archive as the OnRotate of a FileConfig
+type Archiver struct {
+	cfg    ArchiveConfig
+	host   string
+	mu     sync.Mutex
+	closed bool
+	wg     sync.WaitGroup
+	done   chan struct{}
+}
+
+// NewArchiver creates an Archiver uploading to the store of cfg
+func NewArchiver(cfg ArchiveConfig) (*Archiver, error) {
+	if cfg.Store == nil {
+		return nil, errors.New("logger: archive store is required")
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = 5
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = time.Second
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = time.Minute
+	}
+	host, _ := os.Hostname()
+	return &Archiver{cfg: cfg, host: host, done: make(chan struct{})}, nil
+}
+
+// Archive compresses path unless it ends with .gz, uploads it and removes
+// it unless KeepLocal. It returns once the file is archived or failed
+func (a *Archiver) Archive(path string) {
+	a.mu.Lock()
+	if a.closed {
+		a.mu.Unlock()
+		a.fail(path, errArchiverClosed)
+		return
+	}
+	a.wg.Add(1)
+	a.mu.Unlock()
+	defer a.wg.Done()
+	if err := a.archive(path, time.Now()); err != nil {
+		a.fail(path, err)
+	}
+}
+
+func (a *Archiver) fail(path string, err error) {
+	if a.cfg.OnError != nil {
+		a.cfg.OnError(path, err)
+	}
+}
+
+func (a *Archiver) archive(path string, now time.Time) error {
+	if !strings.HasSuffix(path, ".gz") {
+		if err := gzipFile(path, path+".gz"); err != nil {
+			return err
+		}
+		if err := os.Remove(path); err != nil {
+			return err
+		}
+		path += ".gz"
+	}
+	key := strings.NewReplacer("${host}", a.host, "${date}", now.Format("2006/01/02")).Replace(a.cfg.Prefix) + filepath.Base(path)
+	if err := a.upload(key, path); err != nil {
+		return err
+	}
+	if a.cfg.Manifest {
+		if err := a.uploadManifest(key, path, now); err != nil {
+			return err
+		}
+	}
+	if a.cfg.KeepLocal {
+		return nil
+	}
+	return os.Remove(path)
+}
+
+// upload uploads the file at path to key, retrying with exponential
+// backoff. Retries stop on Close
+func (a *Archiver) upload(key, path string) error {
+	backoff := a.cfg.MinBackoff
+	for retry := 0; ; retry++ {
+		err := a.put(key, path)
+		if err == nil || retry == a.cfg.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-a.done:
+			return err
+		}
+		if backoff *= 2; backoff > a.cfg.MaxBackoff {
+			backoff = a.cfg.MaxBackoff
+		}
+	}
+}
+
+func (a *Archiver) put(key, path string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	return a.cfg.Store.Upload(key, f, info.Size())
+}
+
+// ArchiveManifest describes an archived file for integrity checks
+type ArchiveManifest struct {
+	// File is the name of the file
+	File string `json:"file"`
+	// Key is the key the file was uploaded under
+	Key string `json:"key"`
+	// Size is the size of the uploaded file in bytes
+	Size int64 `json:"size"`
+	// SHA256 is the hex SHA-256 of the uploaded file
+	SHA256 string `json:"sha256"`
+	// Lines is the number of lines in the uncompressed file
+	Lines int64 `json:"lines"`
+	// Archived is when the file was archived
+	Archived time.Time `json:"archived"`
+}
+
+// Verify checks that the uploaded file read from r matches the manifest
+func (m ArchiveManifest) Verify(r io.Reader) error {
+	size, sum, lines, err := digestArchive(r)
+	if err != nil {
+		return err
+	}
+	switch {
+	case size != m.Size:
+		return fmt.Errorf("logger: %s has %d bytes, manifest %d", m.File, size, m.Size)
+	case sum != m.SHA256:
+		return fmt.Errorf("logger: %s has SHA-256 %s, manifest %s", m.File, sum, m.SHA256)
+	case lines != m.Lines:
+		return fmt.Errorf("logger: %s has %d lines, manifest %d", m.File, lines, m.Lines)
+	}
+	return nil
+}
+
+// digestArchive returns the size and SHA-256 of a gzip file and the lines
+// of its content
+func digestArchive(r io.Reader) (size int64, sum string, lines int64, err error) {
+	h := sha256.New()
+	counted := &countingReader{r: io.TeeReader(r, h)}
+	zr, err := gzip.NewReader(counted)
+	if err != nil {
+		return 0, "", 0, err
+	}
+	buf := make([]byte, 32<<10)
+	for {
+		n, err := zr.Read(buf)
+		lines += int64(bytes.Count(buf[:n], []byte{'\n'}))
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return 0, "", 0, err
+		}
+	}
+	// Hash trailing bytes the gzip reader left unread
+	if _, err := io.Copy(ioutil.Discard, counted); err != nil {
+		return 0, "", 0, err
+	}
+	return counted.n, hex.EncodeToString(h.Sum(nil)), lines, nil
+}
+
+// countingReader counts the bytes read from r
+type countingReader struct {
+	r io.Reader
+	n int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	c.n += int64(n)
+	return n, err
+}
+
+// uploadManifest uploads the manifest of the file at path uploaded to key
+func (a *Archiver) uploadManifest(key, path string, now time.Time) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	size, sum, lines, err := digestArchive(f)
+	f.Close()
+	if err != nil {
+		return err
+	}
+	m, _ := json.Marshal(ArchiveManifest{
+		File:     filepath.Base(path),
+		Key:      key,
+		Size:     size,
+		SHA256:   sum,
+		Lines:    lines,
+		Archived: now.UTC(),
+	})
+	backoff := a.cfg.MinBackoff
+	for retry := 0; ; retry++ {
+		err := a.cfg.Store.Upload(key+".manifest.json", bytes.NewReader(m), int64(len(m)))
+		if err == nil || retry == a.cfg.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-a.done:
+			return err
+		}
+		if backoff *= 2; backoff > a.cfg.MaxBackoff {
+			backoff = a.cfg.MaxBackoff
+		}
+	}
+}
+
+// gzipFile compresses src to dst, which only appears once it is complete
+func gzipFile(src, dst string) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst+".tmp", os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	zw := gzip.NewWriter(out)
+	zw.Name = filepath.Base(src)
+	_, err = io.Copy(zw, in)
+	if cerr := zw.Close(); err == nil {
+		err = cerr
+	}
+	if serr := out.Sync(); err == nil {
+		err = serr
+	}
+	if cerr := out.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		os.Remove(dst + ".tmp")
+		return err
+	}
+	return os.Rename(dst+".tmp", dst)
+}
+
+// Close stops retries and waits for the files being archived
+func (a *Archiver) Close() error {
+	a.mu.Lock()
+	if !a.closed {
+		a.closed = true
+		close(a.done)
+	}
+	a.mu.Unlock()
+	a.wg.Wait()
+	return nil
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// memStore keeps uploads in memory, failing the first fails of them
+type memStore struct {
+	mu      sync.Mutex
+	fails   int
+	uploads map[string][]byte
+}
+
+func (s *memStore) Upload(key string, body io.Reader, size int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.fails > 0 {
+		s.fails--
+		return errors.New("unavailable")
+	}
+	b, err := ioutil.ReadAll(body)
+	if err != nil || int64(len(b)) != size {
+		return errors.New("short body")
+	}
+	if s.uploads == nil {
+		s.uploads = make(map[string][]byte)
+	}
+	s.uploads[key] = b
+	return nil
+}
+
+func gunzip(t *testing.T, b []byte) string {
+	zr, err := gzip.NewReader(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := ioutil.ReadAll(zr)
+	return string(data)
+}
+
+func TestArchiver(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access-2024-05-01T00-00-00.000.log")
+	ioutil.WriteFile(path, []byte("GET / 200\n"), 0644)
+
+	store := &memStore{fails: 1}
+	a, err := NewArchiver(ArchiveConfig{Store: store, Prefix: "access/${host}/", MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	a.Archive(path)
+	a.Close()
+
+	host, _ := os.Hostname()
+	b, ok := store.uploads["access/"+host+"/access-2024-05-01T00-00-00.000.log.gz"]
+	if !ok {
+		t.Fatalf("Has: %v, expected: the compressed file under the prefix", store.uploads)
+	}
+	if data := gunzip(t, b); data != "GET / 200\n" {
+		t.Errorf("Has: %q, expected: the file", data)
+	}
+	if files, _ := ioutil.ReadDir(dir); len(files) != 0 {
+		t.Errorf("Has: %d files, expected: the local copies removed", len(files))
+	}
+}
+
+func TestArchiver_failed(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access-2024-05-01T00-00-00.000.log")
+	ioutil.WriteFile(path, []byte("GET / 200\n"), 0644)
+
+	var failed string
+	a, _ := NewArchiver(ArchiveConfig{
+		Store:      &memStore{fails: 10},
+		Retries:    2,
+		MinBackoff: time.Millisecond,
+		OnError:    func(path string, err error) { failed = path },
+	})
+	a.Archive(path)
+	a.Close()
+
+	if failed != path {
+		t.Errorf("Has: %q, expected: OnError for %s", failed, path)
+	}
+	if _, err := os.Stat(path + ".gz"); err != nil {
+		t.Errorf("Has: %v, expected: the compressed file kept", err)
+	}
+	a.Archive(path + ".gz")
+	if !strings.HasSuffix(failed, ".gz") {
+		t.Errorf("Has: %q, expected: OnError after Close", failed)
+	}
+}
+
+func TestArchiver_KeepLocal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	store := &memStore{}
+	a, _ := NewArchiver(ArchiveConfig{Store: store, KeepLocal: true})
+	fw, err := NewFileWriter(FileConfig{Filename: filepath.Join(dir, "access.log"), OnRotate: a.Archive})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	fw.Write([]byte("GET / 200\n"))
+	fw.Rotate()
+	fw.Close()
+	// Wait for OnRotate to start archiving
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		store.mu.Lock()
+		n := len(store.uploads)
+		store.mu.Unlock()
+		if n == 1 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	a.Close()
+
+	if len(store.uploads) != 1 {
+		t.Errorf("Has: %d uploads, expected: the rotated file", len(store.uploads))
+	}
+	if matches, _ := filepath.Glob(filepath.Join(dir, "access-*.log.gz")); len(matches) != 1 {
+		t.Errorf("Has: %v, expected: the compressed file kept", matches)
+	}
+}
+
+func TestArchiver_Manifest(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access-2024-05-01T00-00-00.000.log")
+	ioutil.WriteFile(path, []byte("GET / 200\nGET /users 404\n"), 0644)
+
+	store := &memStore{}
+	a, _ := NewArchiver(ArchiveConfig{Store: store, Manifest: true})
+	a.Archive(path)
+	a.Close()
+
+	segment := store.uploads["access-2024-05-01T00-00-00.000.log.gz"]
+	var m ArchiveManifest
+	if err := json.Unmarshal(store.uploads["access-2024-05-01T00-00-00.000.log.gz.manifest.json"], &m); err != nil {
+		t.Fatalf("Has: %v, expected: a manifest next to the file", err)
+	}
+	sum := sha256.Sum256(segment)
+	if m.File != "access-2024-05-01T00-00-00.000.log.gz" || m.Lines != 2 || m.Size != int64(len(segment)) || m.SHA256 != hex.EncodeToString(sum[:]) {
+		t.Errorf("Has: %+v, expected: the name, lines, size and SHA-256 of the file", m)
+	}
+	if err := m.Verify(bytes.NewReader(segment)); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	segment[len(segment)-1] ^= 1
+	if err := m.Verify(bytes.NewReader(segment)); err == nil {
+		t.Errorf("Has: nil, expected: an error for a modified file")
+	}
+}
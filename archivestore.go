@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// S3Config configures an S3Store
+type S3Config struct {
+	// Bucket the files are uploaded to
+	// Required
+	Bucket string
+	// Region of the bucket
+	// Optional. Default: AWS_REGION or AWS_DEFAULT_REGION, "auto" with an
+	// Endpoint
+	Region string
+	// AccessKeyID, SecretAccessKey and SessionToken sign the requests
+	// Optional. Default: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN, else the credentials of the ECS task role
+	AccessKeyID, SecretAccessKey, SessionToken string
+	// Endpoint of an S3 compatible service, objects are addressed by path,
+	// e.g. "https://storage.googleapis.com" for GCS with HMAC keys
+	// Optional. Default: "https://<Bucket>.s3.<Region>.amazonaws.com"
+	Endpoint string
+	// StorageClass of the objects, e.g. "STANDARD_IA"
+	// Optional. Default: ""
+	StorageClass string
+	// Client sends the requests
+	// Optional. Default: a client with a 10 minute timeout
+	Client *http.Client
+}
+
+// S3Store uploads files to an S3 bucket, or to GCS and other services with
+// an S3 compatible API, with single PUT requests of up to 5 GB
+type S3Store struct {
+	cfg   S3Config
+	base  string
+	creds *awsCredentialSource
+}
+
+// NewS3Store creates a store for the bucket of cfg
+func NewS3Store(cfg S3Config) (*S3Store, error) {
+	if cfg.Bucket == "" {
+		return nil, errors.New("logger: s3 bucket is required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = awsRegion()
+	}
+	if cfg.Region == "" && cfg.Endpoint != "" {
+		cfg.Region = "auto"
+	}
+	if cfg.Region == "" {
+		return nil, errors.New("logger: s3 region is required")
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Minute}
+	}
+	base := "https://" + cfg.Bucket + ".s3." + cfg.Region + ".amazonaws.com"
+	if cfg.Endpoint != "" {
+		base = strings.TrimRight(cfg.Endpoint, "/") + "/" + cfg.Bucket
+	}
+	creds, err := newAWSCredentialSource(cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken, cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+	return &S3Store{cfg: cfg, base: base, creds: creds}, nil
+}
+
+// Upload puts body as the object key
+func (s *S3Store) Upload(key string, body io.Reader, size int64) error {
+	req, err := http.NewRequest(http.MethodPut, s.base+(&url.URL{Path: "/" + key}).EscapedPath(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("X-Amz-Content-Sha256", "UNSIGNED-PAYLOAD")
+	if s.cfg.StorageClass != "" {
+		req.Header.Set("X-Amz-Storage-Class", s.cfg.StorageClass)
+	}
+	creds, err := s.creds.get()
+	if err != nil {
+		return err
+	}
+	signAWS(req, nil, creds, s.cfg.Region, "s3", time.Now())
+	return uploadResponse(s.cfg.Client.Do(req))
+}
+
+// AzureBlobConfig configures an AzureBlobStore
+type AzureBlobConfig struct {
+	// ContainerURL is the URL of the container with a SAS token allowing
+	// to create blobs, e.g.
+	// "https://account.blob.core.windows.net/logs?sv=2022-11-02&sp=cw&sig=..."
+	// Required
+	ContainerURL string
+	// AccessTier of the blobs, e.g. "Cool" or "Archive"
+	// Optional. Default: ""
+	AccessTier string
+	// Client sends the requests
+	// Optional. Default: a client with a 10 minute timeout
+	Client *http.Client
+}
+
+// AzureBlobStore uploads files as block blobs of up to 5000 MiB to an Azure
+// Blob Storage container
+type AzureBlobStore struct {
+	cfg       AzureBlobConfig
+	container *url.URL
+}
+
+// NewAzureBlobStore creates a store for the container of cfg
+func NewAzureBlobStore(cfg AzureBlobConfig) (*AzureBlobStore, error) {
+	if cfg.ContainerURL == "" {
+		return nil, errors.New("logger: azure container URL is required")
+	}
+	container, err := url.Parse(cfg.ContainerURL)
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Minute}
+	}
+	return &AzureBlobStore{cfg: cfg, container: container}, nil
+}
+
+// Upload puts body as the blob key
+func (s *AzureBlobStore) Upload(key string, body io.Reader, size int64) error {
+	u := *s.container
+	u.Path = strings.TrimRight(u.Path, "/") + "/" + key
+	req, err := http.NewRequest(http.MethodPut, u.String(), body)
+	if err != nil {
+		return err
+	}
+	req.ContentLength = size
+	req.Header.Set("X-Ms-Blob-Type", "BlockBlob")
+	req.Header.Set("X-Ms-Version", "2020-10-02")
+	if s.cfg.AccessTier != "" {
+		req.Header.Set("X-Ms-Access-Tier", s.cfg.AccessTier)
+	}
+	return uploadResponse(s.cfg.Client.Do(req))
+}
+
+// uploadResponse returns the error of an upload request
+func uploadResponse(resp *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return nil
+	}
+	return fmt.Errorf("logger: upload: %s: %s", resp.Status, bytes.TrimSpace(msg))
+}
@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// uploadServer records the last PUT request and its body
+func uploadServer(t *testing.T, status int) (*httptest.Server, *http.Request, *string) {
+	var req http.Request
+	var body string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		b, _ := ioutil.ReadAll(r.Body)
+		req, body = *r, string(b)
+		w.WriteHeader(status)
+	}))
+	return server, &req, &body
+}
+
+func TestS3Store(t *testing.T) {
+	server, req, body := uploadServer(t, http.StatusOK)
+	defer server.Close()
+	s, err := NewS3Store(S3Config{Bucket: "logs", Region: "auto", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret", StorageClass: "STANDARD_IA"})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	if err := s.Upload("access/web-1/access.log.gz", strings.NewReader("data"), 4); err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	if req.Method != http.MethodPut || req.URL.Path != "/logs/access/web-1/access.log.gz" || *body != "data" {
+		t.Errorf("Has: %s %s %q, expected: a PUT of the object", req.Method, req.URL.Path, *body)
+	}
+	auth := req.Header.Get("Authorization")
+	if !strings.Contains(auth, "/auto/s3/aws4_request") || !strings.Contains(auth, "x-amz-storage-class") ||
+		req.Header.Get("X-Amz-Content-Sha256") != "UNSIGNED-PAYLOAD" {
+		t.Errorf("Has: %v, expected: a signed request", req.Header)
+	}
+}
+
+func TestAzureBlobStore(t *testing.T) {
+	server, req, body := uploadServer(t, http.StatusCreated)
+	defer server.Close()
+	s, err := NewAzureBlobStore(AzureBlobConfig{ContainerURL: server.URL + "/logs?sv=2022-11-02&sig=abc"})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	if err := s.Upload("access.log.gz", strings.NewReader("data"), 4); err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	if req.URL.Path != "/logs/access.log.gz" || req.URL.Query().Get("sig") != "abc" || *body != "data" {
+		t.Errorf("Has: %s?%s %q, expected: the blob with the SAS token", req.URL.Path, req.URL.RawQuery, *body)
+	}
+	if req.Header.Get("X-Ms-Blob-Type") != "BlockBlob" {
+		t.Errorf("Has: %v, expected: a block blob", req.Header)
+	}
+}
+
+func TestAzureBlobStore_error(t *testing.T) {
+	server, _, _ := uploadServer(t, http.StatusForbidden)
+	defer server.Close()
+	s, _ := NewAzureBlobStore(AzureBlobConfig{ContainerURL: server.URL + "/logs"})
+	if err := s.Upload("access.log.gz", strings.NewReader("data"), 4); err == nil {
+		t.Errorf("Has: nil, expected: an error for 403")
+	}
+}
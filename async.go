@@ -0,0 +1,148 @@
+package logger
+
+import (
+	"io"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// OverflowPolicy is what Async does with an entry while the queue is full
+type OverflowPolicy int
+
+// Overflow policies
+const (
+	// OverflowBlock makes the request wait for room, no entry is lost
+	OverflowBlock OverflowPolicy = iota
+	// OverflowDropNewest drops the newest queued entry of a lower priority
+	// than the one of the request, else the entry of the request
+	OverflowDropNewest
+	// OverflowDropOldest drops the oldest queued entry of the lowest
+	// priority for the one of the request, keeping the most recent entries
+	// of a priority
+	OverflowDropOldest
+)
+
+// async is the background writer of Config.Async
+type async struct {
+	queue queue
+	// order serializes numbering and queueing of entries with ${seq}, so
+	// they are written in the order of their numbers
+	order sync.Mutex
+	// mu guards closed, held for reading while pushing so no entry is
+	// pushed after the queue was closed
+	mu      sync.RWMutex
+	closed  bool
+	drained chan struct{}
+	// reported is the time of the last diagnostic about dropped entries in
+	// Unix nanoseconds
+	reported int64
+}
+
+// startAsync starts the background writer of Output. The drop policies
+// take the classQueue, which evicts entries of the lowest priority first
+func (l *Logger) startAsync(size int, policy OverflowPolicy) {
+	q := newQueue(size)
+	if policy == OverflowDropNewest || policy == OverflowDropOldest {
+		q = newClassQueue(size)
+	}
+	l.async = &async{queue: q, drained: make(chan struct{})}
+	go l.drain()
+}
+
+// drain writes the queued entries to Output until the queue is closed
+func (l *Logger) drain() {
+	defer close(l.async.drained)
+	for b := l.async.queue.pop(); b != nil; b = l.async.queue.pop() {
+		if _, err := l.out.Write(b.B); err != nil {
+			l.diag(LevelError, "writing entry: %v", err)
+		}
+		bytebufferpool.Put(b)
+		atomic.AddInt64(&l.pending, -1)
+	}
+}
+
+// enqueue hands buf of priority p to the background writer, applying
+// OverflowPolicy while the queue is full. The drop policies evict a queued
+// entry of lower priority first and drop by their policy only among
+// entries of the same priority. It returns false after Close, buf is then
+// still the caller's
+func (l *Logger) enqueue(buf *bytebufferpool.ByteBuffer, p Priority) bool {
+	a := l.async
+	a.mu.RLock()
+	defer a.mu.RUnlock()
+	if a.closed {
+		return false
+	}
+	atomic.AddInt64(&l.pending, 1)
+	for !a.queue.push(buf, p) {
+		switch l.cfg.OverflowPolicy {
+		case OverflowDropNewest, OverflowDropOldest:
+			old := a.queue.(*classQueue).evict(p, l.cfg.OverflowPolicy == OverflowDropOldest)
+			if old == nil {
+				// Nothing queued ranks below the entry of the request
+				old, buf = buf, nil
+			}
+			atomic.AddInt64(&l.pending, -1)
+			bytebufferpool.Put(old)
+			l.overflowed()
+			if buf == nil {
+				return true
+			}
+		default:
+			time.Sleep(50 * time.Microsecond)
+		}
+	}
+	return true
+}
+
+// overflowed counts an entry dropped from the full queue and reports the
+// drops at most once a second
+func (l *Logger) overflowed() {
+	dropped := atomic.AddUint64(&l.dropped, 1)
+	now := time.Now().UnixNano()
+	last := atomic.LoadInt64(&l.async.reported)
+	if now-last >= int64(time.Second) && atomic.CompareAndSwapInt64(&l.async.reported, last, now) {
+		l.diag(LevelWarn, "async queue full, %d entries dropped so far", dropped)
+	}
+}
+
+// Flush waits until the entries queued with Async are written
+func (l *Logger) Flush() {
+	if l.async == nil {
+		return
+	}
+	for atomic.LoadInt64(&l.pending) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+}
+
+// Close writes the entries queued with Async and stops the background
+// writer, later entries are written synchronously. It writes the summaries
+// of the current windows like the aggregate lines and the rollups, stops
+// polling Remote and closes the FileWriter of Config.File, other outputs
+// are left to the caller
+func (l *Logger) Close() error {
+	l.once.Do(func() {
+		close(l.done)
+		if a := l.async; a != nil {
+			a.mu.Lock()
+			a.closed = true
+			a.queue.close()
+			a.mu.Unlock()
+			<-a.drained
+		}
+		l.flushSummaries()
+		if l.rollup != nil {
+			l.flushRollup(l.cfg.Clock.Now())
+		}
+	})
+	if l.cfg.File != nil {
+		if c, ok := l.cfg.Output.(io.Closer); ok {
+			return c.Close()
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// gatedWriter blocks writes until open is closed
+type gatedWriter struct {
+	open chan struct{}
+	mu   sync.Mutex
+	buf  strings.Builder
+}
+
+func (w *gatedWriter) Write(p []byte) (int, error) {
+	<-w.open
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.Write(p)
+}
+
+func (w *gatedWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+func TestLogger_Async(t *testing.T) {
+	out := &gatedWriter{open: make(chan struct{})}
+	l := NewLogger(Config{Format: "${path}\n", Output: out, Async: true})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {})
+
+	// Requests finish while the output is blocked
+	for _, path := range []string{"/a", "/b"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Fatalf("Has: %+v, expected: nil", err)
+		}
+	}
+	close(out.open)
+	l.Flush()
+	if out.String() != "/a\n/b\n" {
+		t.Errorf("Has: %q, expected: the queued entries after Flush", out.String())
+	}
+
+	l.Close()
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/c", nil), 1000); err != nil {
+		t.Fatalf("Has: %+v, expected: nil", err)
+	}
+	if out.String() != "/a\n/b\n/c\n" {
+		t.Errorf("Has: %q, expected: entries written directly after Close", out.String())
+	}
+}
+
+func TestLogger_AsyncSeq(t *testing.T) {
+	const apps, requests = 8, 50
+	out := &gatedWriter{open: make(chan struct{})}
+	close(out.open)
+	l := NewLogger(Config{Format: "${seq}\n", Output: out, Async: true, AsyncQueueSize: 16})
+	var wg sync.WaitGroup
+	for i := 0; i < apps; i++ {
+		app := fiber.New()
+		app.Use(l.Handle)
+		app.Get("/", func(ctx *fiber.Ctx) {})
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := 0; j < requests; j++ {
+				app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000)
+			}
+		}()
+	}
+	wg.Wait()
+	l.Close()
+
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != apps*requests {
+		t.Fatalf("Has: %d entries, expected: %d", len(lines), apps*requests)
+	}
+	for i, line := range lines {
+		if line != strconv.Itoa(i+1) {
+			t.Fatalf("Has: %s at line %d, expected: entries in the order of their numbers", line, i+1)
+		}
+	}
+}
+
+func TestLogger_OverflowPolicy(t *testing.T) {
+	for policy, expected := range map[OverflowPolicy]string{
+		OverflowDropNewest: "/1\n/2\n/3\n",
+		OverflowDropOldest: "/1\n/3\n/4\n",
+	} {
+		out := &gatedWriter{open: make(chan struct{})}
+		l := NewLogger(Config{
+			Format:         "${path}\n",
+			Output:         out,
+			Async:          true,
+			AsyncQueueSize: 2,
+			OverflowPolicy: policy,
+			Diagnostics:    ioutil.Discard,
+		})
+		app := fiber.New()
+		app.Use(l.Handle)
+		app.Get("/*", func(ctx *fiber.Ctx) {})
+		for i, path := range []string{"/1", "/2", "/3", "/4"} {
+			if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+				t.Fatalf("Has: %+v, expected: nil", err)
+			}
+			// The writer holds the first entry, the queue the next two
+			for i == 0 && l.async.queue.len() > 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		close(out.open)
+		l.Close()
+		if out.String() != expected {
+			t.Errorf("Has: %q, expected: %q for policy %d", out.String(), expected, policy)
+		}
+		if l.dropped != 1 {
+			t.Errorf("Has: %d, expected: 1 entry dropped", l.dropped)
+		}
+	}
+}
+
+func TestLogger_OverflowPriority(t *testing.T) {
+	for _, policy := range []OverflowPolicy{OverflowDropNewest, OverflowDropOldest} {
+		out := &gatedWriter{open: make(chan struct{})}
+		l := NewLogger(Config{
+			Format:         "${path}\n",
+			Output:         out,
+			Async:          true,
+			AsyncQueueSize: 2,
+			OverflowPolicy: policy,
+			Diagnostics:    ioutil.Discard,
+		})
+		app := fiber.New()
+		app.Use(l.Handle)
+		app.Get("/*", func(ctx *fiber.Ctx) {
+			if ctx.Path() == "/error" {
+				ctx.SendStatus(500)
+			}
+		})
+		for i, path := range []string{"/1", "/2", "/3", "/error", "/4"} {
+			if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+				t.Fatalf("Has: %+v, expected: nil", err)
+			}
+			for i == 0 && l.async.queue.len() > 0 {
+				time.Sleep(time.Millisecond)
+			}
+		}
+		close(out.open)
+		l.Close()
+		// The queue is full of ok entries, the error evicts one of them and
+		// is kept over the ok entry after it
+		if !strings.Contains(out.String(), "/error\n") {
+			t.Errorf("Has: %q, expected: /error kept for policy %d", out.String(), policy)
+		}
+		if l.dropped != 2 {
+			t.Errorf("Has: %d, expected: 2 entries dropped", l.dropped)
+		}
+	}
+}
@@ -0,0 +1,181 @@
+package logger
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// awsCredentials sign requests to AWS, Expires is zero for static keys
+type awsCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	Expires         time.Time
+}
+
+// awsCredentialSource returns the credentials of static keys, the
+// environment as set in Lambda, or the container credentials endpoint of
+// ECS, which are refreshed before they expire
+type awsCredentialSource struct {
+	static awsCredentials
+	client *http.Client
+	mu     sync.Mutex
+	cached awsCredentials
+}
+
+// newAWSCredentialSource uses the keys when set, else the environment
+func newAWSCredentialSource(accessKeyID, secretAccessKey, sessionToken string, client *http.Client) (*awsCredentialSource, error) {
+	if accessKeyID == "" {
+		accessKeyID = os.Getenv("AWS_ACCESS_KEY_ID")
+		secretAccessKey = os.Getenv("AWS_SECRET_ACCESS_KEY")
+		sessionToken = os.Getenv("AWS_SESSION_TOKEN")
+	}
+	s := &awsCredentialSource{
+		static: awsCredentials{AccessKeyID: accessKeyID, SecretAccessKey: secretAccessKey, SessionToken: sessionToken},
+		client: client,
+	}
+	if accessKeyID == "" && awsContainerCredentialsURI() == "" {
+		return nil, errors.New("logger: no aws credentials, set the keys or run with a task role")
+	}
+	return s, nil
+}
+
+// awsContainerCredentialsURI returns the credentials endpoint ECS and other
+// container platforms provide to tasks with a role
+func awsContainerCredentialsURI() string {
+	if uri := os.Getenv("AWS_CONTAINER_CREDENTIALS_RELATIVE_URI"); uri != "" {
+		return "http://169.254.170.2" + uri
+	}
+	return os.Getenv("AWS_CONTAINER_CREDENTIALS_FULL_URI")
+}
+
+// get returns valid credentials, fetched again within 5 minutes of expiry
+func (s *awsCredentialSource) get() (awsCredentials, error) {
+	if s.static.AccessKeyID != "" {
+		return s.static, nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.cached.AccessKeyID != "" && time.Until(s.cached.Expires) > 5*time.Minute {
+		return s.cached, nil
+	}
+	req, err := http.NewRequest(http.MethodGet, awsContainerCredentialsURI(), nil)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	token := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN")
+	if file := os.Getenv("AWS_CONTAINER_AUTHORIZATION_TOKEN_FILE"); file != "" {
+		b, err := ioutil.ReadFile(file)
+		if err != nil {
+			return awsCredentials{}, err
+		}
+		token = strings.TrimSpace(string(b))
+	}
+	if token != "" {
+		req.Header.Set("Authorization", token)
+	}
+	resp, err := s.client.Do(req)
+	if err != nil {
+		return awsCredentials{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		return awsCredentials{}, fmt.Errorf("logger: aws container credentials: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	var c struct {
+		AccessKeyID     string    `json:"AccessKeyId"`
+		SecretAccessKey string    `json:"SecretAccessKey"`
+		Token           string    `json:"Token"`
+		Expiration      time.Time `json:"Expiration"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&c); err != nil {
+		return awsCredentials{}, err
+	}
+	s.cached = awsCredentials{AccessKeyID: c.AccessKeyID, SecretAccessKey: c.SecretAccessKey, SessionToken: c.Token, Expires: c.Expiration}
+	return s.cached, nil
+}
+
+// awsRegion returns AWS_REGION or AWS_DEFAULT_REGION
+func awsRegion() string {
+	if region := os.Getenv("AWS_REGION"); region != "" {
+		return region
+	}
+	return os.Getenv("AWS_DEFAULT_REGION")
+}
+
+// signAWS signs req with Signature Version 4, covering the host, all headers
+// set on req and body or the payload hash of X-Amz-Content-Sha256
+func signAWS(req *http.Request, body []byte, c awsCredentials, region, service string, t time.Time) {
+	t = t.UTC()
+	date := t.Format("20060102")
+	req.Header.Set("X-Amz-Date", t.Format("20060102T150405Z"))
+	if c.SessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.SessionToken)
+	}
+	headers := map[string]string{"host": req.Host}
+	if req.Host == "" {
+		headers["host"] = req.URL.Host
+	}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	signed := strings.Join(names, ";")
+
+	var canonical strings.Builder
+	canonical.WriteString(req.Method + "\n")
+	path := req.URL.EscapedPath()
+	if path == "" {
+		path = "/"
+	}
+	canonical.WriteString(path + "\n")
+	canonical.WriteString(strings.Replace(req.URL.Query().Encode(), "+", "%20", -1) + "\n")
+	for _, name := range names {
+		canonical.WriteString(name + ":" + headers[name] + "\n")
+	}
+	canonical.WriteString("\n" + signed + "\n")
+	// S3 takes the payload hash from a header, e.g. UNSIGNED-PAYLOAD for
+	// streamed bodies
+	if payload := req.Header.Get("X-Amz-Content-Sha256"); payload != "" {
+		canonical.WriteString(payload)
+	} else {
+		canonical.WriteString(hexSHA256(body))
+	}
+
+	scope := date + "/" + region + "/" + service + "/aws4_request"
+	toSign := "AWS4-HMAC-SHA256\n" + t.Format("20060102T150405Z") + "\n" + scope + "\n" + hexSHA256([]byte(canonical.String()))
+	key := []byte("AWS4" + c.SecretAccessKey)
+	for _, part := range []string{date, region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	req.Header.Set("Authorization", "AWS4-HMAC-SHA256 Credential="+c.AccessKeyID+"/"+scope+
+		", SignedHeaders="+signed+", Signature="+hex.EncodeToString(hmacSHA256(key, toSign)))
+}
+
+func hexSHA256(b []byte) string {
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+)
+
+func TestSignAWS(t *testing.T) {
+	// get-vanilla of the AWS Signature Version 4 test suite
+	req, _ := http.NewRequest(http.MethodGet, "https://example.amazonaws.com/", nil)
+	signAWS(req, nil, awsCredentials{AccessKeyID: "AKIDEXAMPLE", SecretAccessKey: "wJalrXUtnFEMI/K7MDENG+bPxRfiCYEXAMPLEKEY"},
+		"us-east-1", "service", time.Date(2015, 8, 30, 12, 36, 0, 0, time.UTC))
+
+	expected := "AWS4-HMAC-SHA256 Credential=AKIDEXAMPLE/20150830/us-east-1/service/aws4_request, " +
+		"SignedHeaders=host;x-amz-date, Signature=5fa00fa31553b73ebf1942676e86291e8372ff2a2260956d9b8aae1d763fbf31"
+	if auth := req.Header.Get("Authorization"); auth != expected {
+		t.Errorf("Has: %s, expected: %s", auth, expected)
+	}
+}
+
+func TestAWSCredentialSource_container(t *testing.T) {
+	var fetches int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		if r.Header.Get("Authorization") != "task-token" {
+			t.Errorf("Has: %q, expected: the authorization token", r.Header.Get("Authorization"))
+		}
+		w.Write([]byte(`{"AccessKeyId":"AKID","SecretAccessKey":"secret","Token":"session","Expiration":"` +
+			time.Now().Add(time.Hour).UTC().Format(time.RFC3339) + `"}`))
+	}))
+	defer server.Close()
+	for key, value := range map[string]string{
+		"AWS_ACCESS_KEY_ID":                      "",
+		"AWS_CONTAINER_CREDENTIALS_FULL_URI":     server.URL + "/creds",
+		"AWS_CONTAINER_AUTHORIZATION_TOKEN":      "task-token",
+		"AWS_CONTAINER_CREDENTIALS_RELATIVE_URI": "",
+	} {
+		defer os.Setenv(key, os.Getenv(key))
+		os.Setenv(key, value)
+	}
+
+	s, err := newAWSCredentialSource("", "", "", http.DefaultClient)
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	for i := 0; i < 2; i++ {
+		c, err := s.get()
+		if err != nil || c.AccessKeyID != "AKID" || c.SessionToken != "session" {
+			t.Errorf("Has: %+v %v, expected: the credentials of the endpoint", c, err)
+		}
+	}
+	if fetches != 1 {
+		t.Errorf("Has: %d fetches, expected: 1 until the credentials expire", fetches)
+	}
+}
@@ -0,0 +1,134 @@
+package logger
+
+import (
+	"os"
+	"sync"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// maxBatchLines is the IOV_MAX of Linux, the most buffers of a writev
+const maxBatchLines = 1024
+
+// BatchConfig configures a BatchWriter
+type BatchConfig struct {
+	// MaxLines is the number of lines written at once, at most 1024
+	// Optional. Default: 256
+	MaxLines int
+	// FlushInterval is the longest a line waits to be written
+	// Optional. Default: 100 * time.Millisecond
+	FlushInterval time.Duration
+}
+
+// BatchWriter collects the lines written to a file and writes them in
+// batches of MaxLines or every FlushInterval. On Linux a batch is written
+// with a single writev system call, elsewhere line by line. An error of a
+// batch written in the background is returned by the next Write instead of
+// taking its line
+type BatchWriter struct {
+	f      *os.File
+	cfg    BatchConfig
+	mu     sync.Mutex
+	lines  []*bytebufferpool.ByteBuffer
+	bufs   [][]byte
+	err    error
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewBatchWriter batches the writes to f
+func NewBatchWriter(f *os.File, cfg BatchConfig) *BatchWriter {
+	if cfg.MaxLines <= 0 {
+		cfg.MaxLines = 256
+	}
+	if cfg.MaxLines > maxBatchLines {
+		cfg.MaxLines = maxBatchLines
+	}
+	if cfg.FlushInterval <= 0 {
+		cfg.FlushInterval = 100 * time.Millisecond
+	}
+	w := &BatchWriter{
+		f:      f,
+		cfg:    cfg,
+		lines:  make([]*bytebufferpool.ByteBuffer, 0, cfg.MaxLines),
+		bufs:   make([][]byte, 0, cfg.MaxLines),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go w.run()
+	return w
+}
+
+// Write adds a copy of p to the batch, writing it once it is full
+func (w *BatchWriter) Write(p []byte) (int, error) {
+	line := bytebufferpool.Get()
+	line.Write(p)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if err := w.err; err != nil {
+		w.err = nil
+		bytebufferpool.Put(line)
+		return 0, err
+	}
+	w.lines = append(w.lines, line)
+	if len(w.lines) >= w.cfg.MaxLines {
+		if err := w.flush(); err != nil {
+			return 0, err
+		}
+	}
+	return len(p), nil
+}
+
+// Flush writes the pending lines
+func (w *BatchWriter) Flush() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.flush()
+}
+
+func (w *BatchWriter) flush() error {
+	if len(w.lines) == 0 {
+		return nil
+	}
+	for _, line := range w.lines {
+		w.bufs = append(w.bufs, line.B)
+	}
+	err := writeBatch(w.f, w.bufs)
+	for i, line := range w.lines {
+		bytebufferpool.Put(line)
+		w.lines[i], w.bufs[i] = nil, nil
+	}
+	w.lines, w.bufs = w.lines[:0], w.bufs[:0]
+	return err
+}
+
+// run writes the batch every FlushInterval until Close
+func (w *BatchWriter) run() {
+	ticker := time.NewTicker(w.cfg.FlushInterval)
+	defer ticker.Stop()
+	defer close(w.closed)
+	for {
+		select {
+		case <-ticker.C:
+			w.mu.Lock()
+			if err := w.flush(); err != nil {
+				w.err = err
+			}
+			w.mu.Unlock()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Close writes the pending lines and stops the writer, the file is left
+// open
+func (w *BatchWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.closed
+	return w.Flush()
+}
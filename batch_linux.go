@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"io"
+	"os"
+	"syscall"
+	"unsafe"
+)
+
+// writeBatch writes bufs to f with writev, continuing after partial writes
+func writeBatch(f *os.File, bufs [][]byte) error {
+	rc, err := f.SyscallConn()
+	if err != nil {
+		return err
+	}
+	iovs := make([]syscall.Iovec, 0, len(bufs))
+	for {
+		iovs = iovs[:0]
+		for _, b := range bufs {
+			if len(b) > 0 {
+				iov := syscall.Iovec{Base: &b[0]}
+				iov.SetLen(len(b))
+				iovs = append(iovs, iov)
+			}
+		}
+		if len(iovs) == 0 {
+			return nil
+		}
+		var n uintptr
+		var errno syscall.Errno
+		err := rc.Write(func(fd uintptr) bool {
+			n, _, errno = syscall.Syscall(syscall.SYS_WRITEV, fd, uintptr(unsafe.Pointer(&iovs[0])), uintptr(len(iovs)))
+			return errno != syscall.EAGAIN
+		})
+		if err != nil {
+			return err
+		}
+		if errno == syscall.EINTR {
+			continue
+		}
+		if errno != 0 {
+			return &os.PathError{Op: "writev", Path: f.Name(), Err: errno}
+		}
+		if n == 0 {
+			return io.ErrShortWrite
+		}
+		// Drop what was written
+		for len(bufs) > 0 && int(n) >= len(bufs[0]) {
+			n -= uintptr(len(bufs[0]))
+			bufs = bufs[1:]
+		}
+		if len(bufs) > 0 {
+			bufs[0] = bufs[0][n:]
+		}
+	}
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package logger
+
+import "os"
+
+// writeBatch writes bufs to f one by one
+func writeBatch(f *os.File, bufs [][]byte) error {
+	for _, b := range bufs {
+		if _, err := f.Write(b); err != nil {
+			return err
+		}
+	}
+	return nil
+}
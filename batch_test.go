@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBatchWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	f, err := os.Create(filepath.Join(dir, "access.log"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	w := NewBatchWriter(f, BatchConfig{MaxLines: 2, FlushInterval: time.Hour})
+	for _, line := range []string{"GET /a 200\n", "GET /b 200\n", "GET /c 200\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Errorf("Has: %v, expected: nil", err)
+		}
+	}
+	if data, _ := ioutil.ReadFile(f.Name()); string(data) != "GET /a 200\nGET /b 200\n" {
+		t.Errorf("Has: %q, expected: the first batch", data)
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	if data, _ := ioutil.ReadFile(f.Name()); string(data) != "GET /a 200\nGET /b 200\nGET /c 200\n" {
+		t.Errorf("Has: %q, expected: all lines after Close", data)
+	}
+}
+
+func TestBatchWriter_interval(t *testing.T) {
+	r, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	defer pw.Close()
+
+	w := NewBatchWriter(pw, BatchConfig{FlushInterval: 10 * time.Millisecond})
+	defer w.Close()
+	w.Write([]byte("GET / 200\n"))
+	line := make([]byte, 10)
+	r.SetReadDeadline(time.Now().Add(time.Second))
+	if n, err := r.Read(line); err != nil || string(line[:n]) != "GET / 200\n" {
+		t.Errorf("Has: %q (%v), expected: the line after the interval", line[:n], err)
+	}
+}
+
+func TestWriteBatch_partial(t *testing.T) {
+	r, pw, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	// More than the capacity of the pipe, written in several parts
+	bufs := [][]byte{bytes.Repeat([]byte("a"), 100000), {}, bytes.Repeat([]byte("b"), 100000)}
+	read := make(chan string)
+	go func() {
+		data, _ := ioutil.ReadAll(r)
+		read <- string(data)
+	}()
+	if err := writeBatch(pw, bufs); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	pw.Close()
+	if data := <-read; data != strings.Repeat("a", 100000)+strings.Repeat("b", 100000) {
+		t.Errorf("Has: %d bytes, expected: 200000 in order", len(data))
+	}
+}
@@ -0,0 +1,106 @@
+package logger
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Budget limits how much the requests matching Route may log per Interval
+type Budget struct {
+	// Route is a request path, a trailing * matches any suffix
+	// Required. Example: "/static/*"
+	Route string
+	// Lines is the maximum number of entries per Interval
+	// Optional. Default: 0 (unlimited)
+	Lines int
+	// Bytes is the maximum number of bytes per Interval
+	// Optional. Default: 0 (unlimited)
+	Bytes int
+	// Interval is the window the budget is counted in
+	// Optional. Default: 1 * time.Minute
+	Interval time.Duration
+}
+
+// budget tracks the usage of a Budget in the current interval
+type budget struct {
+	Budget
+	mu           sync.Mutex
+	reset        time.Time
+	lines        int
+	bytes        int
+	droppedLines int
+	droppedBytes int
+}
+
+func newBudgets(config []Budget) []*budget {
+	budgets := make([]*budget, len(config))
+	for i := range config {
+		budgets[i] = &budget{Budget: config[i]}
+		if budgets[i].Interval <= 0 {
+			budgets[i].Interval = time.Minute
+		}
+	}
+	return budgets
+}
+
+// matchBudget returns the first budget matching path or nil
+func matchBudget(budgets []*budget, path string) *budget {
+	for _, b := range budgets {
+		if matchPath(b.Route, path) {
+			return b
+		}
+	}
+	return nil
+}
+
+// matchPath reports whether path is pattern, a trailing * in pattern
+// matches any suffix
+func matchPath(pattern, path string) bool {
+	if strings.HasSuffix(pattern, "*") {
+		return strings.HasPrefix(path, pattern[:len(pattern)-1])
+	}
+	return path == pattern
+}
+
+// allow reports whether an entry of n bytes fits in the budget. When a new
+// interval starts the overflow of the previous one is summarized to w
+func (b *budget) allow(n int, now time.Time, w summaryWriter) bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if now.After(b.reset) {
+		b.flush(w, now)
+		b.reset = now.Add(b.Interval)
+	}
+	if (b.Lines > 0 && b.lines+1 > b.Lines) || (b.Bytes > 0 && b.bytes+n > b.Bytes) {
+		b.droppedLines++
+		b.droppedBytes += n
+		return false
+	}
+	b.lines++
+	b.bytes += n
+	return true
+}
+
+// tick writes the summary of an interval that elapsed without a later
+// entry, the next entry starts a new interval. With all the current
+// one is written, as on Close
+func (b *budget) tick(now time.Time, all bool, w summaryWriter) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if all || !b.reset.IsZero() && now.After(b.reset) {
+		b.flush(w, now)
+		b.reset = time.Time{}
+	}
+}
+
+// flush writes the overflow of the interval and starts counting from zero
+func (b *budget) flush(w summaryWriter, now time.Time) {
+	if b.droppedLines > 0 {
+		w.writeSummary(&Entry{Time: now, Route: b.Route, Fields: []Field{{"summary", "budget"}, {"dropped", b.droppedLines},
+			{"droppedBytes", b.droppedBytes}, {"window", b.Interval.String()}}},
+			fmt.Sprintf("%s over budget, %d entries (%d bytes) dropped in last %s", b.Route, b.droppedLines, b.droppedBytes, b.Interval))
+	}
+	b.lines, b.bytes, b.droppedLines, b.droppedBytes = 0, 0, 0, 0
+}
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestBudget_allow(t *testing.T) {
+	buf := &strings.Builder{}
+	budgets := newBudgets([]Budget{{Route: "/static/*", Lines: 2}})
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	if b := matchBudget(budgets, "/api"); b != nil {
+		t.Errorf("Has: %s, expected: nil", b.Route)
+	}
+	b := matchBudget(budgets, "/static/app.js")
+	logged := 0
+	for i := 0; i < 5; i++ {
+		if b.allow(10, now, textSummaries{buf, "15:04:05"}) {
+			logged++
+		}
+	}
+	if logged != 2 {
+		t.Errorf("Has: %d, expected: 2", logged)
+	}
+	b.allow(10, now.Add(2*time.Minute), textSummaries{buf, "15:04:05"})
+
+	expectedOutput := "12:02:00 /static/* over budget, 3 entries (30 bytes) dropped in last 1m0s\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
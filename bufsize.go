@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/valyala/bytebufferpool"
+	"github.com/valyala/fasttemplate"
+)
+
+// tagSizes are typical rendered sizes of tags, to estimate the size of an
+// entry. Other tags count as defaultTagSize
+var tagSizes = map[string]int{
+	strIp: 15, strIps: 48, strUrl: 64, strPath: 48, strRoute: 32, strReferer: 64,
+	strUa: 128, strBody: 256, strError: 64, strErrorStack: 512, strRequestLine: 80,
+	strStatus: 3, strMethod: 7, strLatency: 12, strRequestID: 32, strTraceID: 32,
+	strDeps: 48,
+}
+
+const (
+	defaultTagSize = 16
+	// encoderSize is the size of the keys and standard values of an entry
+	// written by an encoder
+	encoderSize = 256
+)
+
+// estimateSize estimates the rendered size of an entry in tmpl, or by enc
+// when set, from the literal text and the typical size of its tags
+func (l *Logger) estimateSize(tmpl *fasttemplate.Template, enc Encoder) int {
+	var size int
+	var tags []string
+	if enc != nil {
+		size = encoderSize
+		if t, ok := enc.(interface{ Tags() []string }); ok {
+			tags = t.Tags()
+		}
+	} else {
+		n, _ := tmpl.ExecuteFunc(ioutil.Discard, func(w io.Writer, tag string) (int, error) {
+			tags = append(tags, tag)
+			return 0, nil
+		})
+		size = int(n)
+	}
+	for _, tag := range tags {
+		switch n, ok := tagSizes[tag]; {
+		case ok:
+			size += n
+		case tag == strTime:
+			size += len(l.cfg.TimeFormat)
+		default:
+			size += defaultTagSize
+		}
+	}
+	return size
+}
+
+// bufferSize returns Config.BufferSize or the largest estimated size of an
+// entry of the output and sinks, which share a buffer
+func (l *Logger) bufferSize() int {
+	if l.cfg.BufferSize > 0 {
+		return l.cfg.BufferSize
+	}
+	size := l.estimateSize(l.tmpl, l.cfg.Encoder)
+	for _, s := range l.sinks {
+		if n := l.estimateSize(s.tmpl, s.Encoder); n > size {
+			size = n
+		}
+	}
+	return size
+}
+
+// buffer returns a pooled buffer grown to the size of an entry, so long
+// entries are not rendered through repeated reallocations
+func (l *Logger) buffer() *bytebufferpool.ByteBuffer {
+	buf := bytebufferpool.Get()
+	if cap(buf.B) < l.bufSize {
+		buf.B = make([]byte, 0, l.bufSize)
+	}
+	return buf
+}
@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"io/ioutil"
+	"testing"
+)
+
+func TestLogger_bufferSize(t *testing.T) {
+	l := NewLogger(Config{
+		Format:     "${time} ${status} ${ua} ${header:X-Tenant}\n",
+		TimeFormat: "15:04:05",
+		Output:     ioutil.Discard,
+	})
+	// 4 literal bytes, time, status, ua and a default sized tag
+	if expected := 4 + 8 + 3 + 128 + defaultTagSize; l.bufSize != expected {
+		t.Errorf("Has: %d, expected: %d", l.bufSize, expected)
+	}
+
+	l = NewLogger(Config{
+		Format: "${status}\n",
+		Output: ioutil.Discard,
+		Sinks:  []SinkConfig{{Format: FormatJSON, Output: ioutil.Discard}},
+	})
+	if l.bufSize != encoderSize {
+		t.Errorf("Has: %d, expected: %d", l.bufSize, encoderSize)
+	}
+
+	l = NewLogger(Config{Output: ioutil.Discard, BufferSize: 4096})
+	buf := l.buffer()
+	if l.bufSize != 4096 || cap(buf.B) < 4096 {
+		t.Errorf("Has: %d, %d, expected: 4096", l.bufSize, cap(buf.B))
+	}
+}
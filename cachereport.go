@@ -0,0 +1,77 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// cacheReport counts full (200) and not modified (304) responses per route
+// and reports their ratio once the interval has elapsed
+type cacheReport struct {
+	mu       sync.Mutex
+	interval time.Duration
+	reset    time.Time
+	counts   map[string]*[2]int
+}
+
+func newCacheReport(interval time.Duration) *cacheReport {
+	return &cacheReport{
+		interval: interval,
+		counts:   make(map[string]*[2]int),
+	}
+}
+
+// add counts a response. When a new interval starts the ratios of the
+// previous one are written to w
+func (r *cacheReport) add(route string, status int, now time.Time, w summaryWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.After(r.reset) {
+		r.flush(w, now)
+		r.reset = now.Add(r.interval)
+	}
+	if status != 200 && status != 304 {
+		return
+	}
+	n := r.counts[route]
+	if n == nil {
+		n = &[2]int{}
+		r.counts[route] = n
+	}
+	if status == 304 {
+		n[1]++
+	} else {
+		n[0]++
+	}
+}
+
+// tick writes the ratios of an interval that elapsed without a later
+// response, the next response starts a new interval. With all the current
+// one is written, as on Close
+func (r *cacheReport) tick(now time.Time, all bool, w summaryWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if all || !r.reset.IsZero() && now.After(r.reset) {
+		r.flush(w, now)
+		r.reset = time.Time{}
+	}
+}
+
+// flush writes one line per route and starts counting from zero
+func (r *cacheReport) flush(w summaryWriter, now time.Time) {
+	routes := make([]string, 0, len(r.counts))
+	for route := range r.counts {
+		routes = append(routes, route)
+	}
+	sort.Strings(routes)
+	for _, route := range routes {
+		n := r.counts[route]
+		percent := n[1] * 100 / (n[0] + n[1])
+		w.writeSummary(&Entry{Time: now, Route: route, Fields: []Field{{"summary", "cacheReport"}, {"notModified", n[1]},
+			{"full", n[0]}, {"percent", percent}, {"window", r.interval.String()}}},
+			fmt.Sprintf("%s 304/200 %d/%d, %d%% not modified in last %s", route, n[1], n[0], percent, r.interval))
+	}
+	r.counts = make(map[string]*[2]int, len(r.counts))
+}
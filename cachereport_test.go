@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCacheReport_add(t *testing.T) {
+	buf := &strings.Builder{}
+	r := newCacheReport(time.Minute)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	for i := 0; i < 3; i++ {
+		r.add("/assets/*", 304, now, textSummaries{buf, "15:04:05"})
+	}
+	r.add("/assets/*", 200, now, textSummaries{buf, "15:04:05"})
+	r.add("/assets/*", 404, now, textSummaries{buf, "15:04:05"})
+	r.add("/api", 200, now, textSummaries{buf, "15:04:05"})
+	if buf.Len() != 0 {
+		t.Errorf("Has: %s, expected: empty", buf.String())
+	}
+
+	r.add("/api", 200, now.Add(2*time.Minute), textSummaries{buf, "15:04:05"})
+	expectedOutput := "12:02:00 /api 304/200 0/1, 0% not modified in last 1m0s\n" +
+		"12:02:00 /assets/* 304/200 3/1, 75% not modified in last 1m0s\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
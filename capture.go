@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"crypto/subtle"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+// Capture dumps the raw request and response of requests matching Path that
+// carry Token in Header to Output, a surgical alternative to tcpdump
+type Capture struct {
+	// Path is a request path, a trailing * matches any suffix
+	// Required. Example: "/api/orders/*"
+	Path string
+	// Header is the request header carrying Token
+	// Optional. Default: "X-Debug-Capture"
+	Header string
+	// Token must be sent in Header for a request to be captured, its value
+	// is masked in the dump
+	// Required.
+	Token string
+	// Output is the debug sink dumps are written to
+	// Required.
+	Output io.Writer
+	// MaxBody caps the bytes of each body in a dump
+	// Optional. Default: 4096
+	MaxBody int
+}
+
+// capture is a Capture with its tracked output
+type capture struct {
+	Capture
+	out *output
+}
+
+func newCaptures(config []Capture) []*capture {
+	captures := make([]*capture, len(config))
+	for i := range config {
+		captures[i] = &capture{Capture: config[i]}
+		if captures[i].Header == "" {
+			captures[i].Header = "X-Debug-Capture"
+		}
+		if captures[i].MaxBody <= 0 {
+			captures[i].MaxBody = 4096
+		}
+		captures[i].out = newOutput("capture "+captures[i].Path, captures[i].Output)
+	}
+	return captures
+}
+
+// matchCapture returns the first capture the request matches or nil
+func matchCapture(captures []*capture, c *fiber.Ctx) *capture {
+	for _, cp := range captures {
+		// The token is compared in constant time, like the admin token
+		if cp.Token != "" && matchPath(cp.Path, c.Path()) && subtle.ConstantTimeCompare([]byte(c.Get(cp.Header)), []byte(cp.Token)) == 1 {
+			return cp
+		}
+	}
+	return nil
+}
+
+// dump writes the request and response of c
+func (cp *capture) dump(c *fiber.Ctx, stop time.Time) error {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	req, res := &c.Fasthttp.Request, &c.Fasthttp.Response
+
+	buf.WriteString("--- capture " + stop.Format(time.RFC3339Nano))
+	if id := traceID(c); id != "" {
+		buf.WriteString(" " + id)
+	}
+	buf.WriteString("\n")
+	buf.WriteString(requestLine(c) + "\r\n")
+	req.Header.VisitAll(func(key, value []byte) {
+		if strings.EqualFold(string(key), cp.Header) {
+			value = []byte("***")
+		}
+		buf.WriteString(string(key) + ": " + string(value) + "\r\n")
+	})
+	buf.WriteString("\r\n")
+	cp.body(buf, req.Body())
+	buf.WriteString("\n")
+	buf.Write(res.Header.Header())
+	cp.body(buf, res.Body())
+	buf.WriteString("\n---\n")
+	_, err := cp.out.Write(buf.Bytes())
+	return err
+}
+
+// body writes up to MaxBody bytes of b
+func (cp *capture) body(buf *bytebufferpool.ByteBuffer, b []byte) {
+	if len(b) <= cp.MaxBody {
+		buf.Write(b)
+		return
+	}
+	buf.Write(b[:cp.MaxBody])
+	buf.WriteString("\n[" + strconv.Itoa(len(b)-cp.MaxBody) + " bytes truncated]")
+}
@@ -0,0 +1,47 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestCapture_dump(t *testing.T) {
+	dump := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Output: ioutil.Discard,
+		Captures: []Capture{
+			{Path: "/api/*", Token: "s3cret", Output: dump, MaxBody: 8},
+		},
+	}))
+	app.Post("/api/orders", func(ctx *fiber.Ctx) {
+		ctx.Status(201).SendString("created")
+	})
+
+	for _, token := range []string{"", "wrong", "s3cret"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/orders", strings.NewReader(`{"item":"ball"}`))
+		req.Header.Set("Content-Length", "15")
+		req.Header.Set("X-Debug-Capture", token)
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	out := dump.String()
+	if strings.Count(out, "--- capture") != 1 {
+		t.Fatalf("Has: %s, expected: one capture", out)
+	}
+	for _, expected := range []string{"POST /api/orders HTTP/1.1\r\n", "X-Debug-Capture: ***\r\n", `{"item":` + "\n[7 bytes truncated]", "HTTP/1.1 201 Created\r\n", "\r\n\r\ncreated\n---\n"} {
+		if !strings.Contains(out, expected) {
+			t.Errorf("Has: %q, expected: %q", out, expected)
+		}
+	}
+	if strings.Contains(out, "s3cret") {
+		t.Errorf("Has: %s, expected: token masked", out)
+	}
+}
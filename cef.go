@@ -0,0 +1,153 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// FormatCEF as Format writes entries with a CEFEncoder
+const FormatCEF = "cef"
+
+// cefTags are the tags a CEFEncoder maps to extensions
+var cefTags = []string{strUrl, strUa, strReferer, strHost, strLocalIp}
+
+// CEFEncoder writes an entry in the Common Event Format for SIEMs like
+// ArcSight or Splunk ES. The signature id is the status, the name the method
+// and route, and the request is mapped to the extensions rt, src, dst, dhost,
+// requestMethod, request, requestClientApplication, requestContext, cn1
+// (status), cn2 (latency in ms), externalId (request id) and msg (error).
+// The tags in Fields follow as extensions of their own
+type CEFEncoder struct {
+	// Vendor is the Device Vendor of the header
+	// Optional. Default: "gofiber"
+	Vendor string
+	// Product is the Device Product of the header
+	// Optional. Default: "logger"
+	Product string
+	// Version is the Device Version of the header
+	// Optional. Default: "1.0"
+	Version string
+	// Severity maps a status to a severity from 0 to 10
+	// Optional. Default: 8 for 5xx, 5 for 4xx, 2 otherwise
+	Severity func(status int) int
+	// Fields are tags written as extensions, e.g. {"requestId"}
+	// Optional. Default: nil
+	Fields []string
+}
+
+// Tags returns the tags of the standard extensions and Fields
+func (c *CEFEncoder) Tags() []string {
+	return append(append([]string(nil), cefTags...), c.Fields...)
+}
+
+// Encode writes e as a line of CEF
+func (c *CEFEncoder) Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error {
+	severity := cefSeverity
+	if c.Severity != nil {
+		severity = c.Severity
+	}
+	name := e.Route
+	if name == "" {
+		name = e.Path
+	}
+	buf.WriteString("CEF:0")
+	appendCEFHeader(buf, c.Vendor, "gofiber")
+	appendCEFHeader(buf, c.Product, "logger")
+	appendCEFHeader(buf, c.Version, "1.0")
+	appendCEFHeader(buf, strconv.Itoa(e.Status), "")
+	appendCEFHeader(buf, e.Method+" "+name, "")
+	appendCEFHeader(buf, strconv.Itoa(severity(e.Status)), "")
+	buf.WriteString("|rt=")
+	buf.B = strconv.AppendInt(buf.B, e.Time.UnixNano()/1e6, 10)
+	appendCEFExtension(buf, "src", e.IP)
+	appendCEFExtension(buf, "dst", e.getString(strLocalIp))
+	appendCEFExtension(buf, "dhost", e.getString(strHost))
+	appendCEFExtension(buf, "requestMethod", e.Method)
+	appendCEFExtension(buf, "request", e.getString(strUrl))
+	appendCEFExtension(buf, "requestClientApplication", e.getString(strUa))
+	appendCEFExtension(buf, "requestContext", e.getString(strReferer))
+	appendCEFExtension(buf, "cn1", strconv.Itoa(e.Status))
+	appendCEFExtension(buf, "cn1Label", "status")
+	appendCEFExtension(buf, "cn2", strconv.FormatInt(int64(e.Latency/1e6), 10))
+	appendCEFExtension(buf, "cn2Label", "latencyMs")
+	appendCEFExtension(buf, "externalId", e.ID)
+	if e.Err != nil {
+		appendCEFExtension(buf, "msg", e.Err.Error())
+	}
+	for _, f := range e.Fields {
+		if knownTag(f.Key) && !contains(c.Fields, f.Key) {
+			continue
+		}
+		value, ok := f.Value.(string)
+		if !ok && f.Value != nil {
+			value = fmt.Sprint(f.Value)
+		}
+		appendCEFExtension(buf, cefKey(f.Key), value)
+	}
+	buf.WriteString("\n")
+	return nil
+}
+
+// cefSeverity maps a status to a CEF severity: high for 5xx, medium for 4xx
+// and low otherwise
+func cefSeverity(status int) int {
+	switch {
+	case status >= 500:
+		return 8
+	case status >= 400:
+		return 5
+	}
+	return 2
+}
+
+// appendCEFHeader writes a header field escaping '\' and '|'
+func appendCEFHeader(buf *bytebufferpool.ByteBuffer, value, def string) {
+	if value == "" {
+		value = def
+	}
+	buf.WriteString("|")
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '|':
+			buf.B = append(buf.B, '\\', c)
+		case '\n', '\r':
+			buf.B = append(buf.B, ' ')
+		default:
+			buf.B = append(buf.B, c)
+		}
+	}
+}
+
+// appendCEFExtension writes key=value escaping '\', '=' and line breaks,
+// leaving it out when value is empty
+func appendCEFExtension(buf *bytebufferpool.ByteBuffer, key, value string) {
+	if value == "" {
+		return
+	}
+	buf.WriteString(" " + key + "=")
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '\\', '=':
+			buf.B = append(buf.B, '\\', c)
+		case '\n':
+			buf.B = append(buf.B, '\\', 'n')
+		case '\r':
+			buf.B = append(buf.B, '\\', 'r')
+		default:
+			buf.B = append(buf.B, c)
+		}
+	}
+}
+
+// cefKey keeps the letters and digits of a tag, as CEF keys allow no others
+func cefKey(tag string) string {
+	key := make([]byte, 0, len(tag))
+	for i := 0; i < len(tag); i++ {
+		if c := tag[i]; c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' {
+			key = append(key, c)
+		}
+	}
+	return string(key)
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+func TestCEFEncoder(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: FormatCEF,
+		Fields: []string{"header:X-Tenant"},
+		Output: buf,
+		Clock:  &stepClock{now: time.Unix(1577880000, 0), step: 5 * time.Millisecond},
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.Next(errors.New("a=b\nc"))
+	})
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(502)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1?q=1", nil)
+	req.Header.Set("User-Agent", `curl\7`)
+	req.Header.Set("X-Tenant", "acme")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := `CEF:0|gofiber|logger|1.0|502|GET /users/:id|8|rt=1577880000015 src=0.0.0.0 dst=0.0.0.0 dhost=example.com requestMethod=GET request=/users/1?q\=1 requestClientApplication=curl\\7 cn1=502 cn1Label=status cn2=5 cn2Label=latencyMs msg=a\=b\nc headerXTenant=acme` + "\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestAppendCEFHeader(t *testing.T) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	appendCEFHeader(buf, "a|b\\c\nd", "")
+	expected := `|a\|b\\c d`
+	if buf.String() != expected {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expected)
+	}
+}
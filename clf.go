@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+// requestLine returns the first line of the request as in CLF's %r
+func requestLine(c *fiber.Ctx) string {
+	req := &c.Fasthttp.Request
+	proto := "HTTP/1.0"
+	if req.Header.IsHTTP11() {
+		proto = "HTTP/1.1"
+	}
+	return c.Method() + " " + string(req.RequestURI()) + " " + proto
+}
+
+// clf writes the value of tag the way Apache logs it: "-" when empty or
+// for zero bytes like %b, quotes, backslashes and control characters escaped
+func (l *Logger) clf(buf *bytebufferpool.ByteBuffer, c *fiber.Ctx, start, stop time.Time, tag string) (int, error) {
+	from := buf.Len()
+	if _, err := l.tag(buf, c, start, stop, tag); err != nil {
+		return buf.Len() - from, err
+	}
+	value := string(buf.B[from:])
+	buf.B = buf.B[:from]
+	if value == "" || (tag == strBytesSent && value == "0") {
+		return buf.WriteString("-")
+	}
+	for i := 0; i < len(value); i++ {
+		switch ch := value[i]; {
+		case ch == '"' || ch == '\\':
+			buf.B = append(buf.B, '\\', ch)
+		case ch < 0x20 || ch == 0x7f:
+			buf.B = append(buf.B, '\\', 'x', hexDigits[ch>>4], hexDigits[ch&0xf])
+		default:
+			buf.B = append(buf.B, ch)
+		}
+	}
+	return buf.Len() - from, nil
+}
@@ -0,0 +1,61 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestPresetCommon(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(PresetCommon.Config(), Config{
+		Output: buf,
+		Clock:  &stepClock{now: time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), step: time.Millisecond},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		ctx.Status(204)
+	})
+	app.Get("/users", func(ctx *fiber.Ctx) {
+		ctx.SendString("ok")
+	})
+
+	for _, url := range []string{"/", `/users?q=%22a%22`} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, url, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	expectedOutput := "0.0.0.0 - - [01/Jul/2020:12:00:00 +0000] \"GET / HTTP/1.1\" 204 -\n" +
+		"0.0.0.0 - - [01/Jul/2020:12:00:00 +0000] \"GET /users?q=%22a%22 HTTP/1.1\" 200 2\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestPresetCombined(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(PresetCombined.Config(), Config{
+		Output: buf,
+		Clock:  &stepClock{now: time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), step: time.Millisecond},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		ctx.SendString("ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("User-Agent", "say \"hi\"\x01")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "0.0.0.0 - - [01/Jul/2020:12:00:00 +0000] \"GET / HTTP/1.1\" 200 2 \"-\" \"say \\\"hi\\\"\\x01\"\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,16 @@
+package logger
+
+import "time"
+
+// Clock tells the time, tests can set one to get deterministic ${time} and
+// ${latency} values
+type Clock interface {
+	Now() time.Time
+}
+
+// systemClock is the default Clock
+type systemClock struct{}
+
+func (systemClock) Now() time.Time {
+	return time.Now()
+}
@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// stepClock advances by step on every call
+type stepClock struct {
+	now  time.Time
+	step time.Duration
+}
+
+func (s *stepClock) Now() time.Time {
+	s.now = s.now.Add(s.step)
+	return s.now
+}
+
+func TestClock(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format:     "${time} ${latency}",
+		TimeFormat: time.RFC3339Nano,
+		Output:     buf,
+		Clock:      &stepClock{now: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC), step: 5 * time.Millisecond},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	// NewLogger, start and stop each read the clock
+	expectedOutput := "2020-01-01T12:00:00.015Z 5ms"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,410 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// Limits of PutLogEvents
+const (
+	cloudWatchMaxEvents     = 10000
+	cloudWatchMaxBytes      = 1048576
+	cloudWatchEventSize     = 262144
+	cloudWatchEventOverhead = 26
+	cloudWatchMaxSpan       = 24 * time.Hour
+)
+
+// errCloudWatchBacklog is returned for lines dropped while puts fail
+var errCloudWatchBacklog = errors.New("logger: cloudwatch backlog full, entry dropped")
+
+// CloudWatchConfig configures a CloudWatchWriter
+type CloudWatchConfig struct {
+	// LogGroup is the log group of the stream
+	// Required
+	LogGroup string
+	// LogStream is the stream the lines are put to, e.g. the task or
+	// instance id
+	// Required
+	LogStream string
+	// CreateStream creates the log group and stream when they do not exist
+	// Optional. Default: false
+	CreateStream bool
+	// Region of the log group
+	// Optional. Default: AWS_REGION or AWS_DEFAULT_REGION
+	Region string
+	// AccessKeyID, SecretAccessKey and SessionToken sign the requests
+	// Optional. Default: AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and
+	// AWS_SESSION_TOKEN as set in Lambda, else the credentials of the ECS
+	// task role
+	AccessKeyID, SecretAccessKey, SessionToken string
+	// Endpoint of the CloudWatch Logs API
+	// Optional. Default: "https://logs.<Region>.amazonaws.com"
+	Endpoint string
+	// BatchSize is the number of lines put at once, at most 10000
+	// Optional. Default: 10000
+	BatchSize int
+	// BatchWait is the longest a line waits before it is put
+	// Optional. Default: 1 * time.Second
+	BatchWait time.Duration
+	// Backlog is the number of lines held while puts fail, further lines
+	// are dropped
+	// Optional. Default: 10 * BatchSize
+	Backlog int
+	// Retries is how often a put is retried after a network error,
+	// throttling or 5xx response
+	// Optional. Default: 5
+	Retries int
+	// MinBackoff is the wait before the first retry, doubled per retry
+	// Optional. Default: 500 * time.Millisecond
+	MinBackoff time.Duration
+	// MaxBackoff is the longest wait between retries
+	// Optional. Default: 30 * time.Second
+	MaxBackoff time.Duration
+	// Client sends the requests
+	// Optional. Default: a client with a 10 second timeout
+	Client *http.Client
+}
+
+// cloudWatchEvent is a line with its time in milliseconds
+type cloudWatchEvent struct {
+	timestamp int64
+	message   string
+}
+
+// cloudWatchError is the error body of the CloudWatch Logs API
+type cloudWatchError struct {
+	Type                  string `json:"__type"`
+	Message               string `json:"message"`
+	ExpectedSequenceToken string `json:"expectedSequenceToken"`
+}
+
+// is reports whether the error is of the exception type name
+func (e *cloudWatchError) is(name string) bool {
+	return e.Type == name || strings.HasSuffix(e.Type, "#"+name)
+}
+
+// CloudWatchWriter puts lines to a CloudWatch Logs stream, each write being
+// one event. Lines are batched by BatchSize or after BatchWait and split
+// to stay within the 1 MB and 10000 event limits of a put. Sequence tokens
+// are tracked and resynchronized when another writer put to the stream.
+// Failed puts are retried with exponential backoff
+type CloudWatchWriter struct {
+	cfg    CloudWatchConfig
+	creds  *awsCredentialSource
+	token  string
+	mu     sync.Mutex
+	events []cloudWatchEvent
+	failed bool
+	full   chan struct{}
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewCloudWatchWriter starts putting to the log stream of cfg
+func NewCloudWatchWriter(cfg CloudWatchConfig) (*CloudWatchWriter, error) {
+	if cfg.LogGroup == "" || cfg.LogStream == "" {
+		return nil, errors.New("logger: cloudwatch log group and stream are required")
+	}
+	if cfg.Region == "" {
+		cfg.Region = awsRegion()
+	}
+	if cfg.Region == "" && cfg.Endpoint == "" {
+		return nil, errors.New("logger: cloudwatch region is required")
+	}
+	if cfg.Endpoint == "" {
+		cfg.Endpoint = "https://logs." + cfg.Region + ".amazonaws.com"
+	}
+	if cfg.BatchSize <= 0 || cfg.BatchSize > cloudWatchMaxEvents {
+		cfg.BatchSize = cloudWatchMaxEvents
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = time.Second
+	}
+	if cfg.Backlog <= 0 {
+		cfg.Backlog = 10 * cfg.BatchSize
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = 5
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	creds, err := newAWSCredentialSource(cfg.AccessKeyID, cfg.SecretAccessKey, cfg.SessionToken, cfg.Client)
+	if err != nil {
+		return nil, err
+	}
+	w := &CloudWatchWriter{
+		cfg:    cfg,
+		creds:  creds,
+		full:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write adds p as an event at the current time
+func (w *CloudWatchWriter) Write(p []byte) (int, error) {
+	return w.add(time.Now(), p)
+}
+
+// WriteEntry adds p as an event at the time of e
+func (w *CloudWatchWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return w.add(e.Time, p)
+}
+
+func (w *CloudWatchWriter) add(t time.Time, p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	msg := string(p)
+	// Leave room for the truncation marker
+	if max := cloudWatchEventSize - cloudWatchEventOverhead - 32; len(msg) > max {
+		msg = truncate(msg, max)
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.events) >= w.cfg.Backlog {
+		return 0, errCloudWatchBacklog
+	}
+	w.events = append(w.events, cloudWatchEvent{t.UnixNano() / int64(time.Millisecond), msg})
+	if len(w.events) == w.cfg.BatchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return n, nil
+}
+
+// run puts the batch every BatchWait or once it is full, until Close
+func (w *CloudWatchWriter) run() {
+	ticker := time.NewTicker(w.cfg.BatchWait)
+	defer ticker.Stop()
+	defer close(w.closed)
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.full:
+		case <-w.done:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// flush puts the pending events in time order, split into puts within the
+// API limits. Events of a put still failing after the retries are dropped
+func (w *CloudWatchWriter) flush() {
+	w.mu.Lock()
+	events := w.events
+	w.events = nil
+	w.mu.Unlock()
+	if len(events) == 0 {
+		return
+	}
+	// Concurrent writes may be slightly out of order, a put must not be
+	sort.SliceStable(events, func(i, j int) bool { return events[i].timestamp < events[j].timestamp })
+	var err error
+	for len(events) > 0 {
+		n := cloudWatchSplit(events, w.cfg.BatchSize)
+		if perr := w.push(events[:n]); perr != nil {
+			err = perr
+		}
+		events = events[n:]
+	}
+	w.mu.Lock()
+	w.failed = err != nil
+	w.mu.Unlock()
+}
+
+// cloudWatchSplit returns how many of the sorted events fit in one put: at
+// most max events and 1 MB, spanning less than 24 hours
+func cloudWatchSplit(events []cloudWatchEvent, max int) int {
+	size := 0
+	for i, e := range events {
+		size += len(e.message) + cloudWatchEventOverhead
+		if i == max || size > cloudWatchMaxBytes ||
+			time.Duration(e.timestamp-events[0].timestamp)*time.Millisecond >= cloudWatchMaxSpan {
+			return i
+		}
+	}
+	return len(events)
+}
+
+// push puts events, retrying with exponential backoff. Retries stop on Close
+func (w *CloudWatchWriter) push(events []cloudWatchEvent) error {
+	backoff := w.cfg.MinBackoff
+	for retry := 0; ; retry++ {
+		temporary, err := w.put(events)
+		if err == nil || !temporary || retry == w.cfg.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.done:
+			return err
+		}
+		if backoff *= 2; backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+	}
+}
+
+// put makes a PutLogEvents request, repeating it at once with the expected
+// sequence token or after creating a missing stream. temporary reports
+// whether a failure is worth retrying
+func (w *CloudWatchWriter) put(events []cloudWatchEvent) (temporary bool, err error) {
+	body := bytebufferpool.Get()
+	defer bytebufferpool.Put(body)
+	for attempt := 0; attempt < 3; attempt++ {
+		body.Reset()
+		body.WriteString(`{"logGroupName":`)
+		appendJSONString(body, w.cfg.LogGroup)
+		body.WriteString(`,"logStreamName":`)
+		appendJSONString(body, w.cfg.LogStream)
+		if w.token != "" {
+			body.WriteString(`,"sequenceToken":`)
+			appendJSONString(body, w.token)
+		}
+		body.WriteString(`,"logEvents":[`)
+		for i, e := range events {
+			if i > 0 {
+				body.WriteString(",")
+			}
+			body.WriteString(`{"timestamp":`)
+			body.B = strconv.AppendInt(body.B, e.timestamp, 10)
+			body.WriteString(`,"message":`)
+			appendJSONString(body, e.message)
+			body.WriteString("}")
+		}
+		body.WriteString("]}")
+		var result struct {
+			NextSequenceToken string `json:"nextSequenceToken"`
+		}
+		apiErr, temporary, err := w.call("PutLogEvents", body.B, &result)
+		switch {
+		case err == nil:
+			w.token = result.NextSequenceToken
+			return false, nil
+		case apiErr == nil:
+			return temporary, err
+		case apiErr.is("DataAlreadyAcceptedException"):
+			w.token = apiErr.ExpectedSequenceToken
+			return false, nil
+		case apiErr.is("InvalidSequenceTokenException"):
+			w.token = apiErr.ExpectedSequenceToken
+		case apiErr.is("ResourceNotFoundException") && w.cfg.CreateStream:
+			if err := w.create(); err != nil {
+				return true, err
+			}
+			w.token = ""
+		default:
+			return temporary, err
+		}
+	}
+	return true, errors.New("logger: cloudwatch put failed to synchronize with the stream")
+}
+
+// create creates the log group and stream, existing ones are kept
+func (w *CloudWatchWriter) create() error {
+	group := bytebufferpool.Get()
+	defer bytebufferpool.Put(group)
+	group.WriteString(`{"logGroupName":`)
+	appendJSONString(group, w.cfg.LogGroup)
+	group.WriteString("}")
+	apiErr, _, err := w.call("CreateLogGroup", group.B, nil)
+	if err != nil && (apiErr == nil || !apiErr.is("ResourceAlreadyExistsException")) {
+		return err
+	}
+	stream := bytebufferpool.Get()
+	defer bytebufferpool.Put(stream)
+	stream.WriteString(`{"logGroupName":`)
+	appendJSONString(stream, w.cfg.LogGroup)
+	stream.WriteString(`,"logStreamName":`)
+	appendJSONString(stream, w.cfg.LogStream)
+	stream.WriteString("}")
+	apiErr, _, err = w.call("CreateLogStream", stream.B, nil)
+	if err != nil && (apiErr == nil || !apiErr.is("ResourceAlreadyExistsException")) {
+		return err
+	}
+	return nil
+}
+
+// call makes a signed request to action and decodes the response into
+// result. Errors of the API are returned as apiErr as well, temporary
+// reports whether they are worth retrying
+func (w *CloudWatchWriter) call(action string, body []byte, result interface{}) (apiErr *cloudWatchError, temporary bool, err error) {
+	creds, err := w.creds.get()
+	if err != nil {
+		return nil, true, err
+	}
+	req, err := http.NewRequest(http.MethodPost, w.cfg.Endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, false, err
+	}
+	req.Header.Set("Content-Type", "application/x-amz-json-1.1")
+	req.Header.Set("X-Amz-Target", "Logs_20140328."+action)
+	signAWS(req, body, creds, w.cfg.Region, "logs", time.Now())
+	resp, err := w.cfg.Client.Do(req)
+	if err != nil {
+		return nil, true, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusOK {
+		if result != nil {
+			if err := json.NewDecoder(resp.Body).Decode(result); err != nil {
+				return nil, false, err
+			}
+		}
+		return nil, false, nil
+	}
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 4096))
+	apiErr = &cloudWatchError{}
+	if json.Unmarshal(msg, apiErr) != nil || apiErr.Type == "" {
+		apiErr = nil
+	}
+	err = fmt.Errorf("logger: cloudwatch %s: %s: %s", action, resp.Status, bytes.TrimSpace(msg))
+	temporary = resp.StatusCode >= 500 || resp.StatusCode == http.StatusTooManyRequests ||
+		apiErr != nil && (apiErr.is("ThrottlingException") || apiErr.is("ServiceUnavailableException"))
+	return apiErr, temporary, err
+}
+
+// Connected reports whether the last put succeeded, for Health
+func (w *CloudWatchWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.failed
+}
+
+// Close puts the pending lines without further retries and stops the
+// writer
+func (w *CloudWatchWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.closed
+	return nil
+}
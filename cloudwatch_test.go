@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// cloudWatchRequest is a request received by the fake CloudWatch Logs API
+type cloudWatchRequest struct {
+	Action        string
+	SequenceToken string `json:"sequenceToken"`
+	LogEvents     []struct {
+		Timestamp int64  `json:"timestamp"`
+		Message   string `json:"message"`
+	} `json:"logEvents"`
+}
+
+// cloudWatchServer records the requests and answers them in turn with the
+// responses, "" being an empty success
+func cloudWatchServer(t *testing.T, responses ...string) (*httptest.Server, *[]cloudWatchRequest) {
+	var requests []cloudWatchRequest
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "AWS4-HMAC-SHA256 Credential=AKID/") {
+			t.Errorf("Has: %q, expected: a signed request", r.Header.Get("Authorization"))
+		}
+		var req cloudWatchRequest
+		json.NewDecoder(r.Body).Decode(&req)
+		req.Action = strings.TrimPrefix(r.Header.Get("X-Amz-Target"), "Logs_20140328.")
+		requests = append(requests, req)
+		resp := ""
+		if len(requests) <= len(responses) {
+			resp = responses[len(requests)-1]
+		}
+		if resp == "" {
+			w.Write([]byte(`{"nextSequenceToken":"next"}`))
+			return
+		}
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(resp))
+	}))
+	return server, &requests
+}
+
+func TestCloudWatchWriter(t *testing.T) {
+	server, requests := cloudWatchServer(t,
+		`{"__type":"com.amazonaws.logs#InvalidSequenceTokenException","message":"bad token","expectedSequenceToken":"tok-2"}`)
+	defer server.Close()
+
+	cw, err := NewCloudWatchWriter(CloudWatchConfig{
+		LogGroup:        "/ecs/shop",
+		LogStream:       "task-1",
+		Region:          "eu-west-1",
+		Endpoint:        server.URL,
+		AccessKeyID:     "AKID",
+		SecretAccessKey: "secret",
+		BatchWait:       time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	now := time.Now()
+	cw.WriteEntry(&Entry{Time: now}, []byte("GET /b 200\n"))
+	cw.WriteEntry(&Entry{Time: now.Add(-time.Second)}, []byte("GET /a 200\n"))
+	cw.Close()
+
+	if len(*requests) != 2 {
+		t.Fatalf("Has: %d requests, expected: 2", len(*requests))
+	}
+	retried := (*requests)[1]
+	if retried.Action != "PutLogEvents" || retried.SequenceToken != "tok-2" {
+		t.Errorf("Has: %+v, expected: a put with the expected token", retried)
+	}
+	if len(retried.LogEvents) != 2 || retried.LogEvents[0].Message != "GET /a 200" || retried.LogEvents[1].Message != "GET /b 200" {
+		t.Errorf("Has: %+v, expected: the events in time order", retried.LogEvents)
+	}
+	if !cw.Connected() || cw.token != "next" {
+		t.Errorf("Has: token %q, expected: connected with the next token", cw.token)
+	}
+}
+
+func TestCloudWatchWriter_createStream(t *testing.T) {
+	server, requests := cloudWatchServer(t,
+		`{"__type":"ResourceNotFoundException","message":"The specified log group does not exist."}`,
+		"",
+		`{"__type":"ResourceAlreadyExistsException","message":"exists"}`)
+	defer server.Close()
+
+	cw, err := NewCloudWatchWriter(CloudWatchConfig{
+		LogGroup: "/ecs/shop", LogStream: "task-1", CreateStream: true,
+		Region: "eu-west-1", Endpoint: server.URL, AccessKeyID: "AKID", SecretAccessKey: "secret",
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	cw.Write([]byte("GET / 200\n"))
+	cw.Close()
+
+	var actions []string
+	for _, r := range *requests {
+		actions = append(actions, r.Action)
+	}
+	expected := "PutLogEvents CreateLogGroup CreateLogStream PutLogEvents"
+	if strings.Join(actions, " ") != expected {
+		t.Errorf("Has: %v, expected: %s", actions, expected)
+	}
+}
+
+func TestCloudWatchSplit(t *testing.T) {
+	large := strings.Repeat("x", 200000)
+	events := make([]cloudWatchEvent, 8)
+	for i := range events {
+		events[i] = cloudWatchEvent{int64(i), large}
+	}
+	if n := cloudWatchSplit(events, cloudWatchMaxEvents); n != 5 {
+		t.Errorf("Has: %d, expected: 5 events within 1 MB", n)
+	}
+	if n := cloudWatchSplit(events, 3); n != 3 {
+		t.Errorf("Has: %d, expected: 3 events of the batch size", n)
+	}
+	day := int64(cloudWatchMaxSpan / time.Millisecond)
+	spread := []cloudWatchEvent{{0, "a"}, {day - 1, "b"}, {day, "c"}}
+	if n := cloudWatchSplit(spread, cloudWatchMaxEvents); n != 2 {
+		t.Errorf("Has: %d, expected: 2 events within 24 hours", n)
+	}
+}
+
+func TestNewCloudWatchWriter_invalid(t *testing.T) {
+	if _, err := NewCloudWatchWriter(CloudWatchConfig{LogGroup: "/ecs/shop"}); err == nil {
+		t.Errorf("Has: nil, expected: an error without stream")
+	}
+}
@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"bytes"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// colors are the ANSI escape codes of ${color:<name>}
+var colors = map[string]string{
+	"black":   "\x1b[30m",
+	"red":     "\x1b[31m",
+	"green":   "\x1b[32m",
+	"yellow":  "\x1b[33m",
+	"blue":    "\x1b[34m",
+	"magenta": "\x1b[35m",
+	"cyan":    "\x1b[36m",
+	"white":   "\x1b[37m",
+	"bold":    "\x1b[1m",
+	"reset":   "\x1b[0m",
+}
+
+// statusColor returns the color of a status class
+func statusColor(status int) string {
+	switch {
+	case status >= 500:
+		return colors["red"]
+	case status >= 400:
+		return colors["yellow"]
+	case status >= 300:
+		return colors["cyan"]
+	case status >= 200:
+		return colors["green"]
+	}
+	return ""
+}
+
+// stripANSI removes the ANSI escape sequences from p, so outputs that are
+// not terminals never receive them
+func stripANSI(p []byte) []byte {
+	if bytes.IndexByte(p, 0x1b) < 0 {
+		return p
+	}
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	for i := 0; i < len(p); i++ {
+		if p[i] != 0x1b {
+			buf.B = append(buf.B, p[i])
+			continue
+		}
+		// CSI sequences end with a byte in 0x40-0x7e, other escapes take
+		// a single byte
+		if i+1 < len(p) && p[i+1] == '[' {
+			i += 2
+			for i < len(p) && (p[i] < 0x40 || p[i] > 0x7e) {
+				i++
+			}
+		} else {
+			i++
+		}
+	}
+	return append([]byte(nil), buf.B...)
+}
@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStripANSI(t *testing.T) {
+	colored := "\x1b[32m200\x1b[0m GET \x1b[1;31m/\x1b[0m\n"
+	if s := string(stripANSI([]byte(colored))); s != "200 GET /\n" {
+		t.Errorf("Has: %q, expected: %q", s, "200 GET /\n")
+	}
+
+	buf := &strings.Builder{}
+	out := newOutput("file", buf)
+	out.strip = true
+	if n, err := out.Write([]byte(colored)); err != nil || n != len(colored) {
+		t.Errorf("Has: %d, %v, expected: %d, nil", n, err, len(colored))
+	}
+	if buf.String() != "200 GET /\n" {
+		t.Errorf("Has: %q, expected: %q", buf.String(), "200 GET /\n")
+	}
+}
@@ -0,0 +1,30 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+)
+
+// conditional describes a conditional request: the validators the client
+// sent next to the ones of the response, the status and whether the cached
+// copy of the client was used. It is empty for unconditional requests
+func conditional(ifNoneMatch, etag, ifModifiedSince, lastModified string, status int) string {
+	if ifNoneMatch == "" && ifModifiedSince == "" {
+		return ""
+	}
+	var pairs []string
+	if ifNoneMatch != "" {
+		pairs = append(pairs, "If-None-Match="+ifNoneMatch+" ETag="+etag)
+	}
+	if ifModifiedSince != "" {
+		pairs = append(pairs, "If-Modified-Since="+ifModifiedSince+" Last-Modified="+lastModified)
+	}
+	result := "miss"
+	switch {
+	case status == 304 || status == 412:
+		result = "hit"
+	case status < 200 || status > 299:
+		result = "n/a"
+	}
+	return strings.Join(pairs, ", ") + ", " + strconv.Itoa(status) + " " + result
+}
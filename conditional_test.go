@@ -0,0 +1,23 @@
+package logger
+
+import "testing"
+
+func TestConditional(t *testing.T) {
+	cases := []struct {
+		inm, etag, ims, lm string
+		status             int
+		expected           string
+	}{
+		{"", `"v1"`, "", "", 200, ""},
+		{`"v1"`, `"v1"`, "", "", 304, `If-None-Match="v1" ETag="v1", 304 hit`},
+		{`W/"v1"`, `W/"v2"`, "", "", 200, `If-None-Match=W/"v1" ETag=W/"v2", 200 miss`},
+		{"", "", "Wed, 21 Oct 2015 07:28:00 GMT", "Thu, 22 Oct 2015 07:28:00 GMT", 200,
+			"If-Modified-Since=Wed, 21 Oct 2015 07:28:00 GMT Last-Modified=Thu, 22 Oct 2015 07:28:00 GMT, 200 miss"},
+		{`"v1"`, "", "", "", 404, `If-None-Match="v1" ETag=, 404 n/a`},
+	}
+	for _, tc := range cases {
+		if s := conditional(tc.inm, tc.etag, tc.ims, tc.lm, tc.status); s != tc.expected {
+			t.Errorf("Has: %s, expected: %s", s, tc.expected)
+		}
+	}
+}
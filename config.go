@@ -0,0 +1,286 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/gofiber/fiber"
+)
+
+// CommonFormat is the Apache common log format and CombinedFormat the
+// combined log format, they expect CombinedTimeFormat as TimeFormat
+const (
+	CommonFormat       = "${ip} - - [${time}] \"${clf:requestLine}\" ${status} ${clf:bytesSent}\n"
+	CombinedFormat     = "${ip} - - [${time}] \"${clf:requestLine}\" ${status} ${clf:bytesSent} \"${clf:referer}\" \"${clf:ua}\"\n"
+	CombinedTimeFormat = "02/Jan/2006:15:04:05 -0700"
+)
+
+// Preset is a named base configuration to layer own settings on
+type Preset int
+
+// Presets
+const (
+	PresetDefault Preset = iota
+	PresetCombined
+	// PresetDev renders human friendly entries for local development
+	PresetDev
+	// PresetAnalytics logs AnalyticsFormat and skips bots, to feed product
+	// analytics without personal data
+	PresetAnalytics
+	// PresetCommon logs CommonFormat for tooling reading Apache access logs
+	PresetCommon
+)
+
+// Config returns the configuration of the preset
+func (p Preset) Config() Config {
+	switch p {
+	case PresetCombined:
+		return Config{Format: CombinedFormat, TimeFormat: CombinedTimeFormat}
+	case PresetCommon:
+		return Config{Format: CommonFormat, TimeFormat: CombinedTimeFormat}
+	case PresetDev:
+		return Config{
+			Format:     "${time} ${statusSymbol}${status} ${method} ${latency} ${bytesSent} ${path} ${error}\n",
+			TimeFormat: "15:04:05.000",
+			Human:      true,
+		}
+	case PresetAnalytics:
+		return Config{
+			Format:     AnalyticsFormat,
+			TimeFormat: time.RFC3339,
+			Filter: func(c *fiber.Ctx) bool {
+				return isBot(c.Get(fiber.HeaderUserAgent))
+			},
+		}
+	}
+	return Config{}
+}
+
+// Merge layers configs on top of each other, fields set in later configs
+// override those of earlier ones. Nested structs are merged field by field,
+// slices, maps and functions are replaced as a whole. Boolean fields can
+// only be switched on. New and NewLogger merge the configs they are given,
+// so a common layering is
+//
+//	logger.New(logger.PresetCombined.Config(), envConfig, logger.Config{...})
+func Merge(configs ...Config) Config {
+	var cfg Config
+	for i := range configs {
+		merge(reflect.ValueOf(&cfg).Elem(), reflect.ValueOf(configs[i]))
+	}
+	return cfg
+}
+
+func merge(dst, src reflect.Value) {
+	for i := 0; i < src.NumField(); i++ {
+		d, s := dst.Field(i), src.Field(i)
+		switch {
+		case s.Kind() == reflect.Struct && ownType(s.Type()):
+			merge(d, s)
+		case s.Kind() == reflect.Ptr && ownType(s.Type().Elem()) && s.Elem().Kind() == reflect.Struct && !d.IsNil() && !s.IsNil():
+			merged := reflect.New(s.Elem().Type())
+			merged.Elem().Set(d.Elem())
+			merge(merged.Elem(), s.Elem())
+			d.Set(merged)
+		case !isZero(s):
+			d.Set(s)
+		}
+	}
+}
+
+// ownType reports whether t is declared by this package, like FileConfig.
+// Other structs like time.Location are replaced as a whole
+func ownType(t reflect.Type) bool {
+	return t.PkgPath() == reflect.TypeOf(Config{}).PkgPath()
+}
+
+// isZero reports whether v is the zero value of its type, as
+// reflect.Value.IsZero of Go 1.13. It reads no values through Interface,
+// so it takes unexported fields
+func isZero(v reflect.Value) bool {
+	switch v.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Func, reflect.Ptr, reflect.Interface, reflect.Chan, reflect.UnsafePointer:
+		return v.IsNil()
+	case reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if !isZero(v.Index(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Struct:
+		for i := 0; i < v.NumField(); i++ {
+			if !isZero(v.Field(i)) {
+				return false
+			}
+		}
+		return true
+	case reflect.Bool:
+		return !v.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return v.Int() == 0
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return v.Uint() == 0
+	case reflect.Float32, reflect.Float64:
+		return v.Float() == 0
+	case reflect.Complex64, reflect.Complex128:
+		return v.Complex() == 0
+	case reflect.String:
+		return v.Len() == 0
+	}
+	return false
+}
+
+// FromEnv reads a Config from environment variables named after the
+// fields with the given prefix, e.g. LOGGER_FORMAT or LOGGER_SLOW_THRESHOLD
+// for the prefix "LOGGER". Strings, numbers, booleans and durations are
+// supported, unset variables leave the field empty.
+func FromEnv(prefix string) (Config, error) {
+	var cfg Config
+	v := reflect.ValueOf(&cfg).Elem()
+	for i := 0; i < v.NumField(); i++ {
+		field := v.Type().Field(i)
+		key := prefix + "_" + envName(field.Name)
+		value, ok := os.LookupEnv(key)
+		if !ok {
+			continue
+		}
+		f := v.Field(i)
+		var err error
+		switch {
+		case f.Type() == reflect.TypeOf(time.Duration(0)):
+			var d time.Duration
+			if d, err = time.ParseDuration(value); err == nil {
+				f.SetInt(int64(d))
+			}
+		case f.Kind() == reflect.String:
+			f.SetString(value)
+		case f.Kind() == reflect.Bool:
+			var b bool
+			if b, err = strconv.ParseBool(value); err == nil {
+				f.SetBool(b)
+			}
+		case f.Kind() == reflect.Int:
+			var n int64
+			if n, err = strconv.ParseInt(value, 10, 64); err == nil {
+				f.SetInt(n)
+			}
+		case f.Kind() == reflect.Float64:
+			var n float64
+			if n, err = strconv.ParseFloat(value, 64); err == nil {
+				f.SetFloat(n)
+			}
+		default:
+			err = fmt.Errorf("unsupported type %s", f.Type())
+		}
+		if err != nil {
+			return cfg, fmt.Errorf("logger: %s: %v", key, err)
+		}
+	}
+	return cfg, nil
+}
+
+// envName converts a field name like DNSCacheSize to DNS_CACHE_SIZE
+func envName(name string) string {
+	r := []rune(name)
+	var b strings.Builder
+	for i := range r {
+		if i > 0 && unicode.IsUpper(r[i]) &&
+			(unicode.IsLower(r[i-1]) || (i+1 < len(r) && unicode.IsLower(r[i+1]))) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r[i]))
+	}
+	return b.String()
+}
+
+// Config returns the resolved configuration of the logger, including defaults
+func (l *Logger) Config() Config {
+	return l.cfg
+}
+
+// DumpConfig writes the resolved configuration, one field per line, so the
+// outcome of layering can be checked. Functions and writers are written as
+// their type. Secrets like capture tokens, the values of Remote headers and
+// passwords in URLs are redacted, so the dump can be shared
+func (l *Logger) DumpConfig(w io.Writer) error {
+	v := reflect.ValueOf(l.cfg)
+	for i := 0; i < v.NumField(); i++ {
+		name := v.Type().Field(i).Name
+		if _, err := fmt.Fprintf(w, "%s: %s\n", name, strconv.Quote(dumpField(name, v.Field(i)))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// secretFields are the names of config fields holding a secret as a string
+var secretFields = map[string]bool{"Token": true, "Password": true, "Secret": true, "APIKey": true}
+
+// dumpField formats the config field name, redacting secrets. Header
+// fields that are maps keep their names but not their values, like an
+// Authorization header
+func dumpField(name string, f reflect.Value) string {
+	switch {
+	case f.Kind() == reflect.String && secretFields[name] && f.Len() > 0:
+		return redacted
+	case f.Kind() == reflect.String && name == "URL":
+		if u, err := url.Parse(f.String()); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), "xxxxx")
+				return u.String()
+			}
+		}
+	case f.Kind() == reflect.Map && strings.HasPrefix(name, "Header") && !f.IsNil():
+		keys := make([]string, 0, f.Len())
+		for _, k := range f.MapKeys() {
+			keys = append(keys, k.String()+":"+redacted)
+		}
+		sort.Strings(keys)
+		return "map[" + strings.Join(keys, " ") + "]"
+	}
+	return dumpValue(f)
+}
+
+// dumpValue formats a config value, writers and functions by their type
+func dumpValue(f reflect.Value) string {
+	switch f.Kind() {
+	case reflect.Ptr:
+		if !f.IsNil() && f.Elem().Kind() == reflect.Struct && ownType(f.Type().Elem()) {
+			return "&" + dumpValue(f.Elem())
+		}
+	case reflect.Func, reflect.Interface:
+		if f.IsNil() {
+			return "<nil>"
+		}
+		return fmt.Sprintf("%T", f.Interface())
+	case reflect.Slice:
+		if f.Type().Elem().Kind() != reflect.Struct {
+			break
+		}
+		values := make([]string, f.Len())
+		for i := range values {
+			values[i] = dumpValue(f.Index(i))
+		}
+		return "[" + strings.Join(values, " ") + "]"
+	case reflect.Struct:
+		if f.Type() == reflect.TypeOf(time.Time{}) {
+			break
+		}
+		fields := make([]string, f.NumField())
+		for i := range fields {
+			name := f.Type().Field(i).Name
+			fields[i] = name + ":" + dumpField(name, f.Field(i))
+		}
+		return "{" + strings.Join(fields, " ") + "}"
+	}
+	return fmt.Sprintf("%+v", f.Interface())
+}
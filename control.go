@@ -0,0 +1,176 @@
+package logger
+
+import (
+	"crypto/subtle"
+	"encoding/json"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber"
+)
+
+// redacted replaces the values of redacted fields
+const redacted = "[REDACTED]"
+
+// policy holds the settings adjustable at runtime. A policy is not changed
+// once stored, so an entry sees all settings of the same update
+type policy struct {
+	level   Priority
+	rate    float64
+	redact  []string
+	version string
+}
+
+// defaultPolicy logs all entries as they are
+var defaultPolicy = &policy{rate: 1}
+
+// control holds the current policy, read once per entry
+type control struct {
+	mu sync.Mutex
+	p  atomic.Value
+}
+
+// policy returns the current policy
+func (l *Logger) policy() *policy {
+	if p, ok := l.control.p.Load().(*policy); ok {
+		return p
+	}
+	return defaultPolicy
+}
+
+// update stores a copy of the current policy changed by fn
+func (l *Logger) update(fn func(p *policy)) {
+	l.control.mu.Lock()
+	defer l.control.mu.Unlock()
+	p := *l.policy()
+	fn(&p)
+	l.control.p.Store(&p)
+}
+
+// SetLevel logs only entries of priority p or higher from now on, e.g.
+// PriorityError to cut logging to errors during an incident
+func (l *Logger) SetLevel(p Priority) {
+	l.update(func(pol *policy) { pol.level = p })
+	l.diag(LevelInfo, "level set to %s", p)
+}
+
+// Level returns the lowest priority logged
+func (l *Logger) Level() Priority {
+	return l.policy().level
+}
+
+// SetSampleRate logs the fraction rate (0..1) of entries from now on. Like
+// sinks, entries are sampled by their request id, see SampleSeed
+func (l *Logger) SetSampleRate(rate float64) {
+	rate = math.Min(math.Max(rate, 0), 1)
+	l.update(func(p *policy) { p.rate = rate })
+	l.diag(LevelInfo, "sample rate set to %g", rate)
+}
+
+// SampleRate returns the fraction of entries logged
+func (l *Logger) SampleRate() float64 {
+	return l.policy().rate
+}
+
+// allows reports whether an entry of priority p passes the level and
+// sample rate
+func (p *policy) allows(c *fiber.Ctx, priority Priority, seed string) bool {
+	if priority < p.level {
+		return false
+	}
+	if p.rate < 1 {
+		return sample(seed, traceID(c), p.rate)
+	}
+	return true
+}
+
+// redactEntry replaces the values of the fields of p.redact in e, keys
+// ending in * match any suffix. Of the core fields path and ip are
+// redacted as well when listed
+func (p *policy) redactEntry(e *Entry) {
+	for _, key := range p.redact {
+		prefix := strings.TrimSuffix(key, "*")
+		for i := range e.Fields {
+			if e.Fields[i].Key == key || prefix != key && strings.HasPrefix(e.Fields[i].Key, prefix) {
+				e.Fields[i].Value = redacted
+			}
+		}
+		switch key {
+		case "path":
+			e.Path = redacted
+		case "ip":
+			e.IP = redacted
+		}
+	}
+}
+
+// adminState is the response of AdminHandler
+type adminState struct {
+	Level    string  `json:"level"`
+	Sampling float64 `json:"sampling"`
+}
+
+// AdminHandler returns a handler adjusting the logger at runtime, for
+// tuning logging with curl during incidents instead of redeploying.
+// PUT .../loglevel takes a priority ("ok", "slow" or "error") as body and
+// PUT .../sampling a rate from 0 to 1, GET returns both. Requests must
+// carry "Authorization: Bearer <token>", an empty token panics
+func (l *Logger) AdminHandler(token string) func(*fiber.Ctx) {
+	if token == "" {
+		panic("logger: admin token is required")
+	}
+	return func(c *fiber.Ctx) {
+		auth := c.Get(fiber.HeaderAuthorization)
+		if !strings.HasPrefix(auth, "Bearer ") || subtle.ConstantTimeCompare([]byte(auth[len("Bearer "):]), []byte(token)) != 1 {
+			c.Status(fiber.StatusUnauthorized).SendString("logger: invalid token")
+			return
+		}
+		if c.Method() == fiber.MethodPut {
+			if err := l.adjust(c.Path(), strings.TrimSpace(c.Body())); err != nil {
+				c.Status(fiber.StatusBadRequest).SendString(err.Error())
+				return
+			}
+		} else if c.Method() != fiber.MethodGet {
+			c.Status(fiber.StatusMethodNotAllowed)
+			return
+		}
+		body, _ := json.Marshal(adminState{Level: l.Level().String(), Sampling: l.SampleRate()})
+		c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+		c.SendBytes(body)
+	}
+}
+
+// adjust applies value to the setting at the end of path
+func (l *Logger) adjust(path, value string) error {
+	switch {
+	case strings.HasSuffix(path, "/loglevel"):
+		p, err := parseLevel(value)
+		if err != nil {
+			return err
+		}
+		l.SetLevel(p)
+		return nil
+	case strings.HasSuffix(path, "/sampling"):
+		rate, err := strconv.ParseFloat(value, 64)
+		if err != nil || rate < 0 || rate > 1 {
+			return fmt.Errorf("logger: invalid sample rate %q, expected 0 to 1", value)
+		}
+		l.SetSampleRate(rate)
+		return nil
+	}
+	return fmt.Errorf("logger: unknown setting %s", path)
+}
+
+// parseLevel returns the priority named value
+func parseLevel(value string) (Priority, error) {
+	for _, p := range []Priority{PriorityOK, PrioritySlow, PriorityError} {
+		if value == p.String() {
+			return p, nil
+		}
+	}
+	return 0, fmt.Errorf("logger: unknown level %q, expected ok, slow or error", value)
+}
@@ -0,0 +1,78 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestLogger_AdminHandler(t *testing.T) {
+	buf := &strings.Builder{}
+	l := NewLogger(Config{
+		Format:      "${path} ",
+		Output:      buf,
+		Diagnostics: ioutil.Discard,
+	})
+	app := fiber.New()
+	app.Put("/admin/*", l.AdminHandler("secret"))
+	app.Get("/admin/*", l.AdminHandler("secret"))
+	app.Use(l.Handle)
+	app.Get("/ok", func(ctx *fiber.Ctx) {})
+	app.Get("/error", func(ctx *fiber.Ctx) { ctx.SendStatus(500) })
+
+	admin := func(method, path, token, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Length", strconv.Itoa(len(body)))
+		req.Header.Set("Authorization", "Bearer "+token)
+		resp, err := app.Test(req, 1000)
+		if err != nil {
+			t.Fatalf("Has: %+v, expected: nil", err)
+		}
+		b, _ := ioutil.ReadAll(resp.Body)
+		return resp.StatusCode, string(b)
+	}
+	if status, _ := admin(http.MethodPut, "/admin/loglevel", "wrong", "error"); status != 401 {
+		t.Errorf("Has: %d, expected: 401 for a wrong token", status)
+	}
+	if status, _ := admin(http.MethodPut, "/admin/loglevel", "secret", "loud"); status != 400 {
+		t.Errorf("Has: %d, expected: 400 for an unknown level", status)
+	}
+	if status, body := admin(http.MethodPut, "/admin/loglevel", "secret", "error\n"); status != 200 || body != `{"level":"error","sampling":1}` {
+		t.Errorf("Has: %d %s, expected: the level set to error", status, body)
+	}
+	for _, path := range []string{"/ok", "/error"} {
+		app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000)
+	}
+	if buf.String() != "/error " {
+		t.Errorf("Has: %q, expected: only the error", buf.String())
+	}
+
+	if status, body := admin(http.MethodPut, "/admin/sampling", "secret", "0"); status != 200 || body != `{"level":"error","sampling":0}` {
+		t.Errorf("Has: %d %s, expected: the sample rate set to 0", status, body)
+	}
+	app.Test(httptest.NewRequest(http.MethodGet, "/error", nil), 1000)
+	if buf.String() != "/error " {
+		t.Errorf("Has: %q, expected: no further entries", buf.String())
+	}
+	if status, body := admin(http.MethodGet, "/admin/", "secret", ""); status != 200 || body != `{"level":"error","sampling":0}` {
+		t.Errorf("Has: %d %s, expected: the current settings", status, body)
+	}
+}
+
+func TestLogger_SetSampleRate(t *testing.T) {
+	l := NewLogger(Config{Diagnostics: ioutil.Discard})
+	if rate := l.SampleRate(); rate != 1 {
+		t.Errorf("Has: %g, expected: 1 by default", rate)
+	}
+	for _, rate := range []float64{0.1, 0, 1} {
+		l.SetSampleRate(rate)
+		if has := l.SampleRate(); has != rate {
+			t.Errorf("Has: %g, expected: %g", has, rate)
+		}
+	}
+}
@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber"
+)
+
+// Locals keys of the correlation data, LocalsRequestID is shared with
+// fiber's requestid middleware
+const (
+	LocalsRequestID    = "requestid"           // string
+	LocalsTraceContext = "logger.traceContext" // TraceContext
+)
+
+// Trace context headers
+const (
+	HeaderTraceparent = "traceparent"
+	HeaderBaggage     = "baggage"
+	HeaderB3          = "b3"
+	HeaderB3TraceID   = "X-B3-TraceId"
+	HeaderB3SpanID    = "X-B3-SpanId"
+	HeaderB3ParentID  = "X-B3-ParentSpanId"
+	HeaderB3Sampled   = "X-B3-Sampled"
+	HeaderUberTraceID = "uber-trace-id"
+)
+
+// Lengths of W3C ids in hex characters
+const (
+	traceIDLength = 32
+	spanIDLength  = 16
+)
+
+// maxRequestIDLength is the most bytes of an X-Request-ID taken from the
+// caller, longer ids are cut at a character boundary
+const maxRequestIDLength = 64
+
+// Propagation is a format trace context is passed between services in
+type Propagation int
+
+// Propagation formats
+const (
+	// PropagationW3C uses the traceparent header
+	PropagationW3C Propagation = iota
+	// PropagationB3 uses the b3 or X-B3-* headers of Zipkin
+	PropagationB3
+	// PropagationJaeger uses the uber-trace-id header
+	PropagationJaeger
+)
+
+// TraceContext is the W3C trace context of a request
+type TraceContext struct {
+	// TraceID identifies the whole trace, 32 hex characters
+	TraceID string
+	// SpanID identifies the handling of this request and is the parent of
+	// outbound calls, 16 hex characters
+	SpanID string
+	// ParentID is the span of the caller, empty when the trace started here
+	ParentID string
+	// Sampled is the sampled flag of the caller, true for new traces
+	Sampled bool
+	// propagation are the formats Inject writes
+	propagation []Propagation
+}
+
+// Traceparent returns the traceparent header value for outbound calls
+func (t TraceContext) Traceparent() string {
+	flags := "00"
+	if t.Sampled {
+		flags = "01"
+	}
+	return "00-" + t.TraceID + "-" + t.SpanID + "-" + flags
+}
+
+// correlate takes the request id and trace context of the caller or starts
+// new ones and stores them in the request locals. The trace context is taken
+// from the first of the propagation formats the request carries
+func correlate(c *fiber.Ctx, propagation []Propagation) {
+	id := c.Get(fiber.HeaderXRequestID)
+	if len(id) > maxRequestIDLength {
+		cut := maxRequestIDLength
+		for cut > 0 && !utf8.RuneStart(id[cut]) {
+			cut--
+		}
+		id = id[:cut]
+	}
+	if id == "" {
+		id = newID(16)
+	}
+	c.Locals(LocalsRequestID, id)
+	c.Set(fiber.HeaderXRequestID, id)
+
+	tc := TraceContext{SpanID: newID(8), Sampled: true, propagation: propagation}
+	for _, p := range propagation {
+		if extract(c, p, &tc) {
+			break
+		}
+	}
+	if tc.TraceID == "" {
+		tc.TraceID = newID(16)
+	}
+	c.Locals(LocalsTraceContext, tc)
+}
+
+// extract reads the trace and parent span of the caller in format p
+func extract(c *fiber.Ctx, p Propagation, tc *TraceContext) bool {
+	var trace, parent, sampled string
+	switch p {
+	case PropagationW3C:
+		parts := strings.Split(c.Get(HeaderTraceparent), "-")
+		if len(parts) != 4 {
+			return false
+		}
+		trace, parent, sampled = parts[1], parts[2], parts[3]
+		tc.Sampled = sampled == "01"
+	case PropagationB3:
+		if single := c.Get(HeaderB3); single != "" {
+			parts := strings.Split(single, "-")
+			if len(parts) < 2 {
+				return false
+			}
+			trace, parent = parts[0], parts[1]
+			if len(parts) > 2 {
+				sampled = parts[2]
+			}
+		} else {
+			trace, parent, sampled = c.Get(HeaderB3TraceID), c.Get(HeaderB3SpanID), c.Get(HeaderB3Sampled)
+		}
+		tc.Sampled = sampled != "0"
+	case PropagationJaeger:
+		header, _ := url.PathUnescape(c.Get(HeaderUberTraceID))
+		parts := strings.Split(header, ":")
+		if len(parts) != 4 {
+			return false
+		}
+		trace, parent, sampled = parts[0], parts[1], parts[3]
+		flags, _ := strconv.ParseUint(sampled, 16, 8)
+		tc.Sampled = flags&1 == 1
+	}
+	if !isHex(trace, traceIDLength) || !isHex(parent, spanIDLength) {
+		return false
+	}
+	tc.TraceID = leftPad(trace, traceIDLength)
+	tc.ParentID = leftPad(parent, spanIDLength)
+	return true
+}
+
+// isHex reports whether s is a non-empty hex string of at most n characters
+func isHex(s string, n int) bool {
+	if s == "" || len(s) > n {
+		return false
+	}
+	for i := 0; i < len(s); i++ {
+		if !('0' <= s[i] && s[i] <= '9' || 'a' <= s[i] && s[i] <= 'f' || 'A' <= s[i] && s[i] <= 'F') {
+			return false
+		}
+	}
+	return true
+}
+
+// leftPad widens 64 bit B3 and Jaeger ids to the W3C length
+func leftPad(id string, n int) string {
+	return strings.Repeat("0", n-len(id)) + strings.ToLower(id)
+}
+
+// newID returns n random bytes hex encoded
+func newID(n int) string {
+	b := make([]byte, n)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// RequestID returns the id of the request, taken from X-Request-ID or
+// generated when Config.RequestID is enabled
+func RequestID(c *fiber.Ctx) string {
+	id, _ := c.Locals(LocalsRequestID).(string)
+	return id
+}
+
+// Trace returns the trace context of the request when Config.RequestID is
+// enabled
+func Trace(c *fiber.Ctx) TraceContext {
+	tc, _ := c.Locals(LocalsTraceContext).(TraceContext)
+	return tc
+}
+
+// Inject passes the request id and trace context of c on to an outbound
+// call through set, e.g. the Set method of its headers, so the correlation
+// created by the logger propagates across services
+//
+//	req := fasthttp.AcquireRequest()
+//	logger.Inject(c, req.Header.Set)
+func Inject(c *fiber.Ctx, set func(key, value string)) {
+	if id := RequestID(c); id != "" {
+		set(fiber.HeaderXRequestID, id)
+	}
+	if tc := Trace(c); tc.TraceID != "" {
+		for _, p := range tc.propagation {
+			switch p {
+			case PropagationW3C:
+				set(HeaderTraceparent, tc.Traceparent())
+			case PropagationB3:
+				sampled := "0"
+				if tc.Sampled {
+					sampled = "1"
+				}
+				set(HeaderB3TraceID, tc.TraceID)
+				set(HeaderB3SpanID, newID(8))
+				set(HeaderB3ParentID, tc.SpanID)
+				set(HeaderB3Sampled, sampled)
+			case PropagationJaeger:
+				flags := "0"
+				if tc.Sampled {
+					flags = "1"
+				}
+				set(HeaderUberTraceID, tc.TraceID+":"+newID(8)+":"+tc.SpanID+":"+flags)
+			}
+		}
+	}
+	if baggage := c.Get(HeaderBaggage); baggage != "" {
+		set(HeaderBaggage, baggage)
+	}
+}
+
+// baggage returns the value of key in a W3C baggage header like
+// "tenant=acme,user=42;prop=1", values are percent decoded
+func baggage(header, key string) string {
+	for _, member := range strings.Split(header, ",") {
+		if i := strings.IndexByte(member, ';'); i >= 0 {
+			member = member[:i]
+		}
+		i := strings.IndexByte(member, '=')
+		if i < 0 || strings.TrimSpace(member[:i]) != key {
+			continue
+		}
+		value := strings.TrimSpace(member[i+1:])
+		if unescaped, err := url.PathUnescape(value); err == nil {
+			return unescaped
+		}
+		return value
+	}
+	return ""
+}
@@ -0,0 +1,123 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestInject(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format:    "${requestId} ${traceId} ${spanId}",
+		Output:    buf,
+		RequestID: true,
+	}))
+	outbound := map[string]string{}
+	app.Get("/", func(ctx *fiber.Ctx) {
+		Inject(ctx, func(key, value string) {
+			outbound[key] = value
+		})
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", "req-1")
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	resp, err := app.Test(req, 1000)
+	if err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	if id := resp.Header.Get("X-Request-ID"); id != "req-1" {
+		t.Errorf("Has: %s, expected: req-1", id)
+	}
+
+	fields := strings.Fields(buf.String())
+	if len(fields) != 3 || fields[0] != "req-1" || fields[1] != "4bf92f3577b34da6a3ce929d0e0e4736" || len(fields[2]) != 16 {
+		t.Errorf("Has: %s, expected: request id, trace id and span id", buf.String())
+	}
+	expected := "00-4bf92f3577b34da6a3ce929d0e0e4736-" + fields[len(fields)-1] + "-01"
+	if outbound["traceparent"] != expected || outbound["X-Request-ID"] != "req-1" {
+		t.Errorf("Has: %v, expected: traceparent %s", outbound, expected)
+	}
+}
+
+func TestBaggage(t *testing.T) {
+	header := "userId=alice, tenant = acme%20corp;ttl=60,empty="
+	if v := baggage(header, "tenant"); v != "acme corp" {
+		t.Errorf("Has: %s, expected: acme corp", v)
+	}
+	if v := baggage(header, "missing"); v != "" {
+		t.Errorf("Has: %s, expected: empty", v)
+	}
+}
+
+func TestPropagation(t *testing.T) {
+	cases := []struct {
+		propagation []Propagation
+		headers     map[string]string
+		trace       string
+		parent      string
+		sampled     bool
+	}{
+		{[]Propagation{PropagationB3}, map[string]string{"X-B3-TraceId": "a3ce929d0e0e4736", "X-B3-SpanId": "00f067aa0ba902b7", "X-B3-Sampled": "1"}, "0000000000000000a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{[]Propagation{PropagationB3}, map[string]string{"b3": "4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-0"}, "4bf92f3577b34da6a3ce929d0e0e4736", "00f067aa0ba902b7", false},
+		{[]Propagation{PropagationJaeger}, map[string]string{"uber-trace-id": "a3ce929d0e0e4736%3A00f067aa0ba902b7%3A0%3A1"}, "0000000000000000a3ce929d0e0e4736", "00f067aa0ba902b7", true},
+		{[]Propagation{PropagationW3C, PropagationJaeger}, map[string]string{"uber-trace-id": "a3ce929d0e0e4736:f067aa0ba902b7:0:0"}, "0000000000000000a3ce929d0e0e4736", "00f067aa0ba902b7", false},
+		{[]Propagation{PropagationW3C}, map[string]string{"X-B3-TraceId": "a3ce929d0e0e4736", "X-B3-SpanId": "00f067aa0ba902b7"}, "", "", true},
+	}
+	for _, tc := range cases {
+		app := fiber.New()
+		app.Use(New(Config{Format: "", Output: &strings.Builder{}, RequestID: true, Propagation: tc.propagation}))
+		var trace TraceContext
+		outbound := map[string]string{}
+		app.Get("/", func(ctx *fiber.Ctx) {
+			trace = Trace(ctx)
+			Inject(ctx, func(key, value string) {
+				outbound[key] = value
+			})
+		})
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		for k, v := range tc.headers {
+			req.Header.Set(k, v)
+		}
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+		if tc.trace != "" && (trace.TraceID != tc.trace || trace.ParentID != tc.parent || trace.Sampled != tc.sampled) {
+			t.Errorf("Has: %+v, expected: trace %s, parent %s, sampled %t", trace, tc.trace, tc.parent, tc.sampled)
+		}
+		if tc.trace == "" && (trace.ParentID != "" || len(trace.TraceID) != 32) {
+			t.Errorf("Has: %+v, expected: new trace", trace)
+		}
+		switch tc.propagation[len(tc.propagation)-1] {
+		case PropagationB3:
+			if outbound["X-B3-TraceId"] != trace.TraceID || outbound["X-B3-ParentSpanId"] != trace.SpanID {
+				t.Errorf("Has: %v, expected: X-B3 headers for %+v", outbound, trace)
+			}
+		case PropagationJaeger:
+			if !strings.HasPrefix(outbound["uber-trace-id"], trace.TraceID+":") || !strings.Contains(outbound["uber-trace-id"], ":"+trace.SpanID+":") {
+				t.Errorf("Has: %v, expected: uber-trace-id for %+v", outbound, trace)
+			}
+		}
+	}
+}
+
+func TestCorrelate_longRequestID(t *testing.T) {
+	app := fiber.New()
+	app.Use(New(Config{Output: ioutil.Discard, RequestID: true}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("X-Request-ID", strings.Repeat("é", 100))
+	resp, err := app.Test(req, 1000)
+	if err != nil {
+		t.Fatalf("Has: %+v, expected: nil", err)
+	}
+	if id := resp.Header.Get("X-Request-ID"); id != strings.Repeat("é", maxRequestIDLength/2) {
+		t.Errorf("Has: %d bytes, expected: %d", len(id), maxRequestIDLength)
+	}
+}
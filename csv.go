@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// FormatCSV and FormatTSV as Format write entries with a CSVEncoder
+const (
+	FormatCSV = "csv"
+	FormatTSV = "tsv"
+)
+
+// csvColumns are the columns every row starts with
+var csvColumns = []string{"time", "method", "path", "route", "status", "latency_ms", "ip", "error"}
+
+// CSVEncoder writes entries as rows of comma or tab separated values, to load
+// logs into spreadsheets, BigQuery or pandas. Every output, and every file a
+// FileWriter starts, begins with a header row of the columns time, method,
+// path, route, status, latency_ms, ip, error and the tags in Fields. Values
+// are quoted when needed, fields added by processors are left out to keep
+// the columns fixed
+type CSVEncoder struct {
+	// Comma separates the values, e.g. '\t' for TSV
+	// Optional. Default: ','
+	Comma byte
+	// TimeFormat is the format of the time column
+	// Optional. Default: time.RFC3339Nano
+	TimeFormat string
+	// Fields are tags written as columns of their own, e.g. {"ua"}
+	// Optional. Default: nil
+	Fields []string
+}
+
+// Tags returns Fields, the tags the encoder writes
+func (c *CSVEncoder) Tags() []string {
+	return c.Fields
+}
+
+// Header writes the header row of the columns
+func (c *CSVEncoder) Header(buf *bytebufferpool.ByteBuffer) {
+	comma := c.comma()
+	for i, column := range append(csvColumns, c.Fields...) {
+		if i > 0 {
+			buf.B = append(buf.B, comma)
+		}
+		appendCSVValue(buf, column, comma)
+	}
+	buf.WriteString("\n")
+}
+
+// Encode writes e as a row
+func (c *CSVEncoder) Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error {
+	comma := c.comma()
+	timeFormat := c.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+	var err string
+	if e.Err != nil {
+		err = e.Err.Error()
+	}
+	for i, value := range [...]string{
+		e.Time.Format(timeFormat), e.Method, e.Path, e.Route, strconv.Itoa(e.Status),
+		strconv.FormatFloat(milliseconds(e.Latency), 'f', -1, 64), e.IP, err,
+	} {
+		if i > 0 {
+			buf.B = append(buf.B, comma)
+		}
+		appendCSVValue(buf, value, comma)
+	}
+	for _, tag := range c.Fields {
+		buf.B = append(buf.B, comma)
+		v, _ := e.Get(tag)
+		value, ok := v.(string)
+		if !ok && v != nil {
+			value = fmt.Sprint(v)
+		}
+		appendCSVValue(buf, value, comma)
+	}
+	buf.WriteString("\n")
+	return nil
+}
+
+func (c *CSVEncoder) comma() byte {
+	if c.Comma == 0 {
+		return ','
+	}
+	return c.Comma
+}
+
+// appendCSVValue writes value, quoted with doubled quotes when it holds the
+// separator, a quote or a line break
+func appendCSVValue(buf *bytebufferpool.ByteBuffer, value string, comma byte) {
+	if !strings.ContainsAny(value, "\"\r\n") && strings.IndexByte(value, comma) < 0 {
+		buf.WriteString(value)
+		return
+	}
+	buf.B = append(buf.B, '"')
+	for i := 0; i < len(value); i++ {
+		if value[i] == '"' {
+			buf.B = append(buf.B, '"')
+		}
+		buf.B = append(buf.B, value[i])
+	}
+	buf.B = append(buf.B, '"')
+}
@@ -0,0 +1,136 @@
+package logger
+
+import (
+	"encoding/csv"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestCSVEncoder(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: FormatCSV,
+		Fields: []string{"ua"},
+		Output: buf,
+		Clock:  &stepClock{now: time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), step: time.Millisecond},
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {})
+
+	for _, ua := range []string{`say "hi", bye`, "curl"} {
+		req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+		req.Header.Set("User-Agent", ua)
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	rows, err := csv.NewReader(strings.NewReader(buf.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("Has: %v for %s, expected: CSV", err, buf.String())
+	}
+	expected := [][]string{
+		{"time", "method", "path", "route", "status", "latency_ms", "ip", "error", "ua"},
+		{"2020-07-01T12:00:00.003Z", "GET", "/users/1", "/users/:id", "200", "1", "0.0.0.0", "", `say "hi", bye`},
+		{"2020-07-01T12:00:00.005Z", "GET", "/users/1", "/users/:id", "200", "1", "0.0.0.0", "", "curl"},
+	}
+	if !reflect.DeepEqual(rows, expected) {
+		t.Errorf("Has: %q, expected: %q", rows, expected)
+	}
+}
+
+func TestCSVEncoder_tsv(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: FormatTSV,
+		Output: buf,
+		Clock:  &stepClock{now: time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), step: time.Millisecond},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/a%09b", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "time\tmethod\tpath\troute\tstatus\tlatency_ms\tip\terror\n" +
+		"2020-07-01T12:00:00.003Z\tGET\t\"/a\tb\"\t\"/a\tb\"\t404\t1\t0.0.0.0\t\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %q, expected: %q", buf.String(), expectedOutput)
+	}
+}
+
+func TestCSVEncoder_headerPerOutput(t *testing.T) {
+	buf, errs := &strings.Builder{}, &strings.Builder{}
+	enc := &CSVEncoder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Encoder: enc,
+		Output:  buf,
+		// The error sink shares the encoder and drops the first entry
+		Sinks: []SinkConfig{{Name: "errors", Output: errs, Statuses: []StatusRange{{500, 599}}}},
+		Clock: &stepClock{now: time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), step: time.Millisecond},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+	app.Get("/fail", func(ctx *fiber.Ctx) { ctx.SendStatus(500) })
+
+	for _, path := range []string{"/", "/fail"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	header := "time,method,path,route,status,latency_ms,ip,error\n"
+	if !strings.HasPrefix(buf.String(), header) || strings.Count(buf.String(), header) != 1 || strings.Count(buf.String(), "\n") != 3 {
+		t.Errorf("Has: %q, expected: a header and two rows", buf.String())
+	}
+	if !strings.HasPrefix(errs.String(), header) || strings.Count(errs.String(), "\n") != 2 {
+		t.Errorf("Has: %q, expected: a header and a row", errs.String())
+	}
+}
+
+func TestCSVEncoder_headerPerFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.csv")
+	fw, err := NewFileWriter(FileConfig{Filename: name, Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+	app := fiber.New()
+	app.Use(New(Config{Format: FormatCSV, Output: fw}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+
+	var rotated string
+	for i := 0; i < 2; i++ {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+		if i == 0 {
+			if rotated, err = fw.rotate(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)); err != nil {
+				t.Fatalf("Has: %v, expected: nil", err)
+			}
+		}
+	}
+
+	header := "time,method,path,route,status,latency_ms,ip,error\n"
+	for _, path := range []string{rotated, name} {
+		b, _ := ioutil.ReadFile(path)
+		if !strings.HasPrefix(string(b), header) || strings.Count(string(b), "\n") != 2 {
+			t.Errorf("Has: %q in %s, expected: a header and a row", b, path)
+		}
+	}
+}
@@ -0,0 +1,117 @@
+package logger
+
+import (
+	"strconv"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// FormatDatadog as Format writes entries with a DatadogEncoder
+const FormatDatadog = "datadog"
+
+// datadogTags are the tags a DatadogEncoder maps to standard attributes
+var datadogTags = []string{strUrl, strUa, strReferer, strBytesSent, strTraceID, strSpanID}
+
+// DatadogEncoder writes an entry as JSON with Datadog's standard attributes:
+// http.method, http.url, http.status_code, http.route, http.useragent,
+// http.referer, network.client.ip, network.bytes_written, duration in
+// nanoseconds and dd.trace_id and dd.span_id, so logs correlate with APM
+// traces. The tags in Fields are written as keys of their own
+type DatadogEncoder struct {
+	// Service is the service attribute of the unified service tags
+	// Optional. Default: ""
+	Service string
+	// Env is the dd.env attribute
+	// Optional. Default: ""
+	Env string
+	// Version is the dd.version attribute
+	// Optional. Default: ""
+	Version string
+	// Fields are tags written as keys of their own, e.g. {"requestId"}
+	// Optional. Default: nil
+	Fields []string
+}
+
+// Tags returns the tags of the standard attributes and Fields
+func (d *DatadogEncoder) Tags() []string {
+	return append(append([]string(nil), datadogTags...), d.Fields...)
+}
+
+// Encode writes e as a line of JSON
+func (d *DatadogEncoder) Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error {
+	buf.WriteString(`{"date":`)
+	buf.B = strconv.AppendInt(buf.B, e.Time.UnixNano()/1e6, 10)
+	buf.WriteString(`,"status":"` + datadogStatus(e.Status) + `","message":`)
+	appendJSONString(buf, e.Method+" "+e.Path+" "+strconv.Itoa(e.Status))
+	appendJSONKey(buf, "service", d.Service)
+	buf.WriteString(`,"http":{"method":`)
+	appendJSONString(buf, e.Method)
+	appendJSONKey(buf, "url", e.getString(strUrl))
+	buf.WriteString(`,"status_code":`)
+	buf.B = strconv.AppendInt(buf.B, int64(e.Status), 10)
+	appendJSONKey(buf, "route", e.Route)
+	appendJSONKey(buf, "useragent", e.getString(strUa))
+	appendJSONKey(buf, "referer", e.getString(strReferer))
+	appendJSONKey(buf, "request_id", e.ID)
+	buf.WriteString(`},"network":{"client":{"ip":`)
+	appendJSONString(buf, e.IP)
+	buf.WriteString(`}`)
+	if n := e.getString(strBytesSent); n != "" {
+		buf.WriteString(`,"bytes_written":` + n)
+	}
+	buf.WriteString(`},"duration":`)
+	buf.B = strconv.AppendInt(buf.B, int64(e.Latency), 10)
+	buf.WriteString(`,"dd":{`)
+	from := buf.Len()
+	appendJSONKey(buf, "trace_id", datadogID(e.getString(strTraceID)))
+	appendJSONKey(buf, "span_id", datadogID(e.getString(strSpanID)))
+	appendJSONKey(buf, "env", d.Env)
+	appendJSONKey(buf, "version", d.Version)
+	if buf.Len() > from {
+		// Drop the comma of the first key
+		buf.B = append(buf.B[:from], buf.B[from+1:]...)
+	}
+	buf.WriteString(`}`)
+	if e.Err != nil {
+		buf.WriteString(`,"error":{"message":`)
+		appendJSONString(buf, e.Err.Error())
+		buf.WriteString(`}`)
+	}
+	for _, f := range e.Fields {
+		if knownTag(f.Key) && !contains(d.Fields, f.Key) {
+			continue
+		}
+		buf.WriteString(",")
+		appendJSONString(buf, f.Key)
+		buf.WriteString(":")
+		if err := appendJSONValue(buf, f.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// datadogStatus maps a status to a Datadog log status
+func datadogStatus(status int) string {
+	switch {
+	case status >= 500:
+		return "error"
+	case status >= 400:
+		return "warn"
+	}
+	return "info"
+}
+
+// datadogID converts a hex trace or span id to the decimal form Datadog
+// uses, the lower 64 bits of 128 bit W3C trace ids
+func datadogID(id string) string {
+	if len(id) > 16 {
+		id = id[len(id)-16:]
+	}
+	n, err := strconv.ParseUint(id, 16, 64)
+	if err != nil {
+		return ""
+	}
+	return strconv.FormatUint(n, 10)
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestDatadogEncoder(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Output:    buf,
+		RequestID: true,
+		Encoder:   &DatadogEncoder{Service: "shop", Env: "prod"},
+		Clock:     &stepClock{now: time.Unix(1593604800, 0), step: 1500 * time.Microsecond},
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.SendString("ok")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("User-Agent", "curl")
+	req.Header.Set(HeaderTraceparent, "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	has := buf.String()
+	for _, expected := range []string{
+		`{"date":1593604800004,"status":"info","message":"GET /users/1 200","service":"shop",`,
+		`"http":{"method":"GET","url":"/users/1","status_code":200,"route":"/users/:id","useragent":"curl","request_id":"`,
+		`"network":{"client":{"ip":"0.0.0.0"},"bytes_written":2},"duration":1500000,`,
+		`"dd":{"trace_id":"11803532876627986230","span_id":`,
+		`"env":"prod"}}` + "\n",
+	} {
+		if !strings.Contains(has, expected) {
+			t.Errorf("Has: %s, expected: %s", has, expected)
+		}
+	}
+}
+
+func TestDatadogID(t *testing.T) {
+	if id := datadogID("4bf92f3577b34da6a3ce929d0e0e4736"); id != "11803532876627986230" {
+		t.Errorf("Has: %s, expected: 11803532876627986230", id)
+	}
+	if id := datadogID("not hex"); id != "" {
+		t.Errorf("Has: %s, expected: empty", id)
+	}
+}
@@ -0,0 +1,52 @@
+package logger
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// LocalsDeadline is the Locals key of the request deadline, timeout
+// middleware can set it directly, as a context.Context with a deadline, or
+// through SetDeadline
+const LocalsDeadline = "logger.deadline" // time.Time or context.Context
+
+// SetDeadline records the time the request has to be handled by
+func SetDeadline(c *fiber.Ctx, deadline time.Time) {
+	c.Locals(LocalsDeadline, deadline)
+}
+
+func deadline(c *fiber.Ctx) (time.Time, bool) {
+	switch d := c.Locals(LocalsDeadline).(type) {
+	case time.Time:
+		return d, !d.IsZero()
+	case interface{ Deadline() (time.Time, bool) }:
+		return d.Deadline()
+	}
+	return time.Time{}, false
+}
+
+// timeout is the time the request had when it started, empty without
+// deadline
+func timeout(c *fiber.Ctx, start time.Time) string {
+	if d, ok := deadline(c); ok {
+		return d.Sub(start).String()
+	}
+	return ""
+}
+
+// timedOut reports whether the request ran past its deadline or failed with
+// a timeout error, empty without deadline
+func timedOut(c *fiber.Ctx, stop time.Time) string {
+	d, ok := deadline(c)
+	if !ok {
+		return ""
+	}
+	err := c.Error()
+	if t, ok := err.(interface{ Timeout() bool }); ok && t.Timeout() {
+		return "true"
+	}
+	return strconv.FormatBool(err == context.DeadlineExceeded || !stop.Before(d))
+}
@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestDeadline(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${path} ${timedOut};",
+		Output: buf,
+	}))
+	app.Get("/fast", func(ctx *fiber.Ctx) {
+		SetDeadline(ctx, time.Now().Add(time.Minute))
+	})
+	app.Get("/slow", func(ctx *fiber.Ctx) {
+		deadlineCtx, cancel := context.WithTimeout(context.Background(), time.Millisecond)
+		defer cancel()
+		ctx.Locals(LocalsDeadline, deadlineCtx)
+		<-deadlineCtx.Done()
+		ctx.Next(deadlineCtx.Err())
+	})
+	app.Get("/none", func(ctx *fiber.Ctx) {})
+
+	for _, path := range []string{"/fast", "/slow", "/none"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	expectedOutput := "/fast false;/slow true;/none ;"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
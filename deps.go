@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+// localsDeps is the Locals key of the dependency timings of a request
+const localsDeps = "logger.deps"
+
+// deps are the dependency timings of a request in the order of first use
+type deps struct {
+	mu    sync.Mutex
+	names []string
+	times []time.Duration
+}
+
+// AddDep records that the request spent d in the dependency name, like a
+// query to "db". The times of a name add up and are logged as ${deps}, e.g.
+// "db=12ms cache=1ms stripe=230ms". Handlers may call it from goroutines of
+// their own when ${deps} is part of the format
+func AddDep(c *fiber.Ctx, name string, d time.Duration) {
+	requestDeps(c).add(name, d)
+}
+
+// TimeDep starts timing the dependency name and returns the function
+// recording it like AddDep, e.g. defer logger.TimeDep(c, "db")()
+func TimeDep(c *fiber.Ctx, name string) func() {
+	ds := requestDeps(c)
+	start := time.Now()
+	return func() {
+		ds.add(name, time.Since(start))
+	}
+}
+
+// requestDeps returns the dependency timings of the request, the middleware
+// sets them before the handler when they are logged
+func requestDeps(c *fiber.Ctx) *deps {
+	ds, ok := c.Locals(localsDeps).(*deps)
+	if !ok {
+		ds = &deps{}
+		c.Locals(localsDeps, ds)
+	}
+	return ds
+}
+
+func (ds *deps) add(name string, d time.Duration) {
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	for i := range ds.names {
+		if ds.names[i] == name {
+			ds.times[i] += d
+			return
+		}
+	}
+	ds.names = append(ds.names, name)
+	ds.times = append(ds.times, d)
+}
+
+// appendDeps writes the dependency timings of the request to buf, rounded
+// to milliseconds or below a millisecond to microseconds
+func appendDeps(buf *bytebufferpool.ByteBuffer, c *fiber.Ctx) (int, error) {
+	ds, ok := c.Locals(localsDeps).(*deps)
+	if !ok {
+		return 0, nil
+	}
+	ds.mu.Lock()
+	defer ds.mu.Unlock()
+	from := buf.Len()
+	for i, name := range ds.names {
+		if i > 0 {
+			buf.WriteByte(' ')
+		}
+		buf.WriteString(name)
+		buf.WriteByte('=')
+		d := ds.times[i]
+		if d >= time.Millisecond {
+			d = d.Round(time.Millisecond)
+		} else {
+			d = d.Round(time.Microsecond)
+		}
+		appendDuration(buf, d)
+	}
+	return buf.Len() - from, nil
+}
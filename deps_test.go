@@ -0,0 +1,58 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestLogger_Deps(t *testing.T) {
+	out := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{Format: "${path} [${deps}]\n", Output: out}))
+	app.Get("/checkout", func(ctx *fiber.Ctx) {
+		AddDep(ctx, "db", 12400*time.Microsecond)
+		AddDep(ctx, "cache", 350*time.Microsecond)
+		var wg sync.WaitGroup
+		for i := 0; i < 2; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				AddDep(ctx, "stripe", 115*time.Millisecond)
+			}()
+		}
+		wg.Wait()
+		AddDep(ctx, "db", 600*time.Microsecond)
+	})
+	app.Get("/plain", func(ctx *fiber.Ctx) {})
+	for _, path := range []string{"/checkout", "/plain"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+	if out.String() != "/checkout [db=13ms cache=350µs stripe=230ms]\n/plain []\n" {
+		t.Errorf("Has: %q, expected: dependency timings", out.String())
+	}
+}
+
+func TestTimeDep(t *testing.T) {
+	app := fiber.New()
+	var ds *deps
+	app.Get("/", func(ctx *fiber.Ctx) {
+		stop := TimeDep(ctx, "db")
+		time.Sleep(2 * time.Millisecond)
+		stop()
+		ds = ctx.Locals(localsDeps).(*deps)
+	})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	if len(ds.names) != 1 || ds.names[0] != "db" || ds.times[0] < 2*time.Millisecond {
+		t.Errorf("Has: %v %v, expected: db of at least 2ms", ds.names, ds.times)
+	}
+}
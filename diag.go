@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Level is the severity of a diagnostic message of the logger itself
+type Level int
+
+// Diagnostic levels, the zero value is LevelInfo
+const (
+	LevelDebug Level = iota - 1
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// String returns the lowercase name of the level
+func (lvl Level) String() string {
+	switch lvl {
+	case LevelDebug:
+		return "debug"
+	case LevelInfo:
+		return "info"
+	case LevelWarn:
+		return "warn"
+	case LevelError:
+		return "error"
+	}
+	return strconv.Itoa(int(lvl))
+}
+
+// diag writes a diagnostic message to Config.Diagnostics if its level is
+// at least Config.DiagnosticsLevel
+func (l *Logger) diag(lvl Level, format string, args ...interface{}) {
+	if lvl < l.cfg.DiagnosticsLevel {
+		return
+	}
+	fmt.Fprintf(l.cfg.Diagnostics, "%s logger %s: %s\n", l.cfg.Clock.Now().Format(l.cfg.TimeFormat), lvl, fmt.Sprintf(format, args...))
+}
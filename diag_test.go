@@ -0,0 +1,22 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLogger_diag(t *testing.T) {
+	buf := &strings.Builder{}
+	l := NewLogger(Config{
+		Diagnostics:      buf,
+		DiagnosticsLevel: LevelWarn,
+		TimeFormat:       "-",
+	})
+	l.diag(LevelInfo, "skipped")
+	l.diag(LevelError, "writing entry: %s", "disk full")
+
+	expectedOutput := "- logger error: writing entry: disk full\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,312 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// errElasticBuffer is returned for entries dropped while the buffer is full
+var errElasticBuffer = errors.New("logger: elasticsearch buffer full, entry dropped")
+
+// ElasticConfig configures an ElasticWriter
+type ElasticConfig struct {
+	// URL of the cluster, e.g. "http://elasticsearch:9200"
+	// Required
+	URL string
+	// Index is the index of the entries, ${date} is replaced with the date
+	// of the entry in IndexDateFormat
+	// Optional. Default: "access-${date}"
+	Index string
+	// IndexDateFormat is the time layout of ${date} in Index
+	// Optional. Default: "2006.01.02"
+	IndexDateFormat string
+	// Username and Password authenticate with basic auth
+	// Optional. Default: ""
+	Username, Password string
+	// APIKey authenticates with an encoded API key
+	// Optional. Default: ""
+	APIKey string
+	// BatchSize is the number of entries indexed in one bulk request
+	// Optional. Default: 500
+	BatchSize int
+	// BatchWait is the longest an entry waits before it is indexed
+	// Optional. Default: 1 * time.Second
+	BatchWait time.Duration
+	// MaxBuffer is the most bytes of entries buffered and in flight, further
+	// entries are dropped
+	// Optional. Default: 10 << 20 (10 MiB)
+	MaxBuffer int
+	// Retries is how often a bulk request or its rejected entries are
+	// retried after a network error, 429 or 5xx response
+	// Optional. Default: 5
+	Retries int
+	// MinBackoff is the wait before the first retry, doubled per retry
+	// Optional. Default: 500 * time.Millisecond
+	MinBackoff time.Duration
+	// MaxBackoff is the longest wait between retries
+	// Optional. Default: 30 * time.Second
+	MaxBackoff time.Duration
+	// Client sends the requests
+	// Optional. Default: a client with a 30 second timeout
+	Client *http.Client
+}
+
+// elasticBulk is the body of a bulk request, with the offset of every item
+type elasticBulk struct {
+	body  bytebufferpool.ByteBuffer
+	items []int
+}
+
+func (b *elasticBulk) item(i int) []byte {
+	if i+1 < len(b.items) {
+		return b.body.B[b.items[i]:b.items[i+1]]
+	}
+	return b.body.B[b.items[i]:]
+}
+
+// ElasticWriter indexes entries with the bulk API of Elasticsearch or
+// OpenSearch, in batches of BatchSize or every BatchWait. Lines that are
+// not JSON objects are indexed as the message of a document. Requests
+// failing with a network error, 429 or 5xx and entries rejected with 429
+// are retried with exponential backoff
+type ElasticWriter struct {
+	cfg      ElasticConfig
+	mu       sync.Mutex
+	bulk     *elasticBulk
+	buffered int
+	failed   bool
+	full     chan struct{}
+	done     chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// NewElasticWriter starts indexing to the cluster of cfg
+func NewElasticWriter(cfg ElasticConfig) (*ElasticWriter, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("logger: elasticsearch URL is required")
+	}
+	if cfg.Index == "" {
+		cfg.Index = "access-${date}"
+	}
+	if cfg.IndexDateFormat == "" {
+		cfg.IndexDateFormat = "2006.01.02"
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 500
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = time.Second
+	}
+	if cfg.MaxBuffer <= 0 {
+		cfg.MaxBuffer = 10 << 20
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = 5
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 30 * time.Second}
+	}
+	w := &ElasticWriter{
+		cfg:    cfg,
+		bulk:   &elasticBulk{},
+		full:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Write adds p as a document to the index of the current date
+func (w *ElasticWriter) Write(p []byte) (int, error) {
+	return w.add(time.Now(), p)
+}
+
+// WriteEntry adds p as a document to the index of the date of e
+func (w *ElasticWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return w.add(e.Time, p)
+}
+
+func (w *ElasticWriter) add(t time.Time, p []byte) (int, error) {
+	n := len(p)
+	p = bytes.TrimSpace(p)
+	index := strings.Replace(w.cfg.Index, "${date}", t.Format(w.cfg.IndexDateFormat), -1)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.buffered >= w.cfg.MaxBuffer {
+		return 0, errElasticBuffer
+	}
+	b := w.bulk
+	from := b.body.Len()
+	b.items = append(b.items, from)
+	b.body.WriteString(`{"index":{"_index":`)
+	appendJSONString(&b.body, index)
+	b.body.WriteString("}}\n")
+	if len(p) > 0 && p[0] == '{' {
+		b.body.Write(p)
+	} else {
+		b.body.WriteString(`{"@timestamp":`)
+		appendJSONString(&b.body, t.UTC().Format(time.RFC3339Nano))
+		b.body.WriteString(`,"message":`)
+		appendJSONString(&b.body, string(p))
+		b.body.WriteString("}")
+	}
+	b.body.WriteString("\n")
+	w.buffered += b.body.Len() - from
+	if len(b.items) == w.cfg.BatchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return n, nil
+}
+
+// run indexes the batch every BatchWait or once it is full, until Close
+func (w *ElasticWriter) run() {
+	ticker := time.NewTicker(w.cfg.BatchWait)
+	defer ticker.Stop()
+	defer close(w.closed)
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.full:
+		case <-w.done:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// flush indexes the pending entries, retrying with backoff. Entries still
+// failing after the retries are dropped
+func (w *ElasticWriter) flush() {
+	w.mu.Lock()
+	bulk := w.bulk
+	w.bulk = &elasticBulk{}
+	w.mu.Unlock()
+	if len(bulk.items) == 0 {
+		return
+	}
+	size := bulk.body.Len()
+	err := w.push(bulk)
+	w.mu.Lock()
+	w.buffered -= size
+	w.failed = err != nil
+	w.mu.Unlock()
+}
+
+// push sends bulk, then the entries to retry, until none is left
+func (w *ElasticWriter) push(bulk *elasticBulk) error {
+	backoff := w.cfg.MinBackoff
+	for retry := 0; ; retry++ {
+		rest, err := w.send(bulk)
+		if rest == nil || retry == w.cfg.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.done:
+			return err
+		}
+		if backoff *= 2; backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+		bulk = rest
+	}
+}
+
+// send makes a bulk request and returns the entries worth retrying: all of
+// them after a network error, 429 or 5xx, else the ones rejected with 429
+func (w *ElasticWriter) send(bulk *elasticBulk) (*elasticBulk, error) {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimRight(w.cfg.URL, "/")+"/_bulk", bytes.NewReader(bulk.body.B))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/x-ndjson")
+	if w.cfg.APIKey != "" {
+		req.Header.Set("Authorization", "ApiKey "+w.cfg.APIKey)
+	} else if w.cfg.Username != "" {
+		req.SetBasicAuth(w.cfg.Username, w.cfg.Password)
+	}
+	resp, err := w.cfg.Client.Do(req)
+	if err != nil {
+		return bulk, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+		err := fmt.Errorf("logger: elasticsearch bulk: %s: %s", resp.Status, bytes.TrimSpace(msg))
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return bulk, err
+		}
+		return nil, err
+	}
+	var result struct {
+		Errors bool `json:"errors"`
+		Items  []map[string]struct {
+			Status int `json:"status"`
+			Error  struct {
+				Reason string `json:"reason"`
+			} `json:"error"`
+		} `json:"items"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&result); err != nil {
+		return nil, err
+	}
+	if !result.Errors {
+		return nil, nil
+	}
+	var rest *elasticBulk
+	for i, item := range result.Items {
+		for _, r := range item {
+			switch {
+			case r.Status == http.StatusTooManyRequests && i < len(bulk.items):
+				if rest == nil {
+					rest = &elasticBulk{}
+				}
+				rest.items = append(rest.items, rest.body.Len())
+				rest.body.Write(bulk.item(i))
+				err = errors.New("logger: elasticsearch rejected entries with 429")
+			case r.Status >= 300:
+				err = errors.New("logger: elasticsearch: " + r.Error.Reason)
+			}
+		}
+	}
+	return rest, err
+}
+
+// Connected reports whether the last bulk request succeeded, for Health
+func (w *ElasticWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.failed
+}
+
+// Close indexes the pending entries without further retries and stops the
+// writer
+func (w *ElasticWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.closed
+	return nil
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestElasticWriter(t *testing.T) {
+	var mu sync.Mutex
+	var requests [][]string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/_bulk" || r.Header.Get("Authorization") != "ApiKey secret" {
+			t.Errorf("Has: %s %v, expected: _bulk with the API key", r.URL.Path, r.Header)
+		}
+		var lines []string
+		s := bufio.NewScanner(r.Body)
+		for s.Scan() {
+			lines = append(lines, s.Text())
+		}
+		mu.Lock()
+		requests = append(requests, lines)
+		first := len(requests) == 1
+		mu.Unlock()
+		// Reject the second entry of the first request
+		if first {
+			w.Write([]byte(`{"errors":true,"items":[{"index":{"status":201}},{"index":{"status":429,"error":{"reason":"queue full"}}}]}`))
+			return
+		}
+		w.Write([]byte(`{"errors":false,"items":[{"index":{"status":201}}]}`))
+	}))
+	defer server.Close()
+
+	ew, err := NewElasticWriter(ElasticConfig{
+		URL:        server.URL,
+		APIKey:     "secret",
+		BatchSize:  2,
+		BatchWait:  time.Hour,
+		MinBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	day := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ew.WriteEntry(&Entry{Time: day}, []byte(`{"status":200}`+"\n"))
+	ew.WriteEntry(&Entry{Time: day}, []byte("GET / 200\n"))
+	// Close gives up retrying, wait for the retry of the rejected entry
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		mu.Lock()
+		n := len(requests)
+		mu.Unlock()
+		if n == 2 {
+			break
+		}
+		time.Sleep(time.Millisecond)
+	}
+	ew.Close()
+
+	if len(requests) != 2 {
+		t.Fatalf("Has: %d requests, expected: 2", len(requests))
+	}
+	first := requests[0]
+	if len(first) != 4 || first[0] != `{"index":{"_index":"access-2024.05.01"}}` || first[1] != `{"status":200}` {
+		t.Errorf("Has: %q, expected: actions with the dated index", first)
+	}
+	var doc map[string]string
+	if err := json.Unmarshal([]byte(first[3]), &doc); err != nil || doc["message"] != "GET / 200" || !strings.HasPrefix(doc["@timestamp"], "2024-05-01T12:00:00") {
+		t.Errorf("Has: %s, expected: the line as message", first[3])
+	}
+	if retried := requests[1]; len(retried) != 2 || retried[1] != first[3] {
+		t.Errorf("Has: %q, expected: the rejected entry only", retried)
+	}
+	if !ew.Connected() {
+		t.Errorf("Has: disconnected, expected: connected after the retry")
+	}
+}
+
+func TestElasticWriter_buffer(t *testing.T) {
+	ew, err := NewElasticWriter(ElasticConfig{URL: "http://127.0.0.1:0", MaxBuffer: 1, BatchWait: time.Hour})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer ew.Close()
+	if _, err := ew.Write([]byte(`{"status":200}`)); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	if _, err := ew.Write([]byte(`{"status":200}`)); err != errElasticBuffer {
+		t.Errorf("Has: %v, expected: %v", err, errElasticBuffer)
+	}
+}
+
+func TestElasticWriter_badRequest(t *testing.T) {
+	var attempts int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		http.Error(w, "mapping error", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	ew, _ := NewElasticWriter(ElasticConfig{URL: server.URL, BatchWait: time.Hour, MinBackoff: time.Millisecond})
+	ew.Write([]byte(`{"status":200}`))
+	ew.Close()
+	if attempts != 1 || ew.Connected() {
+		t.Errorf("Has: %d attempts, expected: 1 and disconnected", attempts)
+	}
+}
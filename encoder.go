@@ -0,0 +1,248 @@
+package logger
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+	"unicode/utf8"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// FormatJSON as Format writes entries with a JSONEncoder
+const FormatJSON = "json"
+
+// Schema versions of JSON entries
+const (
+	// SchemaV1 writes tags as strings
+	SchemaV1 = "v1"
+	// SchemaV2 writes tags with the types of Schema, like CoerceFields
+	SchemaV2 = "v2"
+)
+
+// Encoder writes entries in a structured format instead of a Format template.
+// Encoders that need tags rendered into the fields of an entry list them
+// with a Tags() []string method
+type Encoder interface {
+	Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error
+}
+
+// HeaderEncoder is an Encoder whose outputs start with a header, like the
+// column names of a CSVEncoder. The header is written before the first
+// entry of every output, and of every file a FileWriter starts
+type HeaderEncoder interface {
+	Encoder
+	Header(buf *bytebufferpool.ByteBuffer)
+}
+
+// headerWriter is a writer that writes the header itself whenever it starts
+// a new file, like a FileWriter
+type headerWriter interface {
+	SetHeader(header []byte)
+}
+
+// encodeHeader returns the header of enc, nil when it has none
+func encodeHeader(enc Encoder) []byte {
+	h, ok := enc.(HeaderEncoder)
+	if !ok {
+		return nil
+	}
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	h.Header(buf)
+	return append([]byte(nil), buf.B...)
+}
+
+// formatEncoder returns the encoder of a structured format, nil for a
+// template
+func formatEncoder(format, timeFormat string, cfg *Config) Encoder {
+	switch format {
+	case FormatJSON:
+		return &JSONEncoder{TimeFormat: timeFormat, Fields: cfg.Fields, ErrorClassifier: cfg.ErrorClassifier, SchemaVersion: cfg.SchemaVersion}
+	case FormatGELF:
+		return &GELFEncoder{Fields: cfg.Fields}
+	case FormatSyslog:
+		return &SyslogEncoder{Fields: cfg.Fields}
+	case FormatCEF:
+		return &CEFEncoder{Fields: cfg.Fields}
+	case FormatCSV:
+		return &CSVEncoder{Fields: cfg.Fields}
+	case FormatTSV:
+		return &CSVEncoder{Comma: '\t', Fields: cfg.Fields}
+	case FormatGCP:
+		return &GCPEncoder{Fields: cfg.Fields}
+	case FormatDatadog:
+		return &DatadogEncoder{Fields: cfg.Fields}
+	}
+	return nil
+}
+
+// JSONEncoder writes an entry as a JSON object per line with the keys time,
+// method, path, route, status, latency_ms, ip and error, followed by the
+// tags in Fields and the fields added by processors. Empty keys are left out
+type JSONEncoder struct {
+	// TimeFormat is the format of the time key
+	// Optional. Default: time.RFC3339Nano
+	TimeFormat string
+	// Fields are tags written as keys of their own, e.g. {"ua", "requestId"}
+	// Optional. Default: nil
+	Fields []string
+	// ErrorClassifier fills the code and retriable keys of the error object
+	// Optional. Default: Config.ErrorClassifier
+	ErrorClassifier func(err error) (code string, retriable bool)
+	// SchemaVersion is stamped into every entry as the schema key and
+	// selects its layout, SchemaV1 or SchemaV2. Consumers migrate from one
+	// version to the next by reading a sink that keeps the previous one
+	// Optional. Default: "" (SchemaV1 without the schema key)
+	SchemaVersion string
+}
+
+// jsonKeys are the keys every JSON entry has
+var jsonKeys = map[string]bool{
+	"time": true, "method": true, "path": true, "route": true,
+	"status": true, "latency_ms": true, "ip": true, "error": true,
+}
+
+// Tags returns Fields, the tags the encoder writes
+func (j *JSONEncoder) Tags() []string {
+	return j.Fields
+}
+
+// Encode writes e as a line of JSON
+func (j *JSONEncoder) Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error {
+	timeFormat := j.TimeFormat
+	if timeFormat == "" {
+		timeFormat = time.RFC3339Nano
+	}
+	switch j.SchemaVersion {
+	case "", SchemaV1, SchemaV2:
+	default:
+		return fmt.Errorf("logger: unknown schema version %q", j.SchemaVersion)
+	}
+	buf.WriteString("{")
+	if j.SchemaVersion != "" {
+		buf.WriteString(`"schema":`)
+		appendJSONString(buf, j.SchemaVersion)
+		buf.WriteString(",")
+	}
+	buf.WriteString(`"time":`)
+	appendJSONString(buf, e.Time.Format(timeFormat))
+	appendJSONKey(buf, "method", e.Method)
+	appendJSONKey(buf, "path", e.Path)
+	appendJSONKey(buf, "route", e.Route)
+	buf.WriteString(`,"status":`)
+	buf.B = strconv.AppendInt(buf.B, int64(e.Status), 10)
+	buf.WriteString(`,"latency_ms":`)
+	buf.B = strconv.AppendFloat(buf.B, milliseconds(e.Latency), 'f', -1, 64)
+	appendJSONKey(buf, "ip", e.IP)
+	if e.Err != nil {
+		classify := j.ErrorClassifier
+		if classify == nil {
+			classify = classifyError
+		}
+		b, err := json.Marshal(newErrorInfo(e.Err, classify))
+		if err != nil {
+			return err
+		}
+		buf.WriteString(`,"error":`)
+		buf.Write(b)
+	}
+	for _, f := range e.Fields {
+		if jsonKeys[f.Key] || (knownTag(f.Key) && !j.listed(f.Key)) {
+			continue
+		}
+		buf.WriteString(",")
+		appendJSONString(buf, f.Key)
+		buf.WriteString(":")
+		value := f.Value
+		if s, ok := value.(string); ok && j.SchemaVersion == SchemaV2 {
+			value = coerce(f.Key, s, e.Time)
+		}
+		if err := appendJSONValue(buf, value); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// listed reports whether tag is one of Fields
+func (j *JSONEncoder) listed(tag string) bool {
+	for _, f := range j.Fields {
+		if f == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// appendJSONKey writes a string key, leaving it out when value is empty
+// like after a field was removed by a processor
+func appendJSONKey(buf *bytebufferpool.ByteBuffer, key, value string) {
+	if value == "" {
+		return
+	}
+	buf.WriteString(`,"` + key + `":`)
+	appendJSONString(buf, value)
+}
+
+func appendJSONValue(buf *bytebufferpool.ByteBuffer, v interface{}) error {
+	switch v := v.(type) {
+	case string:
+		appendJSONString(buf, v)
+	case int:
+		buf.B = strconv.AppendInt(buf.B, int64(v), 10)
+	case int64:
+		buf.B = strconv.AppendInt(buf.B, v, 10)
+	case float64:
+		buf.B = strconv.AppendFloat(buf.B, v, 'f', -1, 64)
+	case bool:
+		buf.B = strconv.AppendBool(buf.B, v)
+	case nil:
+		buf.WriteString("null")
+	default:
+		b, err := json.Marshal(v)
+		if err != nil {
+			return err
+		}
+		buf.Write(b)
+	}
+	return nil
+}
+
+const hexDigits = "0123456789abcdef"
+
+// appendJSONString writes s as a JSON string, invalid UTF-8 is replaced
+func appendJSONString(buf *bytebufferpool.ByteBuffer, s string) {
+	buf.B = append(buf.B, '"')
+	for i := 0; i < len(s); {
+		c := s[i]
+		if c < utf8.RuneSelf {
+			switch {
+			case c == '"' || c == '\\':
+				buf.B = append(buf.B, '\\', c)
+			case c == '\n':
+				buf.B = append(buf.B, '\\', 'n')
+			case c == '\r':
+				buf.B = append(buf.B, '\\', 'r')
+			case c == '\t':
+				buf.B = append(buf.B, '\\', 't')
+			case c < 0x20:
+				buf.B = append(buf.B, '\\', 'u', '0', '0', hexDigits[c>>4], hexDigits[c&0xf])
+			default:
+				buf.B = append(buf.B, c)
+			}
+			i++
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if r == utf8.RuneError && size == 1 {
+			buf.B = append(buf.B, `�`...)
+		} else {
+			buf.B = append(buf.B, s[i:i+size]...)
+		}
+		i += size
+	}
+	buf.B = append(buf.B, '"')
+}
@@ -0,0 +1,98 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+func TestAppendJSONString(t *testing.T) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	appendJSONString(buf, "a\"b\\c\n\x01µ\xff")
+	expected := `"a\"b\\c\n\u0001µ�"`
+	if buf.String() != expected {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expected)
+	}
+}
+
+func TestJSONEncoder(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: FormatJSON,
+		Fields: []string{"ua", "header:X-Tenant"},
+		Output: buf,
+		Processors: []Processor{ProcessorFunc(func(e *Entry) bool {
+			e.Set("region", "eu-1")
+			return true
+		})},
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.Next(errors.New(`upstream "a" failed`))
+	})
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(502)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("User-Agent", "test\tagent")
+	req.Header.Set("X-Tenant", "acme")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	var entry map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &entry); err != nil {
+		t.Fatalf("Has: %v for %s, expected: JSON", err, buf.String())
+	}
+	if entry["method"] != "GET" || entry["path"] != "/users/1" || entry["route"] != "/users/:id" || entry["status"] != 502.0 {
+		t.Errorf("Has: %s, expected: standard keys", buf.String())
+	}
+	if entry["ua"] != "test\tagent" || entry["header:X-Tenant"] != "acme" || entry["region"] != "eu-1" {
+		t.Errorf("Has: %s, expected: fields", buf.String())
+	}
+	if e, _ := entry["error"].(map[string]interface{}); e["message"] != `upstream "a" failed` {
+		t.Errorf("Has: %s, expected: error object", buf.String())
+	}
+	if !strings.HasSuffix(buf.String(), "}\n") {
+		t.Errorf("Has: %q, expected: one line", buf.String())
+	}
+}
+
+func TestJSONEncoder_SchemaVersion(t *testing.T) {
+	v1, v2 := &strings.Builder{}, &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format:        FormatJSON,
+		Fields:        []string{"bytesSent", "upstreamLatency"},
+		SchemaVersion: SchemaV2,
+		Output:        v2,
+		Sinks: []SinkConfig{{Output: v1, Encoder: &JSONEncoder{
+			Fields:        []string{"bytesSent"},
+			SchemaVersion: SchemaV1,
+		}}},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		ctx.SendString("hello")
+	})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	if !strings.HasPrefix(v2.String(), `{"schema":"v2","time":`) || !strings.Contains(v2.String(), `"bytesSent":5`) {
+		t.Errorf("Has: %s, expected: a v2 entry with typed tags", v2.String())
+	}
+	if !strings.HasPrefix(v1.String(), `{"schema":"v1","time":`) || !strings.Contains(v1.String(), `"bytesSent":"5"`) {
+		t.Errorf("Has: %s, expected: a v1 entry with string tags", v1.String())
+	}
+	if err := (&JSONEncoder{SchemaVersion: "v9"}).Encode(bytebufferpool.Get(), &Entry{}); err == nil {
+		t.Errorf("Has: nil, expected: an error for an unknown version")
+	}
+}
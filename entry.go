@@ -0,0 +1,252 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"io/ioutil"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+	"github.com/valyala/fasttemplate"
+)
+
+// Field is a named value of an entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// Entry is the log entry of a request as seen by processors. Fields hold the
+// values of the tags used in the formats, keyed by tag name. Entries are
+// pooled and reused once the request is logged, processors and encoders
+// keeping an entry beyond their call keep the copy returned by Retain
+type Entry struct {
+	Time     time.Time
+	Method   string
+	Path     string
+	Route    string
+	Status   int
+	Latency  time.Duration
+	Err      error
+	Priority Priority
+	IP       string
+	// ID is the request or trace id, empty without correlation
+	ID     string
+	Fields []Field
+}
+
+// Get returns the value of the field key
+func (e *Entry) Get(key string) (interface{}, bool) {
+	for i := range e.Fields {
+		if e.Fields[i].Key == key {
+			return e.Fields[i].Value, true
+		}
+	}
+	return nil, false
+}
+
+// getString returns the value of the field key if it is a string
+func (e *Entry) getString(key string) string {
+	v, _ := e.Get(key)
+	s, _ := v.(string)
+	return s
+}
+
+// Set sets the value of the field key, adding it if it does not exist
+func (e *Entry) Set(key string, value interface{}) {
+	for i := range e.Fields {
+		if e.Fields[i].Key == key {
+			e.Fields[i].Value = value
+			return
+		}
+	}
+	e.Fields = append(e.Fields, Field{key, value})
+}
+
+// Delete removes the field key
+func (e *Entry) Delete(key string) {
+	for i := range e.Fields {
+		if e.Fields[i].Key == key {
+			e.Fields = append(e.Fields[:i], e.Fields[i+1:]...)
+			return
+		}
+	}
+}
+
+// Retain returns a copy of the entry that is not reused by the logger
+func (e *Entry) Retain() *Entry {
+	c := &Entry{}
+	e.copyTo(c)
+	return c
+}
+
+// copyTo copies the entry to dst, reusing the fields of dst
+func (e *Entry) copyTo(dst *Entry) {
+	fields := dst.Fields[:0]
+	*dst = *e
+	dst.Fields = append(fields, e.Fields...)
+}
+
+var entryPool = sync.Pool{
+	New: func() interface{} {
+		return new(Entry)
+	},
+}
+
+func acquireEntry() *Entry {
+	return entryPool.Get().(*Entry)
+}
+
+// releaseEntry returns e to the pool, clearing references to values so
+// they can be collected
+func releaseEntry(e *Entry) {
+	for i := range e.Fields {
+		e.Fields[i] = Field{}
+	}
+	*e = Entry{Fields: e.Fields[:0]}
+	entryPool.Put(e)
+}
+
+// Processor transforms entries before they are written, such as redacting,
+// enriching, sampling or routing them. Returning false drops the entry
+type Processor interface {
+	Process(e *Entry) bool
+}
+
+// ProcessorFunc adapts a function to a Processor
+type ProcessorFunc func(e *Entry) bool
+
+// Process calls f(e)
+func (f ProcessorFunc) Process(e *Entry) bool {
+	return f(e)
+}
+
+// EntryWriter is an output that is passed the entry next to its encoded
+// form, e.g. to derive labels or keys from it. The entry is reused once
+// WriteEntry returns, writers keeping it beyond the call keep the copy
+// returned by Retain. Entries are built for outputs implementing it
+type EntryWriter interface {
+	WriteEntry(e *Entry, p []byte) (int, error)
+}
+
+// isEntryWriter reports whether w is an EntryWriter
+func isEntryWriter(w io.Writer) bool {
+	_, ok := w.(EntryWriter)
+	return ok
+}
+
+// entryValue returns the value of source in e: "route", "method",
+// "status", "priority" or a tag of its fields
+func entryValue(e *Entry, source string) string {
+	switch source {
+	case "route":
+		return e.Route
+	case "method":
+		return e.Method
+	case "status":
+		return strconv.Itoa(e.Status)
+	case "priority":
+		return e.Priority.String()
+	}
+	switch v, _ := e.Get(source); v := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return v
+	default:
+		return fmt.Sprint(v)
+	}
+}
+
+// sourceTags returns the sources of entryValue that are tags, so they are
+// fields of the entry
+func sourceTags(sources ...string) []string {
+	var tags []string
+	for _, source := range sources {
+		switch source {
+		case "", "route", "method", "status", "priority":
+		default:
+			tags = append(tags, source)
+		}
+	}
+	return tags
+}
+
+// process runs e through the processors in order, false if one dropped it
+func process(processors []Processor, e *Entry) bool {
+	for _, p := range processors {
+		if !p.Process(e) {
+			return false
+		}
+	}
+	return true
+}
+
+// templateTags lists the tags of the templates once each, in order of
+// appearance
+func templateTags(tmpls ...*fasttemplate.Template) []string {
+	var tags []string
+	seen := make(map[string]bool)
+	for _, tmpl := range tmpls {
+		tmpl.ExecuteFunc(ioutil.Discard, func(w io.Writer, tag string) (int, error) {
+			if !seen[tag] {
+				seen[tag] = true
+				tags = append(tags, tag)
+			}
+			return 0, nil
+		})
+	}
+	return tags
+}
+
+// newEntry builds the entry of the request with a field per tag of l.fields,
+// taken from the pool. The values share a single string
+func (l *Logger) newEntry(c *fiber.Ctx, start, stop time.Time, p Priority) *Entry {
+	e := acquireEntry()
+	e.Time = stop
+	e.Method = c.Method()
+	e.Path = c.Path()
+	e.Route = routePath(c)
+	e.Status = c.Fasthttp.Response.StatusCode()
+	e.Latency = stop.Sub(start)
+	e.Err = c.Error()
+	e.Priority = p
+	e.IP = c.IP()
+	e.ID = traceID(c)
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	var stack [32]int
+	ends := stack[:0]
+	for _, tag := range l.fields {
+		l.tag(buf, c, start, stop, tag)
+		ends = append(ends, buf.Len())
+	}
+	values := string(buf.B)
+	from := 0
+	for i, tag := range l.fields {
+		e.Fields = append(e.Fields, Field{tag, values[from:ends[i]]})
+		from = ends[i]
+	}
+	return e
+}
+
+// renderEntry writes e in the format of tmpl to buf, tags are taken from the
+// fields of the entry
+func (l *Logger) renderEntry(buf *bytebufferpool.ByteBuffer, tmpl *fasttemplate.Template, e *Entry) {
+	tmpl.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+		from := buf.Len()
+		var n int
+		var err error
+		switch v, _ := e.Get(tag); v := v.(type) {
+		case nil:
+		case string:
+			n, err = buf.WriteString(v)
+		default:
+			n, err = fmt.Fprint(buf, v)
+		}
+		return l.layout(buf, from, tag, n, err)
+	})
+}
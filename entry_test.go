@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestEntry_Set(t *testing.T) {
+	e := &Entry{Fields: []Field{{"ip", "203.0.113.7"}, {"status", "200"}}}
+	e.Set("ip", "redacted")
+	e.Set("region", "eu")
+	e.Delete("status")
+	if v, _ := e.Get("ip"); v != "redacted" {
+		t.Errorf("Has: %v, expected: redacted", v)
+	}
+	if _, ok := e.Get("status"); ok || len(e.Fields) != 2 {
+		t.Errorf("Has: %v, expected: ip and region", e.Fields)
+	}
+}
+
+func TestProcessors(t *testing.T) {
+	out, errs := &strings.Builder{}, &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${ip} ${status} ${header:Authorization};",
+		Output: out,
+		Processors: []Processor{
+			// redact
+			ProcessorFunc(func(e *Entry) bool {
+				if _, ok := e.Get("header:Authorization"); ok {
+					e.Set("header:Authorization", "***")
+				}
+				return true
+			}),
+			// drop health checks
+			ProcessorFunc(func(e *Entry) bool {
+				return e.Path != "/health"
+			}),
+		},
+		Sinks: []SinkConfig{{
+			Output: errs,
+			Format: "${status} ${ip};",
+			Processors: []Processor{
+				// route errors only and leave out the ip
+				ProcessorFunc(func(e *Entry) bool {
+					e.Delete("ip")
+					return e.Status >= 500
+				}),
+			},
+		}},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+	app.Get("/fail", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(500)
+	})
+	app.Get("/health", func(ctx *fiber.Ctx) {})
+
+	for _, path := range []string{"/", "/fail", "/health"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Authorization", "Bearer s3cret")
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	expectedOutput := "0.0.0.0 200 ***;0.0.0.0 500 ***;"
+	if out.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", out.String(), expectedOutput)
+	}
+	if errs.String() != "500 ;" {
+		t.Errorf("Has: %s, expected: 500 ;", errs.String())
+	}
+}
+
+func TestEntry_Retain(t *testing.T) {
+	var retained *Entry
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${ua}\n",
+		Output: &strings.Builder{},
+		Processors: []Processor{ProcessorFunc(func(e *Entry) bool {
+			retained = e.Retain()
+			return true
+		})},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+
+	for _, ua := range []string{"first", "second"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("User-Agent", ua)
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+		if v, _ := retained.Get("ua"); v != ua || retained.Path != "/" {
+			t.Errorf("Has: %v %s, expected: %s /", v, retained.Path, ua)
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// errorInfo describes a handler error for structured output
+type errorInfo struct {
+	Message   string `json:"message"`
+	Type      string `json:"type"`
+	Code      string `json:"code,omitempty"`
+	Retriable bool   `json:"retriable"`
+	Stack     string `json:"stack,omitempty"`
+}
+
+// newErrorInfo collects what is known about err, code and retriable are
+// taken from classify. The stack is taken from errors printing one with
+// "%+v", like github.com/pkg/errors does
+func newErrorInfo(err error, classify func(error) (string, bool)) (info errorInfo) {
+	if err == nil {
+		return info
+	}
+	info.Message = err.Error()
+	info.Type = fmt.Sprintf("%T", err)
+	info.Code, info.Retriable = classify(err)
+	if _, ok := err.(fmt.Formatter); ok {
+		if stack := fmt.Sprintf("%+v", err); stack != info.Message {
+			info.Stack = stack
+		}
+	}
+	return info
+}
+
+// classifyError is the default Config.ErrorClassifier. The code is taken from
+// a Code() method or a Code field like the one of *fiber.Error, errors are
+// retriable when they report to be temporary or a timeout like net.Error
+func classifyError(err error) (code string, retriable bool) {
+	switch e := err.(type) {
+	case interface{ Code() int }:
+		code = strconv.Itoa(e.Code())
+	case interface{ Code() string }:
+		code = e.Code()
+	default:
+		v := reflect.Indirect(reflect.ValueOf(err))
+		if v.Kind() == reflect.Struct {
+			if f := v.FieldByName("Code"); f.IsValid() {
+				switch f.Kind() {
+				case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+					code = strconv.FormatInt(f.Int(), 10)
+				case reflect.String:
+					code = f.String()
+				}
+			}
+		}
+	}
+	switch e := err.(type) {
+	case interface{ Temporary() bool }:
+		retriable = e.Temporary()
+	case interface{ Timeout() bool }:
+		retriable = e.Timeout()
+	}
+	return code, retriable
+}
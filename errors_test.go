@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"errors"
+	"testing"
+)
+
+type codeError struct{}
+
+func (codeError) Error() string   { return "db timeout" }
+func (codeError) Code() string    { return "DB_TIMEOUT" }
+func (codeError) Temporary() bool { return true }
+
+type fieldError struct {
+	Code    int
+	Message string
+}
+
+func (e *fieldError) Error() string { return e.Message }
+
+func TestNewErrorInfo(t *testing.T) {
+	if info := newErrorInfo(nil, classifyError); info != (errorInfo{}) {
+		t.Errorf("Has: %+v, expected: zero value", info)
+	}
+	info := newErrorInfo(codeError{}, classifyError)
+	expected := errorInfo{Message: "db timeout", Type: "logger.codeError", Code: "DB_TIMEOUT", Retriable: true}
+	if info != expected {
+		t.Errorf("Has: %+v, expected: %+v", info, expected)
+	}
+	if info := newErrorInfo(errors.New("boom"), classifyError); info.Type != "*errors.errorString" {
+		t.Errorf("Has: %s, expected: *errors.errorString", info.Type)
+	}
+}
+
+func TestClassifyError(t *testing.T) {
+	code, retriable := classifyError(&fieldError{Code: 422, Message: "invalid"})
+	if code != "422" || retriable {
+		t.Errorf("Has: %s %t, expected: 422 false", code, retriable)
+	}
+}
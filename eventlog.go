@@ -0,0 +1,109 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// errEventLogUnsupported is returned by NewEventLogWriter outside Windows
+var errEventLogUnsupported = errors.New("logger: event log writer is only supported on windows")
+
+// Event types of ReportEvent
+const (
+	eventError       = 0x0001
+	eventWarning     = 0x0002
+	eventInformation = 0x0004
+)
+
+// EventLogConfig configures an EventLogWriter
+type EventLogConfig struct {
+	// Source is the event source the entries are reported by, register it
+	// in the Application log at installation
+	// Optional. Default: the name of the executable without extension
+	Source string
+	// EventID is the id of the reported events
+	// Optional. Default: 1
+	EventID uint32
+	// Severity maps a status to a syslog severity, 3 and below are reported
+	// as errors, 4 as warnings and others as information
+	// Optional. Default: 3 (error) for 5xx, 4 (warning) for 4xx, 6 (info)
+	Severity func(status int) int
+}
+
+// EventLogWriter reports entries to the Windows Event Log, with the event
+// type following the status. Lines written by other means are information
+type EventLogWriter struct {
+	cfg    EventLogConfig
+	mu     sync.Mutex
+	handle uintptr
+}
+
+// NewEventLogWriter registers the event source of cfg
+func NewEventLogWriter(cfg EventLogConfig) (*EventLogWriter, error) {
+	if cfg.Source == "" {
+		name := filepath.Base(os.Args[0])
+		cfg.Source = name[:len(name)-len(filepath.Ext(name))]
+	}
+	if cfg.EventID == 0 {
+		cfg.EventID = 1
+	}
+	if cfg.Severity == nil {
+		cfg.Severity = statusSeverity
+	}
+	handle, err := registerEventSource(cfg.Source)
+	if err != nil {
+		return nil, err
+	}
+	return &EventLogWriter{cfg: cfg, handle: handle}, nil
+}
+
+// eventType returns the event type of a syslog severity
+func eventType(severity int) uint16 {
+	switch {
+	case severity <= 3:
+		return eventError
+	case severity == 4:
+		return eventWarning
+	}
+	return eventInformation
+}
+
+// Write reports p as an information event
+func (w *EventLogWriter) Write(p []byte) (int, error) {
+	return w.report(eventInformation, p)
+}
+
+// WriteEntry reports p as an event of the type of the status of e
+func (w *EventLogWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return w.report(eventType(w.cfg.Severity(e.Status)), p)
+}
+
+func (w *EventLogWriter) report(typ uint16, p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.handle == 0 {
+		return 0, errors.New("logger: event log writer is closed")
+	}
+	if err := reportEvent(w.handle, typ, w.cfg.EventID, string(p)); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
+
+// Close deregisters the event source
+func (w *EventLogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.handle == 0 {
+		return nil
+	}
+	err := deregisterEventSource(w.handle)
+	w.handle = 0
+	return err
+}
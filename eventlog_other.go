@@ -0,0 +1,16 @@
+//go:build !windows
+// +build !windows
+
+package logger
+
+func registerEventSource(source string) (uintptr, error) {
+	return 0, errEventLogUnsupported
+}
+
+func deregisterEventSource(handle uintptr) error {
+	return errEventLogUnsupported
+}
+
+func reportEvent(handle uintptr, typ uint16, id uint32, msg string) error {
+	return errEventLogUnsupported
+}
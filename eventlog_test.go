@@ -0,0 +1,33 @@
+package logger
+
+import (
+	"runtime"
+	"testing"
+)
+
+func TestEventType(t *testing.T) {
+	for status, expected := range map[int]uint16{200: eventInformation, 304: eventInformation, 404: eventWarning, 503: eventError} {
+		if typ := eventType(statusSeverity(status)); typ != expected {
+			t.Errorf("Has: %d for %d, expected: %d", typ, status, expected)
+		}
+	}
+}
+
+func TestEventLogWriter(t *testing.T) {
+	ew, err := NewEventLogWriter(EventLogConfig{Source: "logger-test"})
+	if runtime.GOOS != "windows" {
+		if err != errEventLogUnsupported {
+			t.Errorf("Has: %v, expected: %v", err, errEventLogUnsupported)
+		}
+		return
+	}
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	if _, err := ew.WriteEntry(&Entry{Status: 503}, []byte("GET / 503\n")); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	if err := ew.Close(); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+}
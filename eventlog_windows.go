@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"strings"
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procRegisterEventSource   = advapi32.NewProc("RegisterEventSourceW")
+	procDeregisterEventSource = advapi32.NewProc("DeregisterEventSource")
+	procReportEvent           = advapi32.NewProc("ReportEventW")
+)
+
+func registerEventSource(source string) (uintptr, error) {
+	name, err := syscall.UTF16PtrFromString(source)
+	if err != nil {
+		return 0, err
+	}
+	handle, _, err := procRegisterEventSource.Call(0, uintptr(unsafe.Pointer(name)))
+	if handle == 0 {
+		return 0, err
+	}
+	return handle, nil
+}
+
+func deregisterEventSource(handle uintptr) error {
+	if ok, _, err := procDeregisterEventSource.Call(handle); ok == 0 {
+		return err
+	}
+	return nil
+}
+
+// reportEvent reports msg as the single insertion string of an event
+func reportEvent(handle uintptr, typ uint16, id uint32, msg string) error {
+	s, err := syscall.UTF16PtrFromString(msg)
+	if err != nil {
+		// Messages with NUL bytes are cut at the first one
+		s, _ = syscall.UTF16PtrFromString(msg[:strings.IndexByte(msg, 0)])
+	}
+	args := [1]*uint16{s}
+	ok, _, err := procReportEvent.Call(handle, uintptr(typ), 0, uintptr(id), 0, 1, 0,
+		uintptr(unsafe.Pointer(&args[0])), 0)
+	if ok == 0 {
+		return err
+	}
+	return nil
+}
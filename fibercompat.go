@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// fiberColors are the color tags of fiber's middleware/logger, aliases of
+// ${color:<name>}
+var fiberColors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white", "reset"}
+
+// pid is the process id of ${pid}
+var pid = strconv.Itoa(os.Getpid())
+
+// methodColor returns the color fiber's middleware/logger gives a method
+func methodColor(method string) string {
+	switch method {
+	case "GET":
+		return colors["cyan"]
+	case "POST":
+		return colors["green"]
+	case "PUT":
+		return colors["yellow"]
+	case "DELETE":
+		return colors["red"]
+	case "PATCH":
+		return colors["white"]
+	case "HEAD":
+		return colors["magenta"]
+	case "OPTIONS":
+		return colors["blue"]
+	}
+	return colors["reset"]
+}
+
+// appendFiberLatency writes d rounded to milliseconds and right aligned in
+// 7 columns, like fiber's middleware/logger
+func appendFiberLatency(buf *bytebufferpool.ByteBuffer, d time.Duration) (int, error) {
+	return buf.WriteString(padLeft(d.Round(time.Millisecond).String(), 7))
+}
+
+// appendFiberColored writes s between color and a reset, padded like
+// fiber's middleware/logger
+func appendFiberColored(buf *bytebufferpool.ByteBuffer, color, s string) (int, error) {
+	return buf.WriteString(color + " " + s + " " + colors["reset"])
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestLogger_FiberCompat(t *testing.T) {
+	buf := &strings.Builder{}
+	l := NewLogger(Config{
+		Format:      "${red}[${pid}]${reset} ${status} - ${latency} ${method} ${path} ${resBody}\n",
+		Output:      buf,
+		Clock:       &stepClock{now: time.Unix(1577880000, 0), step: 12400 * time.Microsecond},
+		FiberCompat: true,
+	})
+	// Keep the colors of the builder, which is no terminal
+	l.out.strip = false
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Post("/", func(ctx *fiber.Ctx) { ctx.SendString("created") })
+	if _, err := app.Test(httptest.NewRequest(http.MethodPost, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	expected := "\x1b[31m[" + pid + "]\x1b[0m \x1b[32m 200 \x1b[0m -    12ms \x1b[32m POST    \x1b[0m / created\n"
+	if buf.String() != expected {
+		t.Errorf("Has: %q, expected: %q", buf.String(), expected)
+	}
+	if err := l.Validate(); err != nil {
+		t.Errorf("Has: %v, expected: fiber color tags known", err)
+	}
+}
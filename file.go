@@ -0,0 +1,574 @@
+package logger
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// backupTimeFormat is the time in the names of rotated files
+const backupTimeFormat = "2006-01-02T15-04-05.000"
+
+// Rotation is the schedule a FileWriter rotates its file on
+type Rotation int
+
+// Rotation schedules
+const (
+	RotateNever Rotation = iota
+	RotateDaily
+	RotateHourly
+)
+
+// Compression is the format a FileWriter compresses rotated files with
+type Compression int
+
+// Compression formats
+const (
+	CompressNone Compression = iota
+	CompressGzip
+)
+
+var errFlockUnsupported = errors.New("logger: shared file rotation is not supported on this system")
+
+// FileConfig configures a FileWriter
+type FileConfig struct {
+	// Filename is the file entries are appended to, rotated files are kept
+	// next to it. The name may hold %Y, %m, %d and %H, replaced with the
+	// year, month, day and hour in Location, e.g. "access-%Y%m%d.log". Such
+	// files are not moved aside, a new one is started when the name changes
+	// and the old one is rotated as is
+	// Required
+	Filename string
+	// MaxSize rotates the file before a write would grow it beyond this
+	// many bytes
+	// Optional. Default: 0 (no size limit)
+	MaxSize int
+	// MaxBackups is the number of rotated files kept, older ones are removed
+	// Optional. Default: 0 (all are kept)
+	MaxBackups int
+	// MaxAge removes rotated files last written longer ago
+	// Optional. Default: 0 (all are kept)
+	MaxAge time.Duration
+	// Shared coordinates rotation with other processes appending to
+	// Filename, with an flock on Filename + ".lock". Every write takes the
+	// lock shared and follows a rotation made by another process, so a file
+	// is rotated once and receives no writes after it was rotated. Costs a
+	// few system calls per write and is not supported on Windows
+	// Optional. Default: false
+	Shared bool
+	// Rotation rotates the file on a schedule, RotateDaily at midnight and
+	// RotateHourly at the full hour in Location
+	// Optional. Default: RotateNever, RotateHourly for a Filename with %H and
+	// RotateDaily for one with %d
+	Rotation Rotation
+	// Location is the time zone of rotation boundaries and the names of
+	// rotated files, e.g. the zone of the business day
+	// Optional. Default: time.Local
+	Location *time.Location
+	// Clock tells the time of rotations
+	// Optional. Default: the system clock
+	Clock Clock
+	// Compress compresses rotated files in the background, CompressGzip to
+	// the file name with ".gz" appended. The rotated file is removed once
+	// compressed, or kept when compression fails
+	// Optional. Default: CompressNone
+	Compress Compression
+	// OnRotate is called with the path of a rotated file once no more
+	// entries are written to it, e.g. to upload it. With Compress it is
+	// called once compression completed, with the compressed file or the
+	// rotated one when compression failed. It runs in a goroutine of its own
+	// and only in the process that rotated the file
+	// Optional. Default: nil
+	OnRotate func(oldPath string)
+}
+
+// FileWriter appends entries to a file and rotates it by size, on a schedule
+// or on demand, moving it aside with the time in its name and starting a
+// new one. Rotated files beyond MaxBackups or MaxAge are removed
+type FileWriter struct {
+	cfg  FileConfig
+	mu   sync.Mutex
+	path string
+	f    *os.File
+	size int64
+	lock *os.File
+	next time.Time
+	wg   sync.WaitGroup
+	// held are the rotated files being compressed or passed to OnRotate,
+	// cleanup leaves them until they are released
+	cmu  sync.Mutex
+	held map[string]bool
+	// header starts every file, see SetHeader
+	header []byte
+}
+
+// NewFileWriter opens the file of cfg for appending
+func NewFileWriter(cfg FileConfig) (*FileWriter, error) {
+	if cfg.Filename == "" {
+		return nil, errors.New("logger: file name is required")
+	}
+	if cfg.Location == nil {
+		cfg.Location = time.Local
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+	if cfg.Rotation == RotateNever {
+		if strings.Contains(cfg.Filename, "%H") {
+			cfg.Rotation = RotateHourly
+		} else if strings.Contains(cfg.Filename, "%d") {
+			cfg.Rotation = RotateDaily
+		}
+	}
+	now := cfg.Clock.Now()
+	w := &FileWriter{cfg: cfg, path: expandFilename(cfg.Filename, now.In(cfg.Location))}
+	w.next = nextRotation(cfg.Rotation, now, cfg.Location)
+	if cfg.Shared {
+		if !flockSupported {
+			return nil, errFlockUnsupported
+		}
+		lock, err := os.OpenFile(cfg.Filename+".lock", os.O_CREATE|os.O_RDWR, 0644)
+		if err != nil {
+			return nil, err
+		}
+		w.lock = lock
+	}
+	if err := w.open(); err != nil {
+		if w.lock != nil {
+			w.lock.Close()
+		}
+		return nil, err
+	}
+	return w, nil
+}
+
+// templated reports whether Filename holds time placeholders
+func (w *FileWriter) templated() bool {
+	return strings.Contains(w.cfg.Filename, "%")
+}
+
+// expandFilename replaces %Y, %m, %d, %H and %% in name with t
+func expandFilename(name string, t time.Time) string {
+	if !strings.Contains(name, "%") {
+		return name
+	}
+	buf := make([]byte, 0, len(name)+8)
+	for i := 0; i < len(name); i++ {
+		if name[i] != '%' || i+1 == len(name) {
+			buf = append(buf, name[i])
+			continue
+		}
+		i++
+		switch name[i] {
+		case 'Y':
+			buf = appendPadded(buf, t.Year(), 4)
+		case 'm':
+			buf = appendPadded(buf, int(t.Month()), 2)
+		case 'd':
+			buf = appendPadded(buf, t.Day(), 2)
+		case 'H':
+			buf = appendPadded(buf, t.Hour(), 2)
+		case '%':
+			buf = append(buf, '%')
+		default:
+			buf = append(buf, '%', name[i])
+		}
+	}
+	return string(buf)
+}
+
+// appendPadded appends n zero-padded to width digits
+func appendPadded(buf []byte, n, width int) []byte {
+	s := strconv.Itoa(n)
+	for i := len(s); i < width; i++ {
+		buf = append(buf, '0')
+	}
+	return append(buf, s...)
+}
+
+// matchFilename matches the start of name against the Filename stem,
+// placeholders matching digits, and returns the rest of name
+func matchFilename(stem, name string) (string, bool) {
+	for i := 0; i < len(stem); i++ {
+		width := 0
+		if stem[i] == '%' && i+1 < len(stem) {
+			switch stem[i+1] {
+			case 'Y':
+				width = 4
+			case 'm', 'd', 'H':
+				width = 2
+			case '%':
+				i++
+			}
+		}
+		if width == 0 {
+			if name == "" || name[0] != stem[i] {
+				return "", false
+			}
+			name = name[1:]
+			continue
+		}
+		if len(name) < width {
+			return "", false
+		}
+		for j := 0; j < width; j++ {
+			if name[j] < '0' || name[j] > '9' {
+				return "", false
+			}
+		}
+		name = name[width:]
+		i++
+	}
+	return name, true
+}
+
+// open opens the current file, creating it when missing
+func (w *FileWriter) open() error {
+	f, err := os.OpenFile(w.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if w.f != nil {
+		w.f.Close()
+	}
+	w.f, w.size = f, info.Size()
+	return nil
+}
+
+// nextRotation returns the first boundary of the schedule after now, the
+// zero time for RotateNever. Boundaries are wall clock times in loc, so
+// days across DST transitions last 23 or 25 hours and the hour repeated
+// when clocks go back is part of the hour before
+func nextRotation(r Rotation, now time.Time, loc *time.Location) time.Time {
+	now = now.In(loc)
+	y, m, d := now.Date()
+	switch r {
+	case RotateDaily:
+		return time.Date(y, m, d+1, 0, 0, 0, 0, loc)
+	case RotateHourly:
+		return time.Date(y, m, d, now.Hour()+1, 0, 0, 0, loc)
+	}
+	return time.Time{}
+}
+
+// Write appends p to the file, with Shared to the current file of all
+// processes. The file is rotated first when a boundary of the schedule
+// passed or p would exceed MaxSize
+func (w *FileWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return 0, os.ErrClosed
+	}
+	now := w.cfg.Clock.Now()
+	if !w.next.IsZero() && !now.Before(w.next) {
+		if _, err := w.rotateLocked(now); err != nil {
+			return 0, err
+		}
+	}
+	if w.lock != nil {
+		if err := flock(w.lock, false); err != nil {
+			return 0, err
+		}
+		defer funlock(w.lock)
+		if err := w.follow(); err != nil {
+			return 0, err
+		}
+	}
+	if w.cfg.MaxSize > 0 && w.size > 0 && w.size+int64(len(p)) > int64(w.cfg.MaxSize) {
+		// The shared lock is released for the exclusive one of the rotation
+		if w.lock != nil {
+			funlock(w.lock)
+		}
+		_, err := w.rotateLocked(now)
+		if w.lock != nil {
+			if lerr := flock(w.lock, false); lerr != nil {
+				return 0, lerr
+			}
+			if err == nil {
+				err = w.follow()
+			}
+		}
+		if err != nil {
+			return 0, err
+		}
+	}
+	if w.size == 0 && len(w.header) > 0 {
+		n, err := w.f.Write(w.header)
+		w.size += int64(n)
+		if err != nil {
+			return 0, err
+		}
+	}
+	n, err := w.f.Write(p)
+	w.size += int64(n)
+	return n, err
+}
+
+// SetHeader sets a header written at the start of every file before its
+// first entry, like the column names of a CSVEncoder. The logger sets the
+// header of its encoder, so a rotated file is loaded on its own
+func (w *FileWriter) SetHeader(header []byte) {
+	w.mu.Lock()
+	w.header = header
+	w.mu.Unlock()
+}
+
+// follow reopens the current file when another process rotated the open
+// one
+func (w *FileWriter) follow() error {
+	rotated, err := w.rotated()
+	if err != nil || !rotated {
+		return err
+	}
+	return w.open()
+}
+
+// rotated reports whether the current file is no longer the open one, else
+// it takes the size of the file including the writes of other processes
+func (w *FileWriter) rotated() (bool, error) {
+	info, err := w.f.Stat()
+	if err != nil {
+		return false, err
+	}
+	current, err := os.Stat(w.path)
+	if os.IsNotExist(err) {
+		return true, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	if !os.SameFile(info, current) {
+		return true, nil
+	}
+	w.size = current.Size()
+	return false, nil
+}
+
+// Rotate moves the file aside and starts a new one. With Shared, a file
+// already rotated by another process is not rotated again, the writer
+// moves on to the new file instead
+func (w *FileWriter) Rotate() error {
+	_, err := w.rotate(w.cfg.Clock.Now())
+	return err
+}
+
+// rotate moves the file aside at now and returns its new path, "" when
+// another process rotated it
+func (w *FileWriter) rotate(now time.Time) (string, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return "", os.ErrClosed
+	}
+	return w.rotateLocked(now)
+}
+
+// rotateLocked rotates with w.mu held, calls OnRotate and removes rotated
+// files beyond MaxBackups and MaxAge. With Compress they are removed once
+// the file is compressed
+func (w *FileWriter) rotateLocked(now time.Time) (string, error) {
+	path, err := w.moveLocked(now)
+	if path == "" {
+		return path, err
+	}
+	if w.cfg.Compress != CompressNone {
+		w.hold(path, true)
+		w.wg.Add(1)
+		go w.compress(path, now)
+		return path, err
+	}
+	if w.cfg.OnRotate != nil {
+		w.hold(path, true)
+		go func() {
+			w.cfg.OnRotate(path)
+			w.hold(path, false)
+		}()
+	}
+	w.cleanup(now)
+	return path, err
+}
+
+// hold keeps cleanup from removing the rotated file at path until it is
+// released, while it is compressed or passed to OnRotate
+func (w *FileWriter) hold(path string, held bool) {
+	w.cmu.Lock()
+	defer w.cmu.Unlock()
+	if w.held == nil {
+		w.held = make(map[string]bool)
+	}
+	if held {
+		w.held[filepath.Base(path)] = true
+	} else {
+		delete(w.held, filepath.Base(path))
+	}
+}
+
+// compress compresses the rotated file at path, removes the backups it
+// makes superfluous and calls OnRotate
+func (w *FileWriter) compress(path string, now time.Time) {
+	rotated := path
+	if err := gzipFile(path, path+".gz"); err == nil {
+		// Keep the time of the last write for MaxAge and the order of backups
+		if info, err := os.Stat(path); err == nil {
+			os.Chtimes(path+".gz", info.ModTime(), info.ModTime())
+		}
+		if os.Remove(path) == nil {
+			path += ".gz"
+		}
+	}
+	w.hold(path, true)
+	w.hold(rotated, rotated == path)
+	w.cleanup(now)
+	w.wg.Done()
+	if w.cfg.OnRotate != nil {
+		w.cfg.OnRotate(path)
+	}
+	w.hold(path, false)
+}
+
+// cleanup removes the oldest rotated files beyond MaxBackups and those
+// last written before MaxAge, but for the held ones. Files that cannot be
+// removed are tried again at the next rotation
+func (w *FileWriter) cleanup(now time.Time) {
+	if w.cfg.MaxBackups <= 0 && w.cfg.MaxAge <= 0 {
+		return
+	}
+	backups, err := w.backups()
+	if err != nil {
+		return
+	}
+	w.cmu.Lock()
+	defer w.cmu.Unlock()
+	n := 0
+	for _, b := range backups {
+		name := b.Name()
+		// The partial archive of a file being compressed is not a backup
+		if strings.HasSuffix(name, ".gz") && w.held[strings.TrimSuffix(name, ".gz")] {
+			continue
+		}
+		n++
+		if w.held[name] {
+			continue
+		}
+		if (w.cfg.MaxBackups > 0 && n > w.cfg.MaxBackups) || (w.cfg.MaxAge > 0 && now.Sub(b.ModTime()) > w.cfg.MaxAge) {
+			os.Remove(filepath.Join(filepath.Dir(w.cfg.Filename), name))
+		}
+	}
+}
+
+// backups lists the rotated files of Filename, newest first. For a
+// templated Filename these include the files of past periods
+func (w *FileWriter) backups() ([]os.FileInfo, error) {
+	dir, err := os.Open(filepath.Dir(w.cfg.Filename))
+	if err != nil {
+		return nil, err
+	}
+	infos, err := dir.Readdir(-1)
+	dir.Close()
+	if err != nil {
+		return nil, err
+	}
+	ext := filepath.Ext(w.cfg.Filename)
+	stem := strings.TrimSuffix(filepath.Base(w.cfg.Filename), ext)
+	current := filepath.Base(w.path)
+	var backups []os.FileInfo
+	for _, info := range infos {
+		name := info.Name()
+		if !info.Mode().IsRegular() || name == current {
+			continue
+		}
+		rest, ok := matchFilename(stem, name)
+		if !ok {
+			continue
+		}
+		if w.templated() && strings.HasPrefix(rest, ext) {
+			backups = append(backups, info)
+			continue
+		}
+		if len(rest) < 1+len(backupTimeFormat) || rest[0] != '-' {
+			continue
+		}
+		if _, err := time.Parse(backupTimeFormat, rest[1:1+len(backupTimeFormat)]); err == nil {
+			backups = append(backups, info)
+		}
+	}
+	sort.Slice(backups, func(i, j int) bool { return backups[i].ModTime().After(backups[j].ModTime()) })
+	return backups, nil
+}
+
+// moveLocked schedules the next rotation, moves the file aside and opens a
+// new one. A templated Filename that changed with now is not moved, the
+// file of the new name is opened instead
+func (w *FileWriter) moveLocked(now time.Time) (string, error) {
+	w.next = nextRotation(w.cfg.Rotation, now, w.cfg.Location)
+	if w.lock != nil {
+		if err := flock(w.lock, true); err != nil {
+			return "", err
+		}
+		defer funlock(w.lock)
+	}
+	if path := expandFilename(w.cfg.Filename, now.In(w.cfg.Location)); path != w.path {
+		old := w.path
+		w.path = path
+		// With Shared, the process creating the new file rotated the old one
+		_, err := os.Stat(path)
+		if w.lock != nil && err == nil {
+			return "", w.open()
+		}
+		return old, w.open()
+	}
+	if w.lock != nil {
+		if rotated, err := w.rotated(); err != nil || rotated {
+			if err != nil {
+				return "", err
+			}
+			return "", w.open()
+		}
+	}
+	path := backupName(w.path, now.In(w.cfg.Location))
+	if err := os.Rename(w.path, path); err != nil {
+		return "", err
+	}
+	return path, w.open()
+}
+
+// backupName returns a free name for filename rotated at t, like
+// access-2024-05-01T10-00-00.000.log for access.log
+func backupName(filename string, t time.Time) string {
+	ext := filepath.Ext(filename)
+	prefix := strings.TrimSuffix(filename, ext) + "-" + t.Format(backupTimeFormat)
+	path := prefix + ext
+	for i := 1; ; i++ {
+		if _, err := os.Lstat(path); os.IsNotExist(err) {
+			return path
+		}
+		path = prefix + "-" + strconv.Itoa(i) + ext
+	}
+}
+
+// Close closes the file and waits for rotated files being compressed
+func (w *FileWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	defer w.wg.Wait()
+	if w.f == nil {
+		return nil
+	}
+	err := w.f.Close()
+	w.f = nil
+	if w.lock != nil {
+		w.lock.Close()
+	}
+	return err
+}
@@ -0,0 +1,462 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestFileWriter_Rotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	fw, err := NewFileWriter(FileConfig{Filename: name, Location: time.UTC})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+
+	fw.Write([]byte("GET /a 200\n"))
+	now := time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	first, err := fw.rotate(now)
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	fw.Write([]byte("GET /b 200\n"))
+	second, _ := fw.rotate(now)
+
+	if expected := filepath.Join(dir, "access-2024-05-01T10-00-00.000.log"); first != expected {
+		t.Errorf("Has: %s, expected: %s", first, expected)
+	}
+	if expected := filepath.Join(dir, "access-2024-05-01T10-00-00.000-1.log"); second != expected {
+		t.Errorf("Has: %s, expected: %s", second, expected)
+	}
+	if b, _ := ioutil.ReadFile(first); string(b) != "GET /a 200\n" {
+		t.Errorf("Has: %q, expected: the first line", b)
+	}
+}
+
+func TestFileWriter_daily(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	// 23:59 in New York is already the next day in UTC
+	clock := &stepClock{now: time.Date(2024, 5, 1, 23, 59, 0, 0, loc)}
+	fw, err := NewFileWriter(FileConfig{Filename: name, Rotation: RotateDaily, Location: loc, Clock: clock})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+
+	fw.Write([]byte("GET /a 200\n"))
+	clock.now = time.Date(2024, 5, 2, 0, 0, 1, 0, loc)
+	fw.Write([]byte("GET /b 200\n"))
+
+	rotated := filepath.Join(dir, "access-2024-05-02T00-00-01.000.log")
+	if b, _ := ioutil.ReadFile(rotated); string(b) != "GET /a 200\n" {
+		t.Errorf("Has: %q, expected: the line of May 1st", b)
+	}
+	if b, _ := ioutil.ReadFile(name); string(b) != "GET /b 200\n" {
+		t.Errorf("Has: %q, expected: the line of May 2nd", b)
+	}
+}
+
+func TestNextRotation(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip(err)
+	}
+	for _, test := range []struct {
+		now    time.Time
+		length time.Duration
+	}{
+		{time.Date(2024, 5, 1, 12, 0, 0, 0, loc), 24 * time.Hour},
+		// Clocks spring forward on March 10th and fall back on November 3rd
+		{time.Date(2024, 3, 9, 12, 0, 0, 0, loc), 23 * time.Hour},
+		{time.Date(2024, 11, 2, 12, 0, 0, 0, loc), 25 * time.Hour},
+	} {
+		next := nextRotation(RotateDaily, test.now, loc)
+		after := nextRotation(RotateDaily, next, loc)
+		if next.Hour() != 0 || after.Hour() != 0 || after.Sub(next) != test.length {
+			t.Errorf("Has: %v to %v, expected: midnights %v apart", next, after, test.length)
+		}
+	}
+	hourly := nextRotation(RotateHourly, time.Date(2024, 5, 1, 12, 30, 0, 0, loc), loc)
+	if !hourly.Equal(time.Date(2024, 5, 1, 13, 0, 0, 0, loc)) {
+		t.Errorf("Has: %v, expected: the next full hour", hourly)
+	}
+	if next := nextRotation(RotateNever, time.Now(), loc); !next.IsZero() {
+		t.Errorf("Has: %v, expected: no rotation", next)
+	}
+}
+
+func TestFileWriter_template(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	clock := &stepClock{now: time.Date(2024, 5, 1, 9, 59, 0, 0, time.UTC)}
+	fw, err := NewFileWriter(FileConfig{
+		Filename:   filepath.Join(dir, "access-%Y%m%d-%H.log"),
+		MaxBackups: 1,
+		Location:   time.UTC,
+		Clock:      clock,
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+	if fw.cfg.Rotation != RotateHourly {
+		t.Errorf("Has: %v, expected: hourly rotation for %%H", fw.cfg.Rotation)
+	}
+
+	fw.Write([]byte("GET /a 200\n"))
+	clock.now = time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC)
+	fw.Write([]byte("GET /b 200\n"))
+	clock.now = time.Date(2024, 5, 1, 11, 30, 0, 0, time.UTC)
+	fw.Write([]byte("GET /c 200\n"))
+
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "access-20240501-11.log")); string(b) != "GET /c 200\n" {
+		t.Errorf("Has: %q, expected: the line of 11:30", b)
+	}
+	if b, _ := ioutil.ReadFile(filepath.Join(dir, "access-20240501-10.log")); string(b) != "GET /b 200\n" {
+		t.Errorf("Has: %q, expected: the line of 10:00 kept", b)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "access-20240501-09.log")); !os.IsNotExist(err) {
+		t.Errorf("Has: %v, expected: the file of 9:00 beyond MaxBackups removed", err)
+	}
+}
+
+func TestExpandFilename(t *testing.T) {
+	now := time.Date(2024, 5, 1, 7, 0, 0, 0, time.UTC)
+	for name, expected := range map[string]string{
+		"access.log":          "access.log",
+		"access-%Y%m%d.log":   "access-20240501.log",
+		"%Y%m%d%H.log":        "2024050107.log",
+		"access-100%%-%x.log": "access-100%-%x.log",
+	} {
+		if has := expandFilename(name, now); has != expected {
+			t.Errorf("Has: %s, expected: %s", has, expected)
+		}
+	}
+	if rest, ok := matchFilename("access-%Y%m%d", "access-20240501.log.gz"); !ok || rest != ".log.gz" {
+		t.Errorf("Has: %q %v, expected: the rest after the date", rest, ok)
+	}
+	if _, ok := matchFilename("access-%Y%m%d", "access-2024-05-01.log"); ok {
+		t.Errorf("Has: a match, expected: none for other digits")
+	}
+}
+
+func TestFileWriter_shared(t *testing.T) {
+	if !flockSupported {
+		t.Skip(errFlockUnsupported)
+	}
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	// Two writers stand in for two processes, each holding its own lock
+	a, err := NewFileWriter(FileConfig{Filename: name, Shared: true})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer a.Close()
+	b, _ := NewFileWriter(FileConfig{Filename: name, Shared: true})
+	defer b.Close()
+
+	a.Write([]byte("a1\n"))
+	b.Write([]byte("b1\n"))
+	rotatedA, err := a.rotate(time.Now())
+	if err != nil || rotatedA == "" {
+		t.Fatalf("Has: %q %v, expected: the file rotated", rotatedA, err)
+	}
+	// b follows the rotation instead of rotating the new file again
+	if rotatedB, err := b.rotate(time.Now()); err != nil || rotatedB != "" {
+		t.Errorf("Has: %q %v, expected: no second rotation", rotatedB, err)
+	}
+	b.Write([]byte("b2\n"))
+	a.Write([]byte("a2\n"))
+
+	if data, _ := ioutil.ReadFile(rotatedA); string(data) != "a1\nb1\n" {
+		t.Errorf("Has: %q, expected: the lines before the rotation", data)
+	}
+	if data, _ := ioutil.ReadFile(name); string(data) != "b2\na2\n" {
+		t.Errorf("Has: %q, expected: the lines after the rotation", data)
+	}
+	matches, _ := filepath.Glob(filepath.Join(dir, "access-*.log"))
+	if len(matches) != 1 {
+		t.Errorf("Has: %v, expected: a single rotated file", matches)
+	}
+}
+
+func TestFileWriter_sharedLock(t *testing.T) {
+	if !flockSupported {
+		t.Skip(errFlockUnsupported)
+	}
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	fw, err := NewFileWriter(FileConfig{Filename: name, Shared: true})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+
+	// Another process rotating holds the lock exclusively
+	lock, _ := os.OpenFile(name+".lock", os.O_RDWR, 0644)
+	defer lock.Close()
+	flock(lock, true)
+	written := make(chan struct{})
+	go func() {
+		fw.Write([]byte("GET / 200\n"))
+		close(written)
+	}()
+	select {
+	case <-written:
+		t.Errorf("Has: written, expected: the write waiting for the lock")
+	case <-time.After(50 * time.Millisecond):
+	}
+	funlock(lock)
+	<-written
+}
+
+func TestFileWriter_OnRotate(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rotated := make(chan string, 1)
+	l := NewLogger(Config{
+		Format: "${path}\n",
+		File: &FileConfig{
+			Filename: filepath.Join(dir, "access.log"),
+			OnRotate: func(oldPath string) { rotated <- oldPath },
+		},
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/", func(ctx *fiber.Ctx) {})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	fw := l.cfg.Output.(*FileWriter)
+	defer fw.Close()
+	if err := fw.Rotate(); err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	select {
+	case path := <-rotated:
+		if b, _ := ioutil.ReadFile(path); string(b) != "/\n" {
+			t.Errorf("Has: %q, expected: the entry in the rotated file", b)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Has: no call, expected: OnRotate with the rotated file")
+	}
+}
+
+func TestFileWriter_Compress(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	rotated := make(chan string, 1)
+	fw, err := NewFileWriter(FileConfig{
+		Filename: filepath.Join(dir, "access.log"),
+		Compress: CompressGzip,
+		OnRotate: func(oldPath string) { rotated <- oldPath },
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+	fw.Write([]byte("GET / 200\n"))
+	path, _ := fw.rotate(time.Date(2024, 5, 1, 10, 0, 0, 0, time.UTC))
+
+	select {
+	case has := <-rotated:
+		if has != path+".gz" {
+			t.Errorf("Has: %s, expected: OnRotate with %s.gz", has, path)
+		}
+		b, _ := ioutil.ReadFile(has)
+		if data := gunzip(t, b); data != "GET / 200\n" {
+			t.Errorf("Has: %q, expected: the rotated file compressed", data)
+		}
+		if _, err := os.Stat(path); !os.IsNotExist(err) {
+			t.Errorf("Has: %v, expected: the rotated file removed", err)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Has: no call, expected: OnRotate once compressed")
+	}
+}
+
+func TestFileWriter_CompressRetention(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	var mu sync.Mutex
+	var missing []string
+	calls := 0
+	fw, err := NewFileWriter(FileConfig{
+		Filename:   filepath.Join(dir, "access.log"),
+		Location:   time.UTC,
+		Compress:   CompressGzip,
+		MaxBackups: 1,
+		OnRotate: func(oldPath string) {
+			mu.Lock()
+			defer mu.Unlock()
+			calls++
+			if _, err := os.Stat(oldPath); err != nil {
+				missing = append(missing, oldPath)
+			}
+		},
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	for i := 0; i < 6; i++ {
+		fw.Write([]byte("GET / 200\n"))
+		fw.rotate(time.Date(2024, 5, 1, 10, i, 0, 0, time.UTC))
+	}
+	fw.Close()
+	// Close waits for the compression, OnRotate follows it
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		mu.Lock()
+		done := calls == 6
+		mu.Unlock()
+		if done {
+			break
+		}
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if calls != 6 || len(missing) != 0 {
+		t.Errorf("Has: %d calls, %v missing, expected: every rotated file passed to OnRotate", calls, missing)
+	}
+}
+
+func TestFileWriter_maxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	fw, err := NewFileWriter(FileConfig{Filename: name, MaxSize: 25})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+
+	for _, line := range []string{"GET /a 200\n", "GET /b 200\n", "GET /c 200\n"} {
+		fw.Write([]byte(line))
+	}
+	// The second line fit, the third one would have grown the file to 33 bytes
+	matches, _ := filepath.Glob(filepath.Join(dir, "access-*.log"))
+	if len(matches) != 1 {
+		t.Fatalf("Has: %v, expected: a rotated file", matches)
+	}
+	if b, _ := ioutil.ReadFile(matches[0]); string(b) != "GET /a 200\nGET /b 200\n" {
+		t.Errorf("Has: %q, expected: the first lines", b)
+	}
+	if b, _ := ioutil.ReadFile(name); string(b) != "GET /c 200\n" {
+		t.Errorf("Has: %q, expected: the line after the rotation", b)
+	}
+}
+
+func TestFileWriter_retention(t *testing.T) {
+	for _, cfg := range []FileConfig{{MaxBackups: 2}, {MaxAge: 90 * time.Minute}} {
+		dir, err := ioutil.TempDir("", "logger")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+		now := time.Now()
+		for i, day := range []string{"01", "02", "03"} {
+			path := filepath.Join(dir, "access-2024-05-"+day+"T00-00-00.000.log")
+			ioutil.WriteFile(path, nil, 0644)
+			modified := now.Add(time.Duration(i-3) * time.Hour)
+			os.Chtimes(path, modified, modified)
+		}
+		ioutil.WriteFile(filepath.Join(dir, "access-notes.log"), nil, 0644)
+
+		cfg.Filename = filepath.Join(dir, "access.log")
+		cfg.Location = time.UTC
+		fw, err := NewFileWriter(cfg)
+		if err != nil {
+			t.Fatalf("Has: %v, expected: nil", err)
+		}
+		fw.Rotate()
+		fw.Close()
+
+		var names []string
+		infos, _ := ioutil.ReadDir(dir)
+		for _, info := range infos {
+			names = append(names, info.Name())
+		}
+		// The rotated file, the newest backup, the unrelated file and access.log
+		if len(names) != 4 || names[0] != "access-2024-05-03T00-00-00.000.log" || names[2] != "access-notes.log" {
+			t.Errorf("Has: %v, expected: the old backups removed with %+v", names, cfg)
+		}
+	}
+}
+
+func TestFileWriter_sharedMaxSize(t *testing.T) {
+	if !flockSupported {
+		t.Skip(errFlockUnsupported)
+	}
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	a, err := NewFileWriter(FileConfig{Filename: name, Shared: true, MaxSize: 25})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer a.Close()
+	b, _ := NewFileWriter(FileConfig{Filename: name, Shared: true, MaxSize: 25})
+	defer b.Close()
+
+	// The size counts the lines of both writers
+	a.Write([]byte("GET /a 200\n"))
+	b.Write([]byte("GET /b 200\n"))
+	a.Write([]byte("GET /c 200\n"))
+	b.Write([]byte("GET /d 200\n"))
+
+	matches, _ := filepath.Glob(filepath.Join(dir, "access-*.log"))
+	if len(matches) != 1 {
+		t.Fatalf("Has: %v, expected: a single rotated file", matches)
+	}
+	if data, _ := ioutil.ReadFile(name); string(data) != "GET /c 200\nGET /d 200\n" {
+		t.Errorf("Has: %q, expected: the lines after the rotation", data)
+	}
+}
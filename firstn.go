@@ -0,0 +1,79 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// firstN logs the first n entries per key and interval, samples the rest
+// and reports how many were suppressed once the interval has elapsed
+type firstN struct {
+	mu       sync.Mutex
+	n        int
+	rate     float64
+	seed     string
+	interval time.Duration
+	reset    time.Time
+	seen     map[string]int
+	dropped  map[string]int
+}
+
+func newFirstN(n int, rate float64, seed string, interval time.Duration) *firstN {
+	return &firstN{
+		n:        n,
+		rate:     rate,
+		seed:     seed,
+		interval: interval,
+		seen:     make(map[string]int),
+		dropped:  make(map[string]int),
+	}
+}
+
+// allow reports whether the entry for key should be logged, entries after the
+// first n are sampled by id. When a new interval starts the suppressed counts
+// of the previous one are written to w
+func (f *firstN) allow(key, id string, now time.Time, w summaryWriter) bool {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if now.After(f.reset) {
+		f.flush(w, now)
+		f.reset = now.Add(f.interval)
+	}
+	f.seen[key]++
+	if f.seen[key] <= f.n || sample(f.seed, id, f.rate) {
+		return true
+	}
+	f.dropped[key]++
+	return false
+}
+
+// tick writes the summary of an interval that elapsed without a later
+// entry, the next entry starts a new interval. With all the current
+// one is written, as on Close
+func (f *firstN) tick(now time.Time, all bool, w summaryWriter) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if all || !f.reset.IsZero() && now.After(f.reset) {
+		f.flush(w, now)
+		f.reset = time.Time{}
+	}
+}
+
+// flush writes one summary line per key with suppressed entries and
+// starts counting from zero
+func (f *firstN) flush(w summaryWriter, now time.Time) {
+	keys := make([]string, 0, len(f.dropped))
+	for key := range f.dropped {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		w.writeSummary(&Entry{Time: now, Fields: []Field{{"summary", "firstN"}, {"key", key}, {"count", f.seen[key]},
+			{"window", f.interval.String()}, {"suppressed", f.dropped[key]}}},
+			fmt.Sprintf("%s x%d in last %s, %d suppressed", key, f.seen[key], f.interval, f.dropped[key]))
+	}
+	f.seen = make(map[string]int, len(f.seen))
+	f.dropped = make(map[string]int, len(f.dropped))
+}
@@ -0,0 +1,31 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFirstN_allow(t *testing.T) {
+	buf := &strings.Builder{}
+	f := newFirstN(2, 0, "", time.Minute)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	logged := 0
+	for i := 0; i < 5; i++ {
+		if f.allow("/api 502", "", now, textSummaries{buf, "15:04:05"}) {
+			logged++
+		}
+	}
+	if logged != 2 {
+		t.Errorf("Has: %d, expected: 2", logged)
+	}
+	if !f.allow("/api 502", "", now.Add(2*time.Minute), textSummaries{buf, "15:04:05"}) {
+		t.Errorf("Has: false, expected: true")
+	}
+
+	expectedOutput := "12:02:00 /api 502 x5 in last 1m0s, 3 suppressed\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,38 @@
+package logger
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/gofiber/fiber"
+)
+
+// LocalsFlags is the Locals key of the feature flags evaluated for the
+// request, flag middleware can set it directly or through SetFlag
+const LocalsFlags = "logger.flags" // map[string]string
+
+// SetFlag records the value a feature flag evaluated to for the request
+func SetFlag(c *fiber.Ctx, key, value string) {
+	flags, _ := c.Locals(LocalsFlags).(map[string]string)
+	if flags == nil {
+		flags = make(map[string]string)
+		c.Locals(LocalsFlags, flags)
+	}
+	flags[key] = value
+}
+
+func flag(c *fiber.Ctx, key string) string {
+	flags, _ := c.Locals(LocalsFlags).(map[string]string)
+	return flags[key]
+}
+
+// flags lists all flags of the request as key=value sorted by key
+func flags(c *fiber.Ctx) string {
+	flags, _ := c.Locals(LocalsFlags).(map[string]string)
+	pairs := make([]string, 0, len(flags))
+	for key, value := range flags {
+		pairs = append(pairs, key+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestSetFlag(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${flags} ${flag:checkout} ${flag:missing};",
+		Output: buf,
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		SetFlag(ctx, "search", "off")
+		SetFlag(ctx, "checkout", "v2")
+	})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "checkout=v2,search=off v2 ;"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
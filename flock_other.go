@@ -0,0 +1,16 @@
+//go:build !linux && !darwin && !freebsd && !netbsd && !openbsd && !dragonfly
+// +build !linux,!darwin,!freebsd,!netbsd,!openbsd,!dragonfly
+
+package logger
+
+import "os"
+
+const flockSupported = false
+
+func flock(f *os.File, exclusive bool) error {
+	return errFlockUnsupported
+}
+
+func funlock(f *os.File) error {
+	return errFlockUnsupported
+}
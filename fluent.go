@@ -0,0 +1,381 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"sync"
+	"time"
+)
+
+// FluentConfig configures a FluentWriter
+type FluentConfig struct {
+	// Network is "tcp" or "unix"
+	// Optional. Default: "tcp"
+	Network string
+	// Address of the forward input of fluentd or fluent-bit
+	// Optional. Default: "127.0.0.1:24224"
+	Address string
+	// TLS enables TLS for "tcp"
+	// Optional. Default: nil
+	TLS *tls.Config
+	// Tag is the tag of the events, used by fluentd to route them
+	// Optional. Default: "fiber.access"
+	Tag string
+	// RequireAck waits for the server to acknowledge every event, so a lost
+	// connection is noticed before the next one
+	// Optional. Default: false
+	RequireAck bool
+	// AckTimeout is the longest wait for an acknowledgement
+	// Optional. Default: 5 * time.Second
+	AckTimeout time.Duration
+	// Structured sends the keys and fields of the entry as the record
+	// instead of the encoded line as "log"
+	// Optional. Default: false
+	Structured bool
+	// Fields are tags added to structured records, e.g. {"ua", "requestId"}
+	// Optional. Default: nil
+	Fields []string
+}
+
+// FluentWriter sends events to fluentd or fluent-bit with the forward
+// protocol, each write being one event in message mode. An event failing
+// to send is retried once on a new connection
+type FluentWriter struct {
+	cfg  FluentConfig
+	mu   sync.Mutex
+	conn net.Conn
+	r    *bufio.Reader
+	buf  []byte
+}
+
+// NewFluentWriter connects to the forward input of cfg
+func NewFluentWriter(cfg FluentConfig) (*FluentWriter, error) {
+	if cfg.Network == "" {
+		cfg.Network = "tcp"
+	}
+	if cfg.Network != "tcp" && cfg.Network != "unix" {
+		return nil, errors.New("logger: fluent network must be tcp or unix")
+	}
+	if cfg.Address == "" {
+		cfg.Address = "127.0.0.1:24224"
+	}
+	if cfg.Tag == "" {
+		cfg.Tag = "fiber.access"
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 5 * time.Second
+	}
+	w := &FluentWriter{cfg: cfg}
+	if err := w.dial(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *FluentWriter) dial() error {
+	conn, err := dial(w.cfg.Network, w.cfg.Address, w.cfg.TLS)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	w.r = bufio.NewReader(conn)
+	return nil
+}
+
+// Tags returns Fields, the tags of structured records
+func (w *FluentWriter) Tags() []string {
+	if !w.cfg.Structured {
+		return nil
+	}
+	return w.cfg.Fields
+}
+
+// Write sends p as the "log" of an event, a trailing newline is dropped
+func (w *FluentWriter) Write(p []byte) (int, error) {
+	return w.write(nil, time.Now(), p)
+}
+
+// WriteEntry sends the event of e, its keys and fields as the record with
+// Structured
+func (w *FluentWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	t := e.Time
+	if !w.cfg.Structured {
+		e = nil
+	}
+	return w.write(e, t, p)
+}
+
+func (w *FluentWriter) write(e *Entry, t time.Time, p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	var chunk string
+	if w.cfg.RequireAck {
+		var id [16]byte
+		if _, err := rand.Read(id[:]); err != nil {
+			return 0, err
+		}
+		chunk = base64.StdEncoding.EncodeToString(id[:])
+	}
+	w.buf = w.appendMessage(w.buf[:0], e, t, p, chunk)
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if w.conn == nil {
+			if err = w.dial(); err != nil {
+				return 0, err
+			}
+		}
+		if err = w.send(chunk); err == nil {
+			return n, nil
+		}
+		w.conn.Close()
+		w.conn = nil
+	}
+	return 0, err
+}
+
+// send writes the message in w.buf and waits for the ack of chunk
+func (w *FluentWriter) send(chunk string) error {
+	if _, err := w.conn.Write(w.buf); err != nil {
+		return err
+	}
+	if chunk == "" {
+		return nil
+	}
+	w.conn.SetReadDeadline(time.Now().Add(w.cfg.AckTimeout))
+	ack, err := readFluentAck(w.r)
+	if err != nil {
+		return err
+	}
+	if ack != chunk {
+		return errors.New("logger: fluent ack " + ack + " does not match chunk " + chunk)
+	}
+	return nil
+}
+
+// appendMessage appends the event as [tag, time, record, option], the
+// option only holding the chunk id of an ack
+func (w *FluentWriter) appendMessage(b []byte, e *Entry, t time.Time, line []byte, chunk string) []byte {
+	if chunk == "" {
+		b = append(b, 0x93)
+	} else {
+		b = append(b, 0x94)
+	}
+	b = appendMsgpackString(b, w.cfg.Tag)
+	// EventTime extension with nanoseconds
+	b = append(b, 0xd7, 0x00)
+	b = appendUint32(b, uint32(t.Unix()))
+	b = appendUint32(b, uint32(t.Nanosecond()))
+	if e == nil {
+		b = append(b, 0x81)
+		b = appendMsgpackString(b, "log")
+		b = appendMsgpackString(b, string(line))
+	} else {
+		b = w.appendRecord(b, e)
+	}
+	if chunk != "" {
+		b = append(b, 0x81)
+		b = appendMsgpackString(b, "chunk")
+		b = appendMsgpackString(b, chunk)
+	}
+	return b
+}
+
+// appendRecord appends the keys of e and its fields as a map
+func (w *FluentWriter) appendRecord(b []byte, e *Entry) []byte {
+	fields := make([]Field, 0, 8+len(e.Fields))
+	add := func(key string, value interface{}) {
+		if s, ok := value.(string); !ok || s != "" {
+			fields = append(fields, Field{key, value})
+		}
+	}
+	add("method", e.Method)
+	add("path", e.Path)
+	add("route", e.Route)
+	add("status", e.Status)
+	add("latency_ms", milliseconds(e.Latency))
+	add("ip", e.IP)
+	add("request_id", e.ID)
+	if e.Err != nil {
+		add("error", e.Err.Error())
+	}
+	for _, f := range e.Fields {
+		if knownTag(f.Key) && !contains(w.cfg.Fields, f.Key) {
+			continue
+		}
+		add(f.Key, f.Value)
+	}
+	b = appendMsgpackMap(b, len(fields))
+	for _, f := range fields {
+		b = appendMsgpackString(b, f.Key)
+		b = appendMsgpackValue(b, f.Value)
+	}
+	return b
+}
+
+// Connected reports whether the writer holds a connection, for Health
+func (w *FluentWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn != nil
+}
+
+// Close closes the connection
+func (w *FluentWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
+
+// readFluentAck reads the {"ack": chunk} response to an event
+func readFluentAck(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	if c&0xf0 != 0x80 {
+		return "", fmt.Errorf("logger: fluent ack is not a map but 0x%02x", c)
+	}
+	var ack string
+	for i := 0; i < int(c&0x0f); i++ {
+		key, err := readMsgpackString(r)
+		if err != nil {
+			return "", err
+		}
+		value, err := readMsgpackString(r)
+		if err != nil {
+			return "", err
+		}
+		if key == "ack" {
+			ack = value
+		}
+	}
+	return ack, nil
+}
+
+// readMsgpackString reads a str of up to 64 KiB
+func readMsgpackString(r *bufio.Reader) (string, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return "", err
+	}
+	var n int
+	switch {
+	case c&0xe0 == 0xa0:
+		n = int(c & 0x1f)
+	case c == 0xd9:
+		l, err := r.ReadByte()
+		if err != nil {
+			return "", err
+		}
+		n = int(l)
+	case c == 0xda:
+		var l [2]byte
+		if _, err := io.ReadFull(r, l[:]); err != nil {
+			return "", err
+		}
+		n = int(binary.BigEndian.Uint16(l[:]))
+	default:
+		return "", fmt.Errorf("logger: expected a msgpack string, got 0x%02x", c)
+	}
+	s := make([]byte, n)
+	if _, err := io.ReadFull(r, s); err != nil {
+		return "", err
+	}
+	return string(s), nil
+}
+
+// appendMsgpackString appends s as a msgpack str
+func appendMsgpackString(b []byte, s string) []byte {
+	switch n := len(s); {
+	case n < 32:
+		b = append(b, 0xa0|byte(n))
+	case n < 1<<8:
+		b = append(b, 0xd9, byte(n))
+	case n < 1<<16:
+		b = append(b, 0xda, byte(n>>8), byte(n))
+	default:
+		b = append(b, 0xdb)
+		b = appendUint32(b, uint32(n))
+	}
+	return append(b, s...)
+}
+
+// appendMsgpackMap appends the header of a map of n pairs
+func appendMsgpackMap(b []byte, n int) []byte {
+	switch {
+	case n < 16:
+		return append(b, 0x80|byte(n))
+	case n < 1<<16:
+		return append(b, 0xde, byte(n>>8), byte(n))
+	}
+	b = append(b, 0xdf)
+	return appendUint32(b, uint32(n))
+}
+
+// appendMsgpackInt appends n as a fixint or int64
+func appendMsgpackInt(b []byte, n int64) []byte {
+	if n >= -32 && n < 128 {
+		return append(b, byte(n))
+	}
+	b = append(b, 0xd3)
+	return appendUint64(b, uint64(n))
+}
+
+// appendMsgpackValue appends v as msgpack, types without an equivalent as
+// their string
+func appendMsgpackValue(b []byte, v interface{}) []byte {
+	switch v := v.(type) {
+	case nil:
+		return append(b, 0xc0)
+	case string:
+		return appendMsgpackString(b, v)
+	case []byte:
+		return appendMsgpackString(b, string(v))
+	case bool:
+		if v {
+			return append(b, 0xc3)
+		}
+		return append(b, 0xc2)
+	case int:
+		return appendMsgpackInt(b, int64(v))
+	case int32:
+		return appendMsgpackInt(b, int64(v))
+	case int64:
+		return appendMsgpackInt(b, v)
+	case uint32:
+		return appendMsgpackInt(b, int64(v))
+	case float32:
+		return appendMsgpackValue(b, float64(v))
+	case float64:
+		b = append(b, 0xcb)
+		return appendUint64(b, math.Float64bits(v))
+	case error:
+		return appendMsgpackString(b, v.Error())
+	}
+	return appendMsgpackString(b, fmt.Sprint(v))
+}
+
+func appendUint32(b []byte, n uint32) []byte {
+	return append(b, byte(n>>24), byte(n>>16), byte(n>>8), byte(n))
+}
+
+func appendUint64(b []byte, n uint64) []byte {
+	return appendUint32(appendUint32(b, uint32(n>>32)), uint32(n))
+}
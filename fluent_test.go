@@ -0,0 +1,216 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// decodeMsgpack decodes the msgpack types FluentWriter writes, EventTime as
+// a time.Time
+func decodeMsgpack(r *bufio.Reader) (interface{}, error) {
+	c, err := r.ReadByte()
+	if err != nil {
+		return nil, err
+	}
+	read := func(n int) []byte {
+		b := make([]byte, n)
+		if _, e := io.ReadFull(r, b); e != nil {
+			err = e
+		}
+		return b
+	}
+	collection := func(n int, pairs bool) (interface{}, error) {
+		if pairs {
+			m := make(map[string]interface{})
+			for i := 0; i < n; i++ {
+				k, err := decodeMsgpack(r)
+				if err != nil {
+					return nil, err
+				}
+				if m[k.(string)], err = decodeMsgpack(r); err != nil {
+					return nil, err
+				}
+			}
+			return m, nil
+		}
+		a := make([]interface{}, n)
+		for i := range a {
+			if a[i], err = decodeMsgpack(r); err != nil {
+				return nil, err
+			}
+		}
+		return a, nil
+	}
+	switch {
+	case c < 0x80:
+		return int64(c), nil
+	case c >= 0xe0:
+		return int64(int8(c)), nil
+	case c&0xf0 == 0x80:
+		return collection(int(c&0x0f), true)
+	case c&0xf0 == 0x90:
+		return collection(int(c&0x0f), false)
+	case c&0xe0 == 0xa0:
+		return string(read(int(c & 0x1f))), err
+	}
+	switch c {
+	case 0xc0:
+		return nil, nil
+	case 0xc2, 0xc3:
+		return c == 0xc3, nil
+	case 0xcb:
+		return math.Float64frombits(binary.BigEndian.Uint64(read(8))), err
+	case 0xd3:
+		return int64(binary.BigEndian.Uint64(read(8))), err
+	case 0xd7:
+		b := read(9)
+		return time.Unix(int64(binary.BigEndian.Uint32(b[1:])), int64(binary.BigEndian.Uint32(b[5:]))), err
+	case 0xd9:
+		return string(read(int(read(1)[0]))), err
+	case 0xda:
+		return string(read(int(binary.BigEndian.Uint16(read(2))))), err
+	case 0xdb:
+		return string(read(int(binary.BigEndian.Uint32(read(4))))), err
+	case 0xde:
+		return collection(int(binary.BigEndian.Uint16(read(2))), true)
+	}
+	return nil, fmt.Errorf("unexpected 0x%02x", c)
+}
+
+// fluentServer accepts a connection and decodes its events, acknowledging
+// them unless ack is false
+func fluentServer(t *testing.T, ack bool) (net.Listener, chan []interface{}) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	events := make(chan []interface{}, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				r := bufio.NewReader(conn)
+				for {
+					v, err := decodeMsgpack(r)
+					if err != nil {
+						return
+					}
+					event := v.([]interface{})
+					events <- event
+					if ack && len(event) == 4 {
+						chunk := event[3].(map[string]interface{})["chunk"].(string)
+						conn.Write(appendMsgpackString(appendMsgpackString([]byte{0x81}, "ack"), chunk))
+					}
+				}
+			}()
+		}
+	}()
+	return ln, events
+}
+
+func TestFluentWriter(t *testing.T) {
+	ln, events := fluentServer(t, true)
+	defer ln.Close()
+	fw, err := NewFluentWriter(FluentConfig{
+		Address:    ln.Addr().String(),
+		Tag:        "shop.access",
+		RequireAck: true,
+		Structured: true,
+		Fields:     []string{"header:X-Tenant"},
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+	app := fiber.New()
+	app.Use(New(Config{Output: fw}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.Next(errors.New("upstream failed"))
+	})
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(502)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("X-Tenant", "acme")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	event := <-events
+	if event[0] != "shop.access" {
+		t.Errorf("Has: %v, expected: tag shop.access", event[0])
+	}
+	if ts, ok := event[1].(time.Time); !ok || time.Since(ts) > time.Minute {
+		t.Errorf("Has: %v, expected: the EventTime of the entry", event[1])
+	}
+	record := event[2].(map[string]interface{})
+	if record["route"] != "/users/:id" || record["status"] != int64(502) || record["error"] != "upstream failed" || record["header:X-Tenant"] != "acme" {
+		t.Errorf("Has: %v, expected: the keys and fields of the entry", record)
+	}
+	if _, ok := record["latency_ms"].(float64); !ok {
+		t.Errorf("Has: %v, expected: latency_ms as float", record["latency_ms"])
+	}
+}
+
+func TestFluentWriter_reconnect(t *testing.T) {
+	ln, events := fluentServer(t, false)
+	defer ln.Close()
+	fw, err := NewFluentWriter(FluentConfig{Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+
+	fw.conn.Close()
+	if _, err := fw.Write([]byte("GET / 200\n")); err != nil {
+		t.Errorf("Has: %v, expected: nil after reconnecting", err)
+	}
+	event := <-events
+	if record := event[2].(map[string]interface{}); record["log"] != "GET / 200" || len(event) != 3 {
+		t.Errorf("Has: %v, expected: the line as log without option", event)
+	}
+}
+
+func TestFluentWriter_ackTimeout(t *testing.T) {
+	ln, _ := fluentServer(t, false)
+	defer ln.Close()
+	fw, err := NewFluentWriter(FluentConfig{Address: ln.Addr().String(), RequireAck: true, AckTimeout: 10 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer fw.Close()
+	if _, err := fw.Write([]byte("GET / 200\n")); err == nil {
+		t.Errorf("Has: nil, expected: an error without ack")
+	}
+	if fw.Connected() {
+		t.Errorf("Has: connected, expected: disconnected after a missing ack")
+	}
+}
+
+func TestAppendMsgpackValue(t *testing.T) {
+	long := string(make([]byte, 300))
+	for _, v := range []interface{}{nil, true, false, int64(5), int64(-7), int64(1 << 40), int64(-1000), 1.5, "a", long} {
+		b := appendMsgpackValue(nil, v)
+		decoded, err := decodeMsgpack(bufio.NewReader(bytes.NewReader(b)))
+		if err != nil || decoded != v {
+			t.Errorf("Has: %v (%v), expected: %v", decoded, err, v)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+//go:build gofuzz
+// +build gofuzz
+
+package logger
+
+// Fuzz targets for go-fuzz (https://github.com/dvyukov/go-fuzz), run one
+// with e.g.
+//
+//	go-fuzz-build -func FuzzRequest && go-fuzz
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+	"github.com/valyala/fasthttp"
+	"github.com/valyala/fasttemplate"
+)
+
+// fuzzPartials are the partials available to FuzzFormat
+var fuzzPartials = map[string]string{
+	"a": "${method} ${partial:b}",
+	"b": "${status}",
+	"c": "${partial:c}",
+}
+
+// FuzzFormat parses data as a Format
+func FuzzFormat(data []byte) int {
+	format, err := expandPartials(string(data), fuzzPartials)
+	if err != nil {
+		return 0
+	}
+	tmpl, err := fasttemplate.NewTemplate(format, "${", "}")
+	if err != nil {
+		return 0
+	}
+	for _, tag := range templateTags(tmpl) {
+		knownTag(tag)
+	}
+	return 1
+}
+
+// fuzzLogger renders every tag with Multiline and Colors to exercise all
+// escapers, its sinks have every encoder
+var fuzzLogger = func() *Logger {
+	var format []string
+	for _, tag := range tags {
+		if tag == strIpHostname {
+			continue
+		}
+		if strings.HasSuffix(tag, ":") {
+			tag += "X-Key"
+		}
+		format = append(format, "${"+tag+"}")
+	}
+	var sinks []SinkConfig
+	for _, f := range []string{FormatJSON, FormatGELF, FormatSyslog, FormatCEF} {
+		sinks = append(sinks, SinkConfig{Format: f, Output: ioutil.Discard})
+	}
+	return NewLogger(Config{
+		Format:      strings.Join(format, " "),
+		Fields:      []string{strUa, "header:X-Key"},
+		Multiline:   MultilineIndent,
+		Colors:      true,
+		Output:      ioutil.Discard,
+		Diagnostics: ioutil.Discard,
+		Sinks:       sinks,
+	})
+}()
+
+// FuzzRequest parses data as an HTTP request and logs it
+func FuzzRequest(data []byte) int {
+	fctx := &fasthttp.RequestCtx{}
+	if err := fctx.Request.Read(bufio.NewReader(bytes.NewReader(data))); err != nil {
+		return 0
+	}
+	c := fiber.AcquireCtx(fctx)
+	defer fiber.ReleaseCtx(c)
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	now := time.Now()
+	fuzzLogger.render(buf, fuzzLogger.tmpl, c, now, now)
+	e := fuzzLogger.newEntry(c, now, now, PriorityOK)
+	for _, s := range fuzzLogger.sinks {
+		buf.Reset()
+		if err := s.Encoder.Encode(buf, e); err != nil {
+			panic(err)
+		}
+		if bytes.Count(buf.B, []byte("\n")) != 1 {
+			panic(s.Format + " entry is not a single line: " + buf.String())
+		}
+		if s.Format == FormatJSON && !json.Valid(buf.B) {
+			panic("invalid JSON: " + buf.String())
+		}
+	}
+	return 1
+}
+
+// FuzzEscape checks the escapers of the encoders with data as a value
+func FuzzEscape(data []byte) int {
+	s := string(data)
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+
+	appendJSONString(buf, s)
+	var decoded string
+	if err := json.Unmarshal(buf.B, &decoded); err != nil {
+		panic(err)
+	}
+	if utf8.ValidString(s) && decoded != s {
+		panic("JSON string does not round trip: " + buf.String())
+	}
+
+	buf.Reset()
+	appendSyslogParam(buf, s, s)
+	if bytes.IndexByte(buf.B, '\n') >= 0 {
+		panic("syslog parameter with a line break: " + buf.String())
+	}
+
+	buf.Reset()
+	appendCEFExtension(buf, "k", s)
+	if bytes.IndexAny(buf.B, "\r\n") >= 0 {
+		panic("CEF extension with a line break: " + buf.String())
+	}
+
+	buf.Reset()
+	buf.WriteString(s)
+	fold(buf, 0, MultilineFold)
+	if bytes.IndexAny(buf.B, "\r\n") >= 0 {
+		panic("folded value with a line break: " + buf.String())
+	}
+
+	if bytes.IndexByte(stripANSI([]byte(s)), 0x1b) >= 0 {
+		panic("escape sequence left: " + s)
+	}
+	return 1
+}
@@ -0,0 +1,112 @@
+package logger
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// FormatGCP as Format writes entries with a GCPEncoder
+const FormatGCP = "gcp"
+
+// HeaderCloudTraceContext is the trace header of Google Cloud load balancers
+const HeaderCloudTraceContext = "X-Cloud-Trace-Context"
+
+// gcpTags are the tags a GCPEncoder maps to httpRequest and the trace
+var gcpTags = []string{strUrl, strUa, strReferer, strBytesSent, strHeader + HeaderCloudTraceContext}
+
+// GCPEncoder writes an entry as JSON for Google Cloud Logging, with a
+// severity derived from the status, an httpRequest object and the trace of
+// the X-Cloud-Trace-Context header, so GKE and Cloud Run parse request logs
+// correctly. The tags in Fields are written as keys of the JSON payload
+type GCPEncoder struct {
+	// ProjectID prefixes the trace as projects/<id>/traces/<trace>, the trace
+	// is left out without it
+	// Optional. Default: $GOOGLE_CLOUD_PROJECT
+	ProjectID string
+	// Fields are tags written as keys of their own, e.g. {"requestId"}
+	// Optional. Default: nil
+	Fields []string
+}
+
+// Tags returns the tags of httpRequest, the trace header and Fields
+func (g *GCPEncoder) Tags() []string {
+	return append(append([]string(nil), gcpTags...), g.Fields...)
+}
+
+// Encode writes e as a line of JSON
+func (g *GCPEncoder) Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error {
+	buf.WriteString(`{"severity":"` + gcpSeverity(e.Status) + `","time":`)
+	appendJSONString(buf, e.Time.UTC().Format("2006-01-02T15:04:05.000000000Z"))
+	buf.WriteString(`,"message":`)
+	appendJSONString(buf, e.Method+" "+e.Path+" "+strconv.Itoa(e.Status))
+	buf.WriteString(`,"httpRequest":{"requestMethod":`)
+	appendJSONString(buf, e.Method)
+	appendJSONKey(buf, "requestUrl", e.getString(strUrl))
+	buf.WriteString(`,"status":`)
+	buf.B = strconv.AppendInt(buf.B, int64(e.Status), 10)
+	appendJSONKey(buf, "responseSize", e.getString(strBytesSent))
+	appendJSONKey(buf, "userAgent", e.getString(strUa))
+	appendJSONKey(buf, "remoteIp", e.IP)
+	appendJSONKey(buf, "referer", e.getString(strReferer))
+	buf.WriteString(`,"latency":"`)
+	buf.B = strconv.AppendFloat(buf.B, e.Latency.Seconds(), 'f', -1, 64)
+	buf.WriteString(`s"}`)
+	project := g.ProjectID
+	if project == "" {
+		project = os.Getenv("GOOGLE_CLOUD_PROJECT")
+	}
+	if trace, span, sampled := parseCloudTrace(e.getString(strHeader + HeaderCloudTraceContext)); trace != "" && project != "" {
+		appendJSONKey(buf, "logging.googleapis.com/trace", "projects/"+project+"/traces/"+trace)
+		appendJSONKey(buf, "logging.googleapis.com/spanId", span)
+		buf.WriteString(`,"logging.googleapis.com/trace_sampled":`)
+		buf.B = strconv.AppendBool(buf.B, sampled)
+	}
+	if e.Err != nil {
+		appendJSONKey(buf, "error", e.Err.Error())
+	}
+	for _, f := range e.Fields {
+		if knownTag(f.Key) && !contains(g.Fields, f.Key) {
+			continue
+		}
+		buf.WriteString(",")
+		appendJSONString(buf, f.Key)
+		buf.WriteString(":")
+		if err := appendJSONValue(buf, f.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// gcpSeverity maps a status to a Cloud Logging severity
+func gcpSeverity(status int) string {
+	switch {
+	case status >= 500:
+		return "ERROR"
+	case status >= 400:
+		return "WARNING"
+	}
+	return "INFO"
+}
+
+// parseCloudTrace parses X-Cloud-Trace-Context "TRACE_ID/SPAN_ID;o=1", the
+// decimal span id is returned as 16 hex digits
+func parseCloudTrace(h string) (trace, span string, sampled bool) {
+	i := strings.IndexByte(h, '/')
+	if i < 0 {
+		return h, "", false
+	}
+	trace, h = h[:i], h[i+1:]
+	if j := strings.Index(h, ";o="); j >= 0 {
+		sampled = h[j+3:] == "1"
+		h = h[:j]
+	}
+	if id, err := strconv.ParseUint(h, 10, 64); err == nil {
+		span = leftPad(strconv.FormatUint(id, 16), spanIDLength)
+	}
+	return trace, span, sampled
+}
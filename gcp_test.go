@@ -0,0 +1,50 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestGCPEncoder(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Output:  buf,
+		Encoder: &GCPEncoder{ProjectID: "shop", Fields: []string{"header:X-Tenant"}},
+		Clock:   &stepClock{now: time.Date(2020, 7, 1, 12, 0, 0, 0, time.UTC), step: 1500 * time.Microsecond},
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.Status(404).SendString("nope")
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1?q=1", nil)
+	req.Header.Set("User-Agent", "curl")
+	req.Header.Set("X-Tenant", "acme")
+	req.Header.Set(HeaderCloudTraceContext, "105445aa7843bc8bf206b12000100000/1;o=1")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := `{"severity":"WARNING","time":"2020-07-01T12:00:00.004500000Z","message":"GET /users/1 404",` +
+		`"httpRequest":{"requestMethod":"GET","requestUrl":"/users/1?q=1","status":404,"responseSize":"4","userAgent":"curl","remoteIp":"0.0.0.0","latency":"0.0015s"},` +
+		`"logging.googleapis.com/trace":"projects/shop/traces/105445aa7843bc8bf206b12000100000","logging.googleapis.com/spanId":"0000000000000001","logging.googleapis.com/trace_sampled":true,` +
+		`"header:X-Tenant":"acme"}` + "\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestParseCloudTrace(t *testing.T) {
+	trace, span, sampled := parseCloudTrace("abc/255;o=0")
+	if trace != "abc" || span != "00000000000000ff" || sampled {
+		t.Errorf("Has: %s %s %v, expected: abc 00000000000000ff false", trace, span, sampled)
+	}
+	if trace, span, _ := parseCloudTrace("abc"); trace != "abc" || span != "" {
+		t.Errorf("Has: %s %s, expected: abc", trace, span)
+	}
+}
@@ -0,0 +1,253 @@
+package logger
+
+import (
+	"crypto/rand"
+	"crypto/tls"
+	"errors"
+	"net"
+	"os"
+	"strconv"
+	"sync"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// FormatGELF as Format writes entries with a GELFEncoder
+const FormatGELF = "gelf"
+
+// hostname is the default host of GELF messages
+var hostname, _ = os.Hostname()
+
+// GELFEncoder writes an entry as a GELF 1.1 message for Graylog. The level is
+// derived from the status, the standard keys and the tags in Fields become
+// additional fields prefixed with an underscore
+type GELFEncoder struct {
+	// Host is the host field of the messages
+	// Optional. Default: os.Hostname()
+	Host string
+	// Fields are tags written as additional fields, e.g. {"ua", "requestId"}
+	// Optional. Default: nil
+	Fields []string
+}
+
+// Tags returns Fields, the tags the encoder writes
+func (g *GELFEncoder) Tags() []string {
+	return g.Fields
+}
+
+// Encode writes e as a line of GELF, GELFWriter drops the newline when
+// framing the message
+func (g *GELFEncoder) Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error {
+	host := g.Host
+	if host == "" {
+		host = hostname
+	}
+	buf.WriteString(`{"version":"1.1","host":`)
+	appendJSONString(buf, host)
+	buf.WriteString(`,"short_message":`)
+	appendJSONString(buf, e.Method+" "+e.Path+" "+strconv.Itoa(e.Status))
+	buf.WriteString(`,"timestamp":`)
+	buf.B = strconv.AppendFloat(buf.B, float64(e.Time.UnixNano())/1e9, 'f', 3, 64)
+	buf.WriteString(`,"level":`)
+	buf.B = strconv.AppendInt(buf.B, int64(statusSeverity(e.Status)), 10)
+	appendJSONKey(buf, "_method", e.Method)
+	appendJSONKey(buf, "_path", e.Path)
+	appendJSONKey(buf, "_route", e.Route)
+	buf.WriteString(`,"_status":`)
+	buf.B = strconv.AppendInt(buf.B, int64(e.Status), 10)
+	buf.WriteString(`,"_latency_ms":`)
+	buf.B = strconv.AppendFloat(buf.B, milliseconds(e.Latency), 'f', -1, 64)
+	appendJSONKey(buf, "_ip", e.IP)
+	appendJSONKey(buf, "_request_id", e.ID)
+	if e.Err != nil {
+		appendJSONKey(buf, "_error", e.Err.Error())
+	}
+	for _, f := range e.Fields {
+		if knownTag(f.Key) && !contains(g.Fields, f.Key) {
+			continue
+		}
+		buf.WriteString(`,"_`)
+		buf.B = appendGELFKey(buf.B, f.Key)
+		buf.WriteString(`":`)
+		if err := appendJSONValue(buf, f.Value); err != nil {
+			return err
+		}
+	}
+	buf.WriteString("}\n")
+	return nil
+}
+
+// statusSeverity maps a status to a syslog severity: error for 5xx, warning
+// for 4xx and informational otherwise
+func statusSeverity(status int) int {
+	switch {
+	case status >= 500:
+		return 3
+	case status >= 400:
+		return 4
+	}
+	return 6
+}
+
+// appendGELFKey writes key with the characters GELF does not allow in field
+// names replaced by an underscore, e.g. header:X-Tenant as header_X-Tenant
+func appendGELFKey(dst []byte, key string) []byte {
+	for i := 0; i < len(key); i++ {
+		c := key[i]
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '_', c == '.', c == '-':
+			dst = append(dst, c)
+		default:
+			dst = append(dst, '_')
+		}
+	}
+	return dst
+}
+
+const (
+	// gelfChunkMagic starts every chunk of a chunked UDP message
+	gelfChunkMagic = "\x1e\x0f"
+	// gelfChunkHeader is the size of the magic, message id, sequence number
+	// and count
+	gelfChunkHeader = 12
+	gelfMaxChunks   = 128
+)
+
+// GELFConfig configures a GELFWriter
+type GELFConfig struct {
+	// Network is "udp" or "tcp"
+	// Optional. Default: "udp"
+	Network string
+	// Address of the Graylog input, e.g. "graylog:12201"
+	// Required
+	Address string
+	// TLS enables TLS for "tcp"
+	// Optional. Default: nil
+	TLS *tls.Config
+	// ChunkSize is the largest UDP datagram, messages above it are chunked
+	// Optional. Default: 1420
+	ChunkSize int
+}
+
+// GELFWriter ships GELF messages to Graylog, each write being one message.
+// Over UDP messages larger than ChunkSize are chunked, over TCP they are
+// delimited by a null byte and the connection is redialed after a failure
+type GELFWriter struct {
+	cfg  GELFConfig
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewGELFWriter dials the Graylog input of cfg
+func NewGELFWriter(cfg GELFConfig) (*GELFWriter, error) {
+	if cfg.Network == "" {
+		cfg.Network = "udp"
+	}
+	if cfg.Network != "udp" && cfg.Network != "tcp" {
+		return nil, errors.New("logger: GELF network must be udp or tcp")
+	}
+	if cfg.ChunkSize <= gelfChunkHeader {
+		cfg.ChunkSize = 1420
+	}
+	w := &GELFWriter{cfg: cfg}
+	if err := w.dial(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *GELFWriter) dial() error {
+	conn, err := dial(w.cfg.Network, w.cfg.Address, w.cfg.TLS)
+	if err != nil {
+		return err
+	}
+	w.conn = conn
+	return nil
+}
+
+// dial connects to address, with TLS over tcp when config is set
+func dial(network, address string, config *tls.Config) (net.Conn, error) {
+	if network == "tcp" && config != nil {
+		return tls.Dial(network, address, config)
+	}
+	return net.Dial(network, address)
+}
+
+// Write sends p as a single message, a trailing newline is dropped
+func (w *GELFWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			return 0, err
+		}
+	}
+	var err error
+	if w.cfg.Network == "tcp" {
+		bufs := net.Buffers{p, []byte{0}}
+		_, err = bufs.WriteTo(w.conn)
+	} else {
+		err = w.writeUDP(p)
+	}
+	if err != nil {
+		if w.cfg.Network == "tcp" {
+			w.conn.Close()
+			w.conn = nil
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// writeUDP sends p in one datagram or in chunks sharing a random message id
+func (w *GELFWriter) writeUDP(p []byte) error {
+	if len(p) <= w.cfg.ChunkSize {
+		_, err := w.conn.Write(p)
+		return err
+	}
+	size := w.cfg.ChunkSize - gelfChunkHeader
+	count := (len(p) + size - 1) / size
+	if count > gelfMaxChunks {
+		return errors.New("logger: GELF message of " + strconv.Itoa(len(p)) + " bytes exceeds 128 chunks")
+	}
+	chunk := make([]byte, gelfChunkHeader, w.cfg.ChunkSize)
+	copy(chunk, gelfChunkMagic)
+	if _, err := rand.Read(chunk[2:10]); err != nil {
+		return err
+	}
+	chunk[11] = byte(count)
+	for i := 0; i < count; i++ {
+		end := (i + 1) * size
+		if end > len(p) {
+			end = len(p)
+		}
+		chunk[10] = byte(i)
+		if _, err := w.conn.Write(append(chunk[:gelfChunkHeader], p[i*size:end]...)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Connected reports whether the writer holds a connection, for Health
+func (w *GELFWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn != nil
+}
+
+// Close closes the connection
+func (w *GELFWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,121 @@
+package logger
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestGELFEncoder(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: FormatGELF,
+		Fields: []string{"header:X-Tenant"},
+		Output: buf,
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.Next(errors.New("upstream failed"))
+	})
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(502)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("X-Tenant", "acme")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	var msg map[string]interface{}
+	if err := json.Unmarshal([]byte(buf.String()), &msg); err != nil {
+		t.Fatalf("Has: %v for %s, expected: JSON", err, buf.String())
+	}
+	if msg["version"] != "1.1" || msg["host"] != hostname || msg["short_message"] != "GET /users/1 502" || msg["level"] != 3.0 {
+		t.Errorf("Has: %s, expected: GELF keys", buf.String())
+	}
+	if msg["_route"] != "/users/:id" || msg["_status"] != 502.0 || msg["_error"] != "upstream failed" || msg["_header_X-Tenant"] != "acme" {
+		t.Errorf("Has: %s, expected: additional fields", buf.String())
+	}
+}
+
+func TestStatusSeverity(t *testing.T) {
+	for status, expected := range map[int]int{200: 6, 302: 6, 404: 4, 503: 3} {
+		if level := statusSeverity(status); level != expected {
+			t.Errorf("Has: %d for %d, expected: %d", level, status, expected)
+		}
+	}
+}
+
+func TestGELFWriter_udpChunks(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	w, err := NewGELFWriter(GELFConfig{Address: conn.LocalAddr().String(), ChunkSize: 32})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	msg := []byte(`{"version":"1.1","short_message":"` + strings.Repeat("x", 40) + `"}`)
+	if _, err := w.Write(append(msg, '\n')); err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+
+	var got []byte
+	p := make([]byte, 64)
+	for i := 0; i < 4; i++ {
+		n, _, err := conn.ReadFrom(p)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(p[:2]) != gelfChunkMagic || p[10] != byte(i) || p[11] != 4 {
+			t.Fatalf("Has: header % x, expected: chunk %d of 4", p[:12], i)
+		}
+		got = append(got, p[12:n]...)
+	}
+	if !bytes.Equal(got, msg) {
+		t.Errorf("Has: %s, expected: %s", got, msg)
+	}
+}
+
+func TestGELFWriter_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w, err := NewGELFWriter(GELFConfig{Network: "tcp", Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w.Write([]byte("{\"a\":1}\n"))
+	w.Write([]byte("{\"b\":2}\n"))
+	r := bufio.NewReader(conn)
+	for _, expected := range []string{"{\"a\":1}\x00", "{\"b\":2}\x00"} {
+		msg, err := r.ReadString(0)
+		if err != nil || msg != expected {
+			t.Errorf("Has: %q (%v), expected: %q", msg, err, expected)
+		}
+	}
+	if !w.Connected() {
+		t.Errorf("Has: disconnected, expected: connected")
+	}
+}
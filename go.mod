@@ -5,5 +5,6 @@ go 1.11
 require (
 	github.com/gofiber/fiber v1.9.6
 	github.com/valyala/bytebufferpool v1.0.0
+	github.com/valyala/fasthttp v1.12.0
 	github.com/valyala/fasttemplate v1.1.0
 )
@@ -0,0 +1,179 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber"
+)
+
+// rpcProtocol is the RPC protocol of a request
+type rpcProtocol int
+
+const (
+	rpcNone rpcProtocol = iota
+	// rpcGRPC is gRPC and gRPC-Web, statuses are trailers
+	rpcGRPC
+	// rpcConnectStream is a Connect streaming call, the status is in the
+	// end-stream message
+	rpcConnectStream
+	// rpcConnectUnary is a Connect unary call, errors are JSON bodies
+	rpcConnectUnary
+)
+
+// grpcCodes are the names of the gRPC status codes by number
+var grpcCodes = []string{
+	"OK", "CANCELLED", "UNKNOWN", "INVALID_ARGUMENT", "DEADLINE_EXCEEDED",
+	"NOT_FOUND", "ALREADY_EXISTS", "PERMISSION_DENIED", "RESOURCE_EXHAUSTED",
+	"FAILED_PRECONDITION", "ABORTED", "OUT_OF_RANGE", "UNIMPLEMENTED",
+	"INTERNAL", "UNAVAILABLE", "DATA_LOSS", "UNAUTHENTICATED",
+}
+
+// rpc returns the protocol of an RPC request, detected by the content type
+// or the Connect-Protocol-Version header of unary Connect calls
+func rpc(c *fiber.Ctx) rpcProtocol {
+	ct := c.Fasthttp.Request.Header.ContentType()
+	switch {
+	case bytes.HasPrefix(ct, []byte("application/grpc")):
+		return rpcGRPC
+	case bytes.HasPrefix(ct, []byte("application/connect+")):
+		return rpcConnectStream
+	case len(c.Fasthttp.Request.Header.Peek("Connect-Protocol-Version")) > 0,
+		c.Method() == fiber.MethodGet && string(c.Fasthttp.QueryArgs().Peek("connect")) == "v1":
+		return rpcConnectUnary
+	}
+	return rpcNone
+}
+
+// rpcMethod splits the path /pkg.Service/Method of an RPC request
+func rpcMethod(c *fiber.Ctx) (service, method string) {
+	if rpc(c) == rpcNone {
+		return "", ""
+	}
+	// Method names are case sensitive, unlike c.Path with fiber's defaults
+	path := strings.TrimPrefix(string(c.Fasthttp.Path()), "/")
+	i := strings.LastIndexByte(path, '/')
+	if i < 0 {
+		return "", ""
+	}
+	return path[:i], path[i+1:]
+}
+
+// grpcStatus returns the name of the gRPC status of an RPC request like
+// NOT_FOUND, "" for other requests or when the status is unknown
+func grpcStatus(c *fiber.Ctx) string {
+	res := &c.Fasthttp.Response
+	switch rpc(c) {
+	case rpcNone:
+		return ""
+	case rpcGRPC:
+		if status := res.Header.Peek("Grpc-Status"); len(status) > 0 {
+			return grpcCode(string(status))
+		}
+		body := res.Body()
+		if bytes.HasPrefix(c.Fasthttp.Request.Header.ContentType(), []byte("application/grpc-web-text")) {
+			body, _ = base64.StdEncoding.DecodeString(string(body))
+		}
+		if trailer, ok := lastFrame(body, 0x80); ok {
+			for _, line := range strings.Split(string(trailer), "\r\n") {
+				if i := strings.IndexByte(line, ':'); i > 0 && strings.EqualFold(line[:i], "grpc-status") {
+					return grpcCode(strings.TrimSpace(line[i+1:]))
+				}
+			}
+		}
+	case rpcConnectStream:
+		if end, ok := lastFrame(res.Body(), 0x02); ok {
+			var msg struct {
+				Error *struct {
+					Code string `json:"code"`
+				} `json:"error"`
+			}
+			if json.Unmarshal(end, &msg) == nil {
+				if msg.Error == nil {
+					return "OK"
+				}
+				return connectCode(msg.Error.Code)
+			}
+		}
+	case rpcConnectUnary:
+		if res.StatusCode() == fiber.StatusOK {
+			return "OK"
+		}
+		var msg struct {
+			Code string `json:"code"`
+		}
+		if json.Unmarshal(res.Body(), &msg) == nil && msg.Code != "" {
+			return connectCode(msg.Code)
+		}
+	}
+	if res.StatusCode() == fiber.StatusOK {
+		return ""
+	}
+	return httpCode(res.StatusCode())
+}
+
+// lastFrame returns the data of the last length-prefixed message in body
+// if its flags have the bit flag set, as for gRPC-Web trailers and Connect
+// end-stream messages
+func lastFrame(body []byte, flag byte) ([]byte, bool) {
+	var data []byte
+	var flags byte
+	for len(body) >= 5 {
+		n := binary.BigEndian.Uint32(body[1:5])
+		if uint64(n) > uint64(len(body)-5) {
+			return nil, false
+		}
+		flags, data = body[0], body[5:5+n]
+		body = body[5+n:]
+	}
+	// Compressed end messages are not read
+	return data, flags&flag != 0 && flags&0x01 == 0
+}
+
+// grpcCode returns the name of the numeric gRPC status code
+func grpcCode(code string) string {
+	if n, err := strconv.Atoi(code); err == nil && n >= 0 && n < len(grpcCodes) {
+		return grpcCodes[n]
+	}
+	return code
+}
+
+// connectCode returns the gRPC name of a Connect code like not_found
+func connectCode(code string) string {
+	if code == "canceled" {
+		return "CANCELLED"
+	}
+	return strings.ToUpper(code)
+}
+
+// httpCode maps the HTTP status of a failed RPC without a status of its
+// own to a gRPC status, as the gRPC and Connect specifications do
+func httpCode(status int) string {
+	switch status {
+	case fiber.StatusBadRequest:
+		return "INTERNAL"
+	case fiber.StatusUnauthorized:
+		return "UNAUTHENTICATED"
+	case fiber.StatusForbidden:
+		return "PERMISSION_DENIED"
+	case fiber.StatusNotFound:
+		return "UNIMPLEMENTED"
+	case fiber.StatusTooManyRequests, fiber.StatusBadGateway, fiber.StatusServiceUnavailable, fiber.StatusGatewayTimeout:
+		return "UNAVAILABLE"
+	}
+	return "UNKNOWN"
+}
+
+// grpcServerError reports whether the gRPC status of the request is a
+// server side failure, the counterpart of a 5xx status
+func grpcServerError(c *fiber.Ctx) bool {
+	switch grpcStatus(c) {
+	case "UNKNOWN", "DEADLINE_EXCEEDED", "UNIMPLEMENTED", "INTERNAL", "UNAVAILABLE", "DATA_LOSS":
+		return true
+	}
+	return false
+}
@@ -0,0 +1,94 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/base64"
+	"encoding/binary"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+// frame returns a length-prefixed message with flags
+func frame(flags byte, data string) []byte {
+	b := make([]byte, 5, 5+len(data))
+	b[0] = flags
+	binary.BigEndian.PutUint32(b[1:], uint32(len(data)))
+	return append(b, data...)
+}
+
+func TestLogger_gRPC(t *testing.T) {
+	out := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{Format: "${method} ${grpcService} ${grpcMethod} ${grpcStatus} ${priority}\n", Output: out}))
+	app.Post("/*", func(ctx *fiber.Ctx) {
+		switch _, method := rpcMethod(ctx); method {
+		case "Get":
+			ctx.Set("Grpc-Status", "5")
+		case "Watch":
+			ctx.SendBytes(append(frame(0, "msg"), frame(0x80, "grpc-status: 14\r\ngrpc-message: down\r\n")...))
+		case "WatchText":
+			ctx.SendString(base64.StdEncoding.EncodeToString(frame(0x80, "grpc-status:0\r\n")))
+		case "List":
+			ctx.SendBytes(append(frame(0, "{}"), frame(0x02, `{"error":{"code":"canceled"}}`)...))
+		case "Count":
+			ctx.SendBytes(frame(0x02, `{}`))
+		case "Delete":
+			ctx.Status(fiber.StatusForbidden).SendString(`{"code":"permission_denied","message":"no"}`)
+		case "Update":
+			ctx.SendStatus(fiber.StatusServiceUnavailable)
+		}
+	})
+	app.Get("/acme.users.v1.UserService/Get", func(ctx *fiber.Ctx) {})
+	app.Post("/form", func(ctx *fiber.Ctx) {})
+	for _, r := range []struct{ method, path, contentType, header string }{
+		{http.MethodPost, "/acme.users.v1.UserService/Get", "application/grpc-web+proto", ""},
+		{http.MethodPost, "/acme.users.v1.UserService/Watch", "application/grpc-web", ""},
+		{http.MethodPost, "/acme.users.v1.UserService/WatchText", "application/grpc-web-text", ""},
+		{http.MethodPost, "/acme.users.v1.UserService/List", "application/connect+json", ""},
+		{http.MethodPost, "/acme.users.v1.UserService/Count", "application/connect+proto", ""},
+		{http.MethodPost, "/acme.users.v1.UserService/Delete", "application/json", "1"},
+		{http.MethodPost, "/acme.users.v1.UserService/Update", "application/proto", "1"},
+		{http.MethodGet, "/acme.users.v1.UserService/Get?connect=v1&encoding=json", "", ""},
+		{http.MethodPost, "/form", "application/json", ""},
+	} {
+		req := httptest.NewRequest(r.method, r.path, bytes.NewReader(nil))
+		req.Header.Set("Content-Length", "0")
+		if r.contentType != "" {
+			req.Header.Set("Content-Type", r.contentType)
+		}
+		if r.header != "" {
+			req.Header.Set("Connect-Protocol-Version", r.header)
+		}
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+	expected := "POST acme.users.v1.UserService Get NOT_FOUND ok\n" +
+		"POST acme.users.v1.UserService Watch UNAVAILABLE error\n" +
+		"POST acme.users.v1.UserService WatchText OK ok\n" +
+		"POST acme.users.v1.UserService List CANCELLED ok\n" +
+		"POST acme.users.v1.UserService Count OK ok\n" +
+		"POST acme.users.v1.UserService Delete PERMISSION_DENIED ok\n" +
+		"POST acme.users.v1.UserService Update UNAVAILABLE error\n" +
+		"GET acme.users.v1.UserService Get OK ok\n" +
+		"POST    ok\n"
+	if out.String() != expected {
+		t.Errorf("Has: %q, expected: %q", out.String(), expected)
+	}
+}
+
+func TestLastFrame(t *testing.T) {
+	if _, ok := lastFrame(frame(0, "msg"), 0x80); ok {
+		t.Errorf("Has: true, expected: false for a message")
+	}
+	if _, ok := lastFrame(frame(0x80, "grpc-status: 0")[:8], 0x80); ok {
+		t.Errorf("Has: true, expected: false for a truncated frame")
+	}
+	if _, ok := lastFrame(frame(0x03, "gzipped"), 0x02); ok {
+		t.Errorf("Has: true, expected: false for a compressed frame")
+	}
+}
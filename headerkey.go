@@ -0,0 +1,35 @@
+package logger
+
+import "strings"
+
+// headerKeys maps the header:<key> tags of the formats, also when nested
+// like clf:header:<key>, to their key in the canonical form of fasthttp,
+// resolved once when the logger is created
+type headerKeys map[string][]byte
+
+func newHeaderKeys(tags []string) headerKeys {
+	keys := make(headerKeys)
+	for _, tag := range tags {
+		if i := strings.Index(tag, strHeader); i >= 0 {
+			keys[tag[i:]] = canonicalHeaderKey(tag[i+len(strHeader):])
+		}
+	}
+	return keys
+}
+
+// canonicalHeaderKey returns key with the first letter and letters after a
+// dash upper case and the others lower case, e.g. X-Request-Id
+func canonicalHeaderKey(key string) []byte {
+	b := []byte(key)
+	upper := true
+	for i, c := range b {
+		switch {
+		case upper && c >= 'a' && c <= 'z':
+			b[i] = c - 'a' + 'A'
+		case !upper && c >= 'A' && c <= 'Z':
+			b[i] = c - 'A' + 'a'
+		}
+		upper = c == '-'
+	}
+	return b
+}
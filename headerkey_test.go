@@ -0,0 +1,23 @@
+package logger
+
+import "testing"
+
+func TestCanonicalHeaderKey(t *testing.T) {
+	for key, expected := range map[string]string{
+		"x-request-id": "X-Request-Id",
+		"X-TENANT":     "X-Tenant",
+		"traceparent":  "Traceparent",
+		"-a":           "-A",
+	} {
+		if has := string(canonicalHeaderKey(key)); has != expected {
+			t.Errorf("Has: %s, expected: %s", has, expected)
+		}
+	}
+}
+
+func TestNewHeaderKeys(t *testing.T) {
+	keys := newHeaderKeys([]string{"status", "header:x-tenant", "clf:header:user-agent"})
+	if len(keys) != 2 || string(keys["header:x-tenant"]) != "X-Tenant" || string(keys["header:user-agent"]) != "User-Agent" {
+		t.Errorf("Has: %q, expected: X-Tenant and User-Agent", keys)
+	}
+}
@@ -0,0 +1,233 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// output wraps a writer of the pipeline and keeps track of its health
+type output struct {
+	name string
+	// swap guards w, held for reading by writes and for writing by Swap
+	swap        sync.RWMutex
+	w           io.Writer
+	mu          sync.Mutex
+	writes      int64
+	errors      int64
+	bytes       int64
+	lastErr     error
+	lastErrTime time.Time
+	failing     bool
+	strip       bool
+	// spooled is the backlog of a SpoolWriter at the last health report,
+	// to tell whether it grows
+	spooled int64
+	// header is written before the first entry of w, headed once it was or
+	// when w writes it itself
+	header []byte
+	hmu    sync.Mutex
+	headed bool
+}
+
+func newOutput(name string, w io.Writer) *output {
+	return &output{name: name, w: w}
+}
+
+// Write writes p to the underlying writer and records the result. With
+// strip set ANSI escape sequences are removed first
+func (o *output) Write(p []byte) (int, error) {
+	return o.writeEntry(nil, p)
+}
+
+// writeEntry writes p like Write, passing e along to an EntryWriter
+func (o *output) writeEntry(e *Entry, p []byte) (int, error) {
+	var n int
+	var err error
+	o.swap.RLock()
+	if o.header != nil {
+		err = o.writeHeader()
+	}
+	if err == nil && o.strip {
+		_, err = o.write(e, stripANSI(p))
+		if err == nil {
+			n = len(p)
+		}
+	} else if err == nil {
+		n, err = o.write(e, p)
+	}
+	o.swap.RUnlock()
+	o.mu.Lock()
+	o.writes++
+	o.bytes += int64(n)
+	o.failing = err != nil
+	if err != nil {
+		o.errors++
+		o.lastErr = err
+		o.lastErrTime = time.Now()
+	}
+	o.mu.Unlock()
+	return n, err
+}
+
+// setHeader sets the header of enc, written before the first entry of the
+// output
+func (o *output) setHeader(enc Encoder) {
+	o.header = encodeHeader(enc)
+	o.resetHeader()
+}
+
+// resetHeader has the header written again before the next entry, by the
+// writer itself when it starts new files like a FileWriter
+func (o *output) resetHeader() {
+	o.headed = false
+	if hw, ok := o.w.(headerWriter); ok && o.header != nil {
+		hw.SetHeader(o.header)
+		o.headed = true
+	}
+}
+
+// writeHeader writes the header once to the writer, again after a failed
+// attempt
+func (o *output) writeHeader() error {
+	o.hmu.Lock()
+	defer o.hmu.Unlock()
+	if o.headed {
+		return nil
+	}
+	_, err := o.w.Write(o.header)
+	o.headed = err == nil
+	return err
+}
+
+func (o *output) write(e *Entry, p []byte) (int, error) {
+	if ew, ok := o.w.(EntryWriter); ok && e != nil {
+		return ew.WriteEntry(e, p)
+	}
+	return o.w.Write(p)
+}
+
+// health reports the state of the output, writers that keep a connection
+// can report it with a Connected() bool method
+func (o *output) health() OutputHealth {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	h := OutputHealth{
+		Name:      o.name,
+		Connected: true,
+		Writes:    o.writes,
+		Errors:    o.errors,
+		Bytes:     o.bytes,
+		Failing:   o.failing,
+	}
+	o.swap.RLock()
+	if c, ok := o.w.(interface{ Connected() bool }); ok {
+		h.Connected = c.Connected()
+	}
+	if s, ok := o.w.(spooler); ok {
+		h.Spooled, h.SpooledEntries = s.Spooled(), s.SpooledEntries()
+		h.SpoolGrowing = h.Spooled > o.spooled
+		o.spooled = h.Spooled
+	}
+	o.swap.RUnlock()
+	if o.lastErr != nil {
+		h.LastError = o.lastErr.Error()
+		h.LastErrorTime = o.lastErrTime
+	}
+	return h
+}
+
+// spooler is an output keeping entries on disk while its writer fails,
+// like a SpoolWriter
+type spooler interface {
+	Spooled() int64
+	SpooledEntries() int
+}
+
+// queueNearFull is the fill ratio of the Async queue, in percent, from
+// which logging counts as degraded
+const queueNearFull = 90
+
+// Health describes the state of the logging pipeline
+type Health struct {
+	// Status is "ok" or "degraded" when an output is failing, disconnected
+	// or spooling more, or the Async queue is nearly full
+	Status string `json:"status"`
+	// QueueDepth is the number of entries waiting in the Async queue
+	QueueDepth int `json:"queueDepth"`
+	// QueueSize is the capacity of the Async queue, 0 without Async
+	QueueSize int            `json:"queueSize"`
+	Outputs   []OutputHealth `json:"outputs"`
+}
+
+// OutputHealth describes the state of a single output
+type OutputHealth struct {
+	Name          string    `json:"name"`
+	Connected     bool      `json:"connected"`
+	Failing       bool      `json:"failing"`
+	Writes        int64     `json:"writes"`
+	Errors        int64     `json:"errors"`
+	Bytes         int64     `json:"bytes"`
+	LastError     string    `json:"lastError,omitempty"`
+	LastErrorTime time.Time `json:"lastErrorTime,omitempty"`
+	// Spooled and SpooledEntries are the bytes and entries a SpoolWriter
+	// holds to replay, SpoolGrowing is set while they grew since the last
+	// report
+	Spooled        int64 `json:"spooled,omitempty"`
+	SpooledEntries int   `json:"spooledEntries,omitempty"`
+	SpoolGrowing   bool  `json:"spoolGrowing,omitempty"`
+}
+
+// Health reports the state of the Async queue and the outputs of the logger
+func (l *Logger) Health() Health {
+	h := Health{Status: "ok"}
+	if l.async != nil {
+		h.QueueDepth, h.QueueSize = l.async.queue.len(), l.cfg.AsyncQueueSize
+		if h.QueueDepth*100 >= h.QueueSize*queueNearFull {
+			h.Status = "degraded"
+		}
+	}
+	for _, o := range l.outputs() {
+		oh := o.health()
+		if oh.Failing || !oh.Connected || oh.SpoolGrowing {
+			h.Status = "degraded"
+		}
+		h.Outputs = append(h.Outputs, oh)
+	}
+	return h
+}
+
+// HealthHandler is a handler writing Health as JSON. It responds with
+// 503 Service Unavailable while logging is degraded, so orchestration can
+// alert on it even if requests still succeed
+func (l *Logger) HealthHandler(c *fiber.Ctx) {
+	h := l.Health()
+	body, err := json.Marshal(h)
+	if err != nil {
+		c.Status(fiber.StatusInternalServerError).SendString(err.Error())
+		return
+	}
+	if h.Status != "ok" {
+		c.Status(fiber.StatusServiceUnavailable)
+	}
+	c.Set(fiber.HeaderContentType, fiber.MIMEApplicationJSON)
+	c.SendBytes(body)
+}
+
+// outputs returns the distinct outputs of the logger
+func (l *Logger) outputs() []*output {
+	outputs := []*output{l.out}
+	if l.rollupOut != l.out {
+		outputs = append(outputs, l.rollupOut)
+	}
+	for _, s := range l.sinks {
+		outputs = append(outputs, s.out)
+	}
+	for _, cp := range l.captures {
+		outputs = append(outputs, cp.out)
+	}
+	return outputs
+}
@@ -0,0 +1,102 @@
+package logger
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) {
+	return 0, errors.New("disk full")
+}
+
+func TestLogger_HealthHandler(t *testing.T) {
+	l := NewLogger(Config{
+		Format:      "${path}\n",
+		Output:      failingWriter{},
+		Diagnostics: ioutil.Discard,
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/health", l.HealthHandler)
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/health", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/health", nil), 1000)
+	if err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable {
+		t.Errorf("Has: %d, expected: %d", resp.StatusCode, fiber.StatusServiceUnavailable)
+	}
+	body, _ := ioutil.ReadAll(resp.Body)
+	if !strings.Contains(string(body), `"status":"degraded"`) || !strings.Contains(string(body), `"lastError":"disk full"`) {
+		t.Errorf("Has: %s, expected: degraded status with last error", body)
+	}
+}
+
+func TestLogger_HealthHandlerBacklog(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	spool, err := NewSpoolWriter(&downWriter{down: true}, SpoolConfig{Path: filepath.Join(dir, "spool"), RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer spool.Close()
+	out := &gatedWriter{open: make(chan struct{})}
+	l := NewLogger(Config{
+		Format:         "${path}\n",
+		Output:         out,
+		Async:          true,
+		AsyncQueueSize: 2,
+		OverflowPolicy: OverflowDropNewest,
+		Sinks:          []SinkConfig{{Name: "spool", Output: spool}},
+		Diagnostics:    ioutil.Discard,
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/health", l.HealthHandler)
+	app.Get("/*", func(ctx *fiber.Ctx) {})
+	for i := 0; i < 3; i++ {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+			t.Fatalf("Has: %+v, expected: nil", err)
+		}
+		// The writer holds the first entry, the queue the next two
+		for i == 0 && l.async.queue.len() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	resp, err := app.Test(httptest.NewRequest(http.MethodGet, "/health", nil), 1000)
+	if err != nil {
+		t.Fatalf("Has: %+v, expected: nil", err)
+	}
+	close(out.open)
+	l.Close()
+	var h Health
+	if err := json.NewDecoder(resp.Body).Decode(&h); err != nil {
+		t.Fatalf("Has: %v, expected: the health as JSON", err)
+	}
+	if resp.StatusCode != fiber.StatusServiceUnavailable || h.Status != "degraded" || h.QueueDepth != 2 || h.QueueSize != 2 {
+		t.Errorf("Has: %d %+v, expected: degraded with a full queue", resp.StatusCode, h)
+	}
+	for _, o := range h.Outputs {
+		if o.Name == "spool" && (o.SpooledEntries != 3 || o.Spooled == 0 || !o.SpoolGrowing) {
+			t.Errorf("Has: %+v, expected: 3 entries spooled and growing", o)
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// humanBytes formats n like 512 B, 1.2 KB or 3.4 MB
+func humanBytes(n int) string {
+	const unit = 1024
+	if n < unit {
+		return strconv.Itoa(n) + " B"
+	}
+	div, exp := unit, 0
+	for m := n / unit; m >= unit && exp < 3; m /= unit {
+		div *= unit
+		exp++
+	}
+	return strconv.FormatFloat(float64(n)/float64(div), 'f', 1, 64) + " " + "KMGT"[exp:exp+1] + "B"
+}
+
+// humanDuration rounds d to about three significant digits like 1.23ms
+func humanDuration(d time.Duration) string {
+	switch {
+	case d >= time.Second:
+		d = d.Round(10 * time.Millisecond)
+	case d >= time.Millisecond:
+		d = d.Round(10 * time.Microsecond)
+	case d >= time.Microsecond:
+		d = d.Round(10 * time.Nanosecond)
+	}
+	return d.String()
+}
+
+// padLeft right aligns s in a column of width
+func padLeft(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return strings.Repeat(" ", width-n) + s
+}
+
+// padRight left aligns s in a column of width
+func padRight(s string, width int) string {
+	n := utf8.RuneCountInString(s)
+	if n >= width {
+		return s
+	}
+	return s + strings.Repeat(" ", width-n)
+}
+
+// column fits what was written to buf since from into a column of width,
+// negative widths align to the right. Longer values are truncated with …
+func column(buf *bytebufferpool.ByteBuffer, from, width int) (int, error) {
+	s := string(buf.B[from:])
+	buf.B = buf.B[:from]
+	right := width < 0
+	if right {
+		width = -width
+	}
+	if utf8.RuneCountInString(s) > width {
+		if width == 0 {
+			return 0, nil
+		}
+		s = string([]rune(s)[:width-1]) + "…"
+	}
+	if right {
+		return buf.WriteString(padLeft(s, width))
+	}
+	return buf.WriteString(padRight(s, width))
+}
@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"testing"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+func TestHumanBytes(t *testing.T) {
+	for n, expected := range map[int]string{
+		0:           "0 B",
+		512:         "512 B",
+		1229:        "1.2 KB",
+		3565158:     "3.4 MB",
+		10737418240: "10.0 GB",
+	} {
+		if s := humanBytes(n); s != expected {
+			t.Errorf("Has: %s, expected: %s", s, expected)
+		}
+	}
+}
+
+func TestHumanDuration(t *testing.T) {
+	for d, expected := range map[time.Duration]string{
+		1234567 * time.Nanosecond: "1.23ms",
+		2345678901:                "2.35s",
+		1500:                      "1.5µs",
+		999:                       "999ns",
+	} {
+		if s := humanDuration(d); s != expected {
+			t.Errorf("Has: %s, expected: %s", s, expected)
+		}
+	}
+}
+
+func TestColumn(t *testing.T) {
+	buf := &bytebufferpool.ByteBuffer{}
+	for _, c := range []struct {
+		value    string
+		width    int
+		expected string
+	}{
+		{"GET", 6, "GET   "},
+		{"200", -5, "  200"},
+		{"/api/users/ünicode", 8, "/api/us…"},
+	} {
+		buf.Reset()
+		buf.WriteString("> ")
+		buf.WriteString(c.value)
+		column(buf, 2, c.width)
+		if buf.String() != "> "+c.expected {
+			t.Errorf("Has: %q, expected: %q", buf.String(), "> "+c.expected)
+		}
+	}
+}
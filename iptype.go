@@ -0,0 +1,65 @@
+package logger
+
+import (
+	"net"
+)
+
+// ipClass is a named set of networks
+type ipClass struct {
+	name string
+	nets []*net.IPNet
+}
+
+// ipClasses are checked in order, the first matching class wins
+var ipClasses = []ipClass{
+	{"loopback", mustCIDRs("127.0.0.0/8", "::1/128")},
+	{"private", mustCIDRs("10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "fc00::/7")},
+	{"link-local", mustCIDRs("169.254.0.0/16", "fe80::/10")},
+	{"cgnat", mustCIDRs("100.64.0.0/10")},
+	{"cloud", mustCIDRs(
+		// Google Cloud load balancer health checks
+		"35.191.0.0/16", "130.211.0.0/22",
+		// Azure platform (health probes, DNS, metadata)
+		"168.63.129.16/32",
+		// AWS Route 53 health checkers us-east-1
+		"107.23.255.0/26", "54.243.31.192/26",
+	)},
+}
+
+func mustCIDRs(cidrs ...string) []*net.IPNet {
+	nets := make([]*net.IPNet, len(cidrs))
+	for i, cidr := range cidrs {
+		_, n, err := net.ParseCIDR(cidr)
+		if err != nil {
+			panic("logger: invalid CIDR " + cidr)
+		}
+		nets[i] = n
+	}
+	return nets
+}
+
+// ipType classifies ip as loopback, private, link-local, cgnat, cloud or public,
+// cloud additionally contains the extra networks
+func ipType(ip net.IP, cloud []*net.IPNet) string {
+	if ip == nil {
+		return "unknown"
+	}
+	for _, class := range ipClasses {
+		if containsIP(class.nets, ip) {
+			return class.name
+		}
+	}
+	if containsIP(cloud, ip) {
+		return "cloud"
+	}
+	return "public"
+}
+
+func containsIP(nets []*net.IPNet, ip net.IP) bool {
+	for _, n := range nets {
+		if n.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
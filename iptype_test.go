@@ -0,0 +1,23 @@
+package logger
+
+import (
+	"net"
+	"testing"
+)
+
+func TestIPType(t *testing.T) {
+	cloud := mustCIDRs("203.0.113.0/24")
+	for ip, expected := range map[string]string{
+		"127.0.0.1":    "loopback",
+		"::1":          "loopback",
+		"10.1.2.3":     "private",
+		"100.64.0.1":   "cgnat",
+		"35.191.1.1":   "cloud",
+		"203.0.113.10": "cloud",
+		"8.8.8.8":      "public",
+	} {
+		if typ := ipType(net.ParseIP(ip), cloud); typ != expected {
+			t.Errorf("%s Has: %s, expected: %s", ip, typ, expected)
+		}
+	}
+}
@@ -0,0 +1,196 @@
+package logger
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// JournaldConfig configures a JournaldWriter
+type JournaldConfig struct {
+	// Socket is the native protocol socket of journald
+	// Optional. Default: "/run/systemd/journal/socket"
+	Socket string
+	// Identifier is the SYSLOG_IDENTIFIER of the entries
+	// Optional. Default: the name of the executable
+	Identifier string
+	// Severity maps a status to the PRIORITY of an entry
+	// Optional. Default: 3 (error) for 5xx, 4 (warning) for 4xx, 6 (info)
+	Severity func(status int) int
+	// Fields map journal field names to a value of the entry: "route",
+	// "method", "status", "priority" or a tag, e.g. {"HTTP_UA": "ua"}.
+	// Names are uppercase letters, digits and underscores
+	// Optional. Default: nil
+	Fields map[string]string
+}
+
+// journalField is a custom field and the source of its value
+type journalField struct {
+	name   string
+	source string
+}
+
+// JournaldWriter sends entries to the systemd journal with the native
+// protocol, keeping their metadata as fields: MESSAGE is the line,
+// PRIORITY follows the status and HTTP_METHOD, HTTP_PATH, HTTP_ROUTE,
+// HTTP_STATUS, HTTP_LATENCY_MS, HTTP_REMOTE_ADDR and REQUEST_ID are taken
+// from the entry. Lines written by other means only have MESSAGE, PRIORITY
+// 6 and the identifier
+type JournaldWriter struct {
+	cfg    JournaldConfig
+	fields []journalField
+	mu     sync.Mutex
+	conn   *net.UnixConn
+}
+
+// NewJournaldWriter connects to the journal socket of cfg
+func NewJournaldWriter(cfg JournaldConfig) (*JournaldWriter, error) {
+	if cfg.Socket == "" {
+		cfg.Socket = "/run/systemd/journal/socket"
+	}
+	if cfg.Identifier == "" {
+		cfg.Identifier = filepath.Base(os.Args[0])
+	}
+	if cfg.Severity == nil {
+		cfg.Severity = statusSeverity
+	}
+	w := &JournaldWriter{cfg: cfg}
+	for name, source := range cfg.Fields {
+		if !validJournalField(name) {
+			return nil, fmt.Errorf("logger: invalid journal field %q", name)
+		}
+		w.fields = append(w.fields, journalField{name, source})
+	}
+	sort.Slice(w.fields, func(i, j int) bool { return w.fields[i].name < w.fields[j].name })
+	conn, err := net.DialUnix("unixgram", nil, &net.UnixAddr{Name: cfg.Socket, Net: "unixgram"})
+	if err != nil {
+		return nil, err
+	}
+	w.conn = conn
+	return w, nil
+}
+
+// validJournalField reports whether name matches [A-Z][A-Z0-9_]* of at most
+// 64 characters, fields starting with an underscore are trusted ones set
+// by journald
+func validJournalField(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'A' && c <= 'Z' || i > 0 && (c >= '0' && c <= '9' || c == '_')) {
+			return false
+		}
+	}
+	return name != "" && len(name) <= 64
+}
+
+// Tags returns the tags of Fields, so they are in the entry
+func (w *JournaldWriter) Tags() []string {
+	var sources []string
+	for _, f := range w.fields {
+		sources = append(sources, f.source)
+	}
+	return sourceTags(sources...)
+}
+
+// Write sends p as the MESSAGE of an entry
+func (w *JournaldWriter) Write(p []byte) (int, error) {
+	return w.send(nil, p)
+}
+
+// WriteEntry sends p as the MESSAGE of an entry with the fields of e
+func (w *JournaldWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return w.send(e, p)
+}
+
+func (w *JournaldWriter) send(e *Entry, p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	appendJournalField(buf, "MESSAGE", string(p))
+	priority := 6
+	if e != nil {
+		priority = w.cfg.Severity(e.Status)
+	}
+	appendJournalField(buf, "PRIORITY", strconv.Itoa(priority))
+	appendJournalField(buf, "SYSLOG_IDENTIFIER", w.cfg.Identifier)
+	if e != nil {
+		appendJournalField(buf, "HTTP_METHOD", e.Method)
+		appendJournalField(buf, "HTTP_PATH", e.Path)
+		appendJournalField(buf, "HTTP_ROUTE", e.Route)
+		appendJournalField(buf, "HTTP_STATUS", strconv.Itoa(e.Status))
+		appendJournalField(buf, "HTTP_LATENCY_MS", strconv.FormatFloat(milliseconds(e.Latency), 'f', -1, 64))
+		appendJournalField(buf, "HTTP_REMOTE_ADDR", e.IP)
+		if e.ID != "" {
+			appendJournalField(buf, "REQUEST_ID", e.ID)
+		}
+		for _, f := range w.fields {
+			if value := entryValue(e, f.source); value != "" {
+				appendJournalField(buf, f.name, value)
+			}
+		}
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return 0, errors.New("logger: journald writer is closed")
+	}
+	if _, err := w.conn.Write(buf.B); err != nil {
+		// Entries too large for a datagram are passed as a file
+		if !isMsgSize(err) {
+			return 0, err
+		}
+		if err := sendJournalFile(w.conn, buf.B); err != nil {
+			return 0, err
+		}
+	}
+	return n, nil
+}
+
+// appendJournalField writes a field of the native protocol, values with a
+// line break in the binary form with their length
+func appendJournalField(buf *bytebufferpool.ByteBuffer, name, value string) {
+	buf.WriteString(name)
+	if strings.IndexByte(value, '\n') < 0 {
+		buf.WriteString("=")
+		buf.WriteString(value)
+		buf.WriteString("\n")
+		return
+	}
+	var size [8]byte
+	binary.LittleEndian.PutUint64(size[:], uint64(len(value)))
+	buf.WriteString("\n")
+	buf.Write(size[:])
+	buf.WriteString(value)
+	buf.WriteString("\n")
+}
+
+// Connected reports whether the writer holds the socket, for Health
+func (w *JournaldWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn != nil
+}
+
+// Close closes the socket
+func (w *JournaldWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net"
+	"os"
+	"syscall"
+)
+
+// isMsgSize reports whether err is a datagram exceeding the socket buffer
+func isMsgSize(err error) bool {
+	if op, ok := err.(*net.OpError); ok {
+		if sys, ok := op.Err.(*os.SyscallError); ok {
+			return sys.Err == syscall.EMSGSIZE || sys.Err == syscall.ENOBUFS
+		}
+	}
+	return false
+}
+
+// sendJournalFile writes b to an unlinked file in /dev/shm and passes its
+// descriptor to journald, which reads the entry from it
+func sendJournalFile(conn *net.UnixConn, b []byte) error {
+	f, err := ioutil.TempFile("/dev/shm", "journal")
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if err := os.Remove(f.Name()); err != nil {
+		return err
+	}
+	if _, err := f.Write(b); err != nil {
+		return err
+	}
+	// The socket is connected, which WriteMsgUnix refuses
+	raw, err := conn.SyscallConn()
+	if err != nil {
+		return err
+	}
+	rights := syscall.UnixRights(int(f.Fd()))
+	werr := raw.Write(func(fd uintptr) bool {
+		err = syscall.Sendmsg(int(fd), nil, rights, nil, 0)
+		return err != syscall.EAGAIN
+	})
+	if werr != nil {
+		return werr
+	}
+	return err
+}
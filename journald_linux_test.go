@@ -0,0 +1,41 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"strings"
+	"syscall"
+	"testing"
+)
+
+func TestJournaldWriter_large(t *testing.T) {
+	server, socket, cleanup := journalServer(t)
+	defer cleanup()
+	jw, err := NewJournaldWriter(JournaldConfig{Socket: socket})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer jw.Close()
+
+	body := strings.Repeat("x", 1<<20)
+	if _, err := jw.Write([]byte(body)); err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	oob := make([]byte, 64)
+	_, oobn, _, _, err := server.ReadMsgUnix(make([]byte, 16), oob)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs, _ := syscall.ParseSocketControlMessage(oob[:oobn])
+	if len(msgs) != 1 {
+		t.Fatalf("Has: %d control messages, expected: a file", len(msgs))
+	}
+	fds, _ := syscall.ParseUnixRights(&msgs[0])
+	f := os.NewFile(uintptr(fds[0]), "journal")
+	defer f.Close()
+	f.Seek(0, 0)
+	b, _ := ioutil.ReadAll(f)
+	if fields := decodeJournal(b); fields["MESSAGE"] != body {
+		t.Errorf("Has: %d bytes, expected: the message of %d bytes", len(fields["MESSAGE"]), len(body))
+	}
+}
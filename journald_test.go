@@ -0,0 +1,90 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// journalServer listens on a journal socket in a temporary directory
+func journalServer(t *testing.T) (*net.UnixConn, string, func()) {
+	dir, err := ioutil.TempDir("", "journal")
+	if err != nil {
+		t.Fatal(err)
+	}
+	socket := filepath.Join(dir, "socket")
+	conn, err := net.ListenUnixgram("unixgram", &net.UnixAddr{Name: socket, Net: "unixgram"})
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Skip(err)
+	}
+	return conn, socket, func() {
+		conn.Close()
+		os.RemoveAll(dir)
+	}
+}
+
+// decodeJournal parses the fields of a native protocol entry
+func decodeJournal(b []byte) map[string]string {
+	fields := make(map[string]string)
+	for len(b) > 0 {
+		i := bytes.IndexAny(b, "=\n")
+		name := string(b[:i])
+		if b[i] == '=' {
+			end := bytes.IndexByte(b[i:], '\n') + i
+			fields[name] = string(b[i+1 : end])
+			b = b[end+1:]
+			continue
+		}
+		size := int(binary.LittleEndian.Uint64(b[i+1 : i+9]))
+		fields[name] = string(b[i+9 : i+9+size])
+		b = b[i+10+size:]
+	}
+	return fields
+}
+
+func TestJournaldWriter(t *testing.T) {
+	server, socket, cleanup := journalServer(t)
+	defer cleanup()
+	jw, err := NewJournaldWriter(JournaldConfig{Socket: socket, Identifier: "shop", Fields: map[string]string{"HTTP_UA": "ua"}})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer jw.Close()
+
+	e := &Entry{Method: "GET", Path: "/cart", Route: "/cart", Status: 503, Latency: 1500 * time.Microsecond,
+		IP: "10.0.0.1", Fields: []Field{{strUa, "curl/7.68"}}}
+	if _, err := jw.WriteEntry(e, []byte("GET /cart 503\nupstream down\n")); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	buf := make([]byte, 4096)
+	n, _ := server.Read(buf)
+	fields := decodeJournal(buf[:n])
+	expected := map[string]string{
+		"MESSAGE": "GET /cart 503\nupstream down", "PRIORITY": "3", "SYSLOG_IDENTIFIER": "shop",
+		"HTTP_METHOD": "GET", "HTTP_PATH": "/cart", "HTTP_STATUS": "503", "HTTP_LATENCY_MS": "1.5",
+		"HTTP_REMOTE_ADDR": "10.0.0.1", "HTTP_UA": "curl/7.68",
+	}
+	for name, value := range expected {
+		if fields[name] != value {
+			t.Errorf("Has: %s=%q, expected: %q", name, fields[name], value)
+		}
+	}
+
+	jw.Write([]byte("started\n"))
+	n, _ = server.Read(buf)
+	if fields := decodeJournal(buf[:n]); fields["MESSAGE"] != "started" || fields["PRIORITY"] != "6" || fields["HTTP_STATUS"] != "" {
+		t.Errorf("Has: %v, expected: a plain message", fields)
+	}
+}
+
+func TestNewJournaldWriter_invalid(t *testing.T) {
+	if _, err := NewJournaldWriter(JournaldConfig{Fields: map[string]string{"http_ua": "ua"}}); err == nil {
+		t.Errorf("Has: nil, expected: an error for a lowercase field")
+	}
+}
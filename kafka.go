@@ -0,0 +1,186 @@
+package logger
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// errKafkaBacklog is returned for entries dropped while publishing fails
+var errKafkaBacklog = errors.New("logger: kafka backlog full, entry dropped")
+
+// KafkaMessage is an entry published to Kafka
+type KafkaMessage struct {
+	Topic string
+	Key   []byte
+	Value []byte
+	Time  time.Time
+}
+
+// KafkaProducer publishes batches of messages. It is implemented by a small
+// adapter around a Kafka client like sarama, kafka-go or franz-go, which is
+// configured with the brokers, compression and acks
+type KafkaProducer interface {
+	Produce(msgs []KafkaMessage) error
+}
+
+// KafkaConfig configures a KafkaWriter
+type KafkaConfig struct {
+	// Producer publishes the messages
+	// Required
+	Producer KafkaProducer
+	// Topic the entries are published to
+	// Required
+	Topic string
+	// Key is the entry value used as message key, so the entries of a key
+	// share a partition and stay in order: "route", "method", "status",
+	// "priority" or a tag, e.g. "header:X-Tenant"
+	// Optional. Default: "" (no key)
+	Key string
+	// BatchSize is the number of entries published at once
+	// Optional. Default: 100
+	BatchSize int
+	// BatchWait is the longest an entry waits before it is published
+	// Optional. Default: 100 * time.Millisecond
+	BatchWait time.Duration
+	// Backlog is the number of entries held while publishing, further
+	// entries are dropped
+	// Optional. Default: 10 * BatchSize
+	Backlog int
+}
+
+// KafkaWriter publishes each line as a message to a Kafka topic, in batches
+// of BatchSize or every BatchWait. As an EntryWriter the key is taken from
+// the entry, lines written by other means have no key. Failed batches are
+// dropped, retries are left to the producer
+type KafkaWriter struct {
+	cfg    KafkaConfig
+	mu     sync.Mutex
+	msgs   []KafkaMessage
+	failed bool
+	full   chan struct{}
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewKafkaWriter starts publishing with the producer of cfg
+func NewKafkaWriter(cfg KafkaConfig) (*KafkaWriter, error) {
+	if cfg.Producer == nil || cfg.Topic == "" {
+		return nil, errors.New("logger: kafka producer and topic are required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 100
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = 100 * time.Millisecond
+	}
+	if cfg.Backlog <= 0 {
+		cfg.Backlog = 10 * cfg.BatchSize
+	}
+	w := &KafkaWriter{
+		cfg:    cfg,
+		full:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	go w.run()
+	return w, nil
+}
+
+// Tags returns the tag of Key, so it is in the entry
+func (w *KafkaWriter) Tags() []string {
+	return sourceTags(w.cfg.Key)
+}
+
+// Write adds p as a message without key
+func (w *KafkaWriter) Write(p []byte) (int, error) {
+	return w.add(nil, time.Now(), p)
+}
+
+// WriteEntry adds p as a message with the key of e
+func (w *KafkaWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	var key []byte
+	if w.cfg.Key != "" {
+		key = []byte(entryValue(e, w.cfg.Key))
+	}
+	return w.add(key, e.Time, p)
+}
+
+func (w *KafkaWriter) add(key []byte, t time.Time, p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if len(w.msgs) >= w.cfg.Backlog {
+		return 0, errKafkaBacklog
+	}
+	w.msgs = append(w.msgs, KafkaMessage{
+		Topic: w.cfg.Topic,
+		Key:   key,
+		Value: append([]byte(nil), p...),
+		Time:  t,
+	})
+	if len(w.msgs) == w.cfg.BatchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return n, nil
+}
+
+// run publishes the batch every BatchWait or once it is full, until Close
+func (w *KafkaWriter) run() {
+	ticker := time.NewTicker(w.cfg.BatchWait)
+	defer ticker.Stop()
+	defer close(w.closed)
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.full:
+		case <-w.done:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// flush publishes the pending messages in batches of BatchSize
+func (w *KafkaWriter) flush() {
+	w.mu.Lock()
+	msgs := w.msgs
+	w.msgs = nil
+	w.mu.Unlock()
+	for len(msgs) > 0 {
+		batch := msgs
+		if len(batch) > w.cfg.BatchSize {
+			batch = batch[:w.cfg.BatchSize]
+		}
+		msgs = msgs[len(batch):]
+		err := w.cfg.Producer.Produce(batch)
+		w.mu.Lock()
+		w.failed = err != nil
+		w.mu.Unlock()
+	}
+}
+
+// Connected reports whether the last batch was published, for Health
+func (w *KafkaWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.failed
+}
+
+// Close publishes the pending messages and stops the writer, the producer
+// is left open
+func (w *KafkaWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.closed
+	return nil
+}
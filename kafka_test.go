@@ -0,0 +1,85 @@
+package logger
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// testProducer records the batches it is given
+type testProducer struct {
+	mu      sync.Mutex
+	batches [][]KafkaMessage
+	err     error
+}
+
+func (p *testProducer) Produce(msgs []KafkaMessage) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.batches = append(p.batches, msgs)
+	return p.err
+}
+
+func TestKafkaWriter(t *testing.T) {
+	producer := &testProducer{}
+	kw, err := NewKafkaWriter(KafkaConfig{
+		Producer:  producer,
+		Topic:     "access",
+		Key:       "header:X-Tenant",
+		BatchSize: 2,
+		BatchWait: time.Hour,
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	app := fiber.New()
+	app.Use(New(Config{Format: "${method} ${path}\n", Output: kw}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+
+	for _, tenant := range []string{"acme", "globex", "initech"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Tenant", tenant)
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+	kw.Close()
+
+	if len(producer.batches) != 2 || len(producer.batches[0]) != 2 || len(producer.batches[1]) != 1 {
+		t.Fatalf("Has: %v, expected: batches of 2 and 1", producer.batches)
+	}
+	msg := producer.batches[0][1]
+	if msg.Topic != "access" || string(msg.Key) != "globex" || string(msg.Value) != "GET /" || msg.Time.IsZero() {
+		t.Errorf("Has: %+v, expected: the line keyed by tenant", msg)
+	}
+}
+
+func TestKafkaWriter_failed(t *testing.T) {
+	producer := &testProducer{err: errors.New("no brokers")}
+	kw, err := NewKafkaWriter(KafkaConfig{Producer: producer, Topic: "access", BatchWait: time.Hour, Backlog: 1})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	kw.Write([]byte("GET / 200\n"))
+	if _, err := kw.Write([]byte("GET / 200\n")); err != errKafkaBacklog {
+		t.Errorf("Has: %v, expected: %v", err, errKafkaBacklog)
+	}
+	kw.Close()
+	if kw.Connected() {
+		t.Errorf("Has: connected, expected: disconnected after a failed batch")
+	}
+	if msg := producer.batches[0][0]; msg.Key != nil || string(msg.Value) != "GET / 200" {
+		t.Errorf("Has: %+v, expected: the line without key", msg)
+	}
+}
+
+func TestNewKafkaWriter_invalid(t *testing.T) {
+	if _, err := NewKafkaWriter(KafkaConfig{Topic: "access"}); err == nil {
+		t.Errorf("Has: nil, expected: an error without producer")
+	}
+}
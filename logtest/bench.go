@@ -0,0 +1,170 @@
+package logtest
+
+import (
+	"fmt"
+	"io"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/fasthttp"
+)
+
+// CountingWriter counts the writes and bytes passing to W, to measure the
+// throughput of an output in Bench
+type CountingWriter struct {
+	// Name identifies the writer in BenchResult
+	Name string
+	// W receives the writes
+	// Optional. Default: ioutil.Discard
+	W io.Writer
+
+	writes int64
+	bytes  int64
+}
+
+// Write writes p to W and counts it
+func (w *CountingWriter) Write(p []byte) (int, error) {
+	n, err := writer(w.W).Write(p)
+	atomic.AddInt64(&w.writes, 1)
+	atomic.AddInt64(&w.bytes, int64(n))
+	return n, err
+}
+
+// Counts returns the number of writes and bytes written
+func (w *CountingWriter) Counts() (writes, bytes int64) {
+	return atomic.LoadInt64(&w.writes), atomic.LoadInt64(&w.bytes)
+}
+
+// BenchConfig configures Bench
+type BenchConfig struct {
+	// Requests is the number of requests sent
+	// Optional. Default: 10000
+	Requests int
+	// Concurrency is the number of goroutines sending requests
+	// Optional. Default: 1
+	Concurrency int
+	// RPS limits the requests per second, 0 sends them as fast as possible
+	// Optional. Default: 0
+	RPS int
+	// Request prepares the synthetic request and the response the handler
+	// behind the middleware would have written, i counts the requests
+	// Optional. Default: GET / answered with 200 "OK"
+	Request func(ctx *fasthttp.RequestCtx, i int)
+	// Outputs are the writers of the logger whose throughput is reported
+	// Optional. Default: nil
+	Outputs []*CountingWriter
+}
+
+// BenchResult reports the requests sent by Bench, the allocations per
+// request and the throughput of the outputs
+type BenchResult struct {
+	Requests         int
+	Duration         time.Duration
+	RPS              float64
+	AllocsPerRequest float64
+	BytesPerRequest  float64
+	Outputs          []OutputResult
+}
+
+// OutputResult is the throughput of a CountingWriter
+type OutputResult struct {
+	Name           string
+	Writes         int64
+	Bytes          int64
+	BytesPerSecond float64
+}
+
+// String formats the result for a terminal
+func (r BenchResult) String() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%d requests in %v, %.0f req/s, %.1f allocs/req, %.0f B/req\n",
+		r.Requests, r.Duration, r.RPS, r.AllocsPerRequest, r.BytesPerRequest)
+	for _, o := range r.Outputs {
+		fmt.Fprintf(&b, "%s: %d writes, %d bytes, %.0f B/s\n", o.Name, o.Writes, o.Bytes, o.BytesPerSecond)
+	}
+	return b.String()
+}
+
+// Bench drives middleware with synthetic fasthttp contexts, without a
+// server or app, so the cost of the logging pipeline can be measured for
+// capacity planning. The middleware's call to Next is a no-op, the
+// response is prepared by BenchConfig.Request
+func Bench(middleware func(*fiber.Ctx), cfg BenchConfig) BenchResult {
+	if cfg.Requests <= 0 {
+		cfg.Requests = 10000
+	}
+	if cfg.Concurrency <= 0 {
+		cfg.Concurrency = 1
+	}
+	if cfg.Request == nil {
+		cfg.Request = func(ctx *fasthttp.RequestCtx, i int) {
+			ctx.Request.SetRequestURI("/")
+			ctx.Response.SetBodyString("OK")
+		}
+	}
+	var tick <-chan time.Time
+	if cfg.RPS > 0 {
+		ticker := time.NewTicker(time.Second / time.Duration(cfg.RPS))
+		defer ticker.Stop()
+		tick = ticker.C
+	}
+	writes0 := make([]int64, len(cfg.Outputs))
+	bytes0 := make([]int64, len(cfg.Outputs))
+	for i, o := range cfg.Outputs {
+		writes0[i], bytes0[i] = o.Counts()
+	}
+
+	var next int64 = -1
+	var wg sync.WaitGroup
+	var m0, m1 runtime.MemStats
+	runtime.GC()
+	runtime.ReadMemStats(&m0)
+	start := time.Now()
+	for w := 0; w < cfg.Concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fctx := &fasthttp.RequestCtx{}
+			for {
+				i := int(atomic.AddInt64(&next, 1))
+				if i >= cfg.Requests {
+					return
+				}
+				if tick != nil {
+					<-tick
+				}
+				fctx.Request.Reset()
+				fctx.Response.Reset()
+				cfg.Request(fctx, i)
+				c := fiber.AcquireCtx(fctx)
+				middleware(c)
+				fiber.ReleaseCtx(c)
+			}
+		}()
+	}
+	wg.Wait()
+	d := time.Since(start)
+	runtime.ReadMemStats(&m1)
+
+	r := BenchResult{
+		Requests:         cfg.Requests,
+		Duration:         d,
+		RPS:              float64(cfg.Requests) / d.Seconds(),
+		AllocsPerRequest: float64(m1.Mallocs-m0.Mallocs) / float64(cfg.Requests),
+		BytesPerRequest:  float64(m1.TotalAlloc-m0.TotalAlloc) / float64(cfg.Requests),
+	}
+	for i, o := range cfg.Outputs {
+		writes, bytes := o.Counts()
+		r.Outputs = append(r.Outputs, OutputResult{
+			Name:           o.Name,
+			Writes:         writes - writes0[i],
+			Bytes:          bytes - bytes0[i],
+			BytesPerSecond: float64(bytes-bytes0[i]) / d.Seconds(),
+		})
+	}
+	return r
+}
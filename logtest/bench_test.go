@@ -0,0 +1,55 @@
+package logtest_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/logger"
+	"github.com/gofiber/logger/logtest"
+	"github.com/valyala/fasthttp"
+)
+
+func TestBench(t *testing.T) {
+	out := &logtest.CountingWriter{Name: "stdout"}
+	errs := &logtest.CountingWriter{Name: "errors"}
+	l := logger.NewLogger(logger.Config{
+		Format: "${method} ${path} ${status}\n",
+		Output: out,
+		Sinks:  []logger.SinkConfig{{Output: errs, MinPriority: logger.PriorityError}},
+	})
+	r := logtest.Bench(l.Handle, logtest.BenchConfig{
+		Requests:    100,
+		Concurrency: 4,
+		Request: func(ctx *fasthttp.RequestCtx, i int) {
+			ctx.Request.SetRequestURI("/items")
+			if i%10 == 0 {
+				ctx.SetStatusCode(500)
+			}
+		},
+		Outputs: []*logtest.CountingWriter{out, errs},
+	})
+
+	if r.Requests != 100 || r.Duration <= 0 || r.RPS <= 0 {
+		t.Errorf("Has: %+v, expected: 100 requests", r)
+	}
+	if o := r.Outputs[0]; o.Name != "stdout" || o.Writes != 100 || o.Bytes != 100*int64(len("GET /items 200\n")) {
+		t.Errorf("Has: %+v, expected: 100 writes", o)
+	}
+	if o := r.Outputs[1]; o.Writes != 10 {
+		t.Errorf("Has: %+v, expected: 10 writes", o)
+	}
+	if !strings.Contains(r.String(), "100 requests in ") {
+		t.Errorf("Has: %s, expected: summary", r)
+	}
+}
+
+func TestBench_rps(t *testing.T) {
+	r := logtest.Bench(logger.New(logger.Config{Output: &logtest.CountingWriter{}}), logtest.BenchConfig{
+		Requests: 10,
+		RPS:      200,
+	})
+	if r.Duration < 40*time.Millisecond {
+		t.Errorf("Has: %v, expected: about 50ms at 200 req/s", r.Duration)
+	}
+}
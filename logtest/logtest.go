@@ -0,0 +1,105 @@
+// Package logtest provides writers that simulate slow, failing and partial
+// outputs, to verify how a logging configuration behaves when an output
+// misbehaves before an incident does it
+package logtest
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"sync"
+	"time"
+)
+
+// ErrInjected is the default error of a FailingWriter
+var ErrInjected = errors.New("logtest: injected failure")
+
+// SlowWriter delays every write, like an output under back pressure
+type SlowWriter struct {
+	// W receives the writes
+	// Optional. Default: ioutil.Discard
+	W io.Writer
+	// Delay is added to every write
+	Delay time.Duration
+}
+
+// Write sleeps for Delay and writes p to W
+func (s *SlowWriter) Write(p []byte) (int, error) {
+	time.Sleep(s.Delay)
+	return writer(s.W).Write(p)
+}
+
+// FailingWriter fails writes with Err after the first After writes, and
+// recovers after For failures to simulate an outage
+type FailingWriter struct {
+	// W receives the writes that succeed
+	// Optional. Default: ioutil.Discard
+	W io.Writer
+	// Err is returned by failing writes
+	// Optional. Default: ErrInjected
+	Err error
+	// After is the number of writes that succeed before failing
+	// Optional. Default: 0
+	After int
+	// For is the number of writes that fail before recovering
+	// Optional. Default: 0 (fail forever)
+	For int
+
+	mu       sync.Mutex
+	writes   int
+	failures int
+}
+
+// Write writes p to W or fails it with Err
+func (f *FailingWriter) Write(p []byte) (int, error) {
+	f.mu.Lock()
+	f.writes++
+	fail := f.writes > f.After && (f.For == 0 || f.writes <= f.After+f.For)
+	if fail {
+		f.failures++
+	}
+	f.mu.Unlock()
+	if fail {
+		if f.Err != nil {
+			return 0, f.Err
+		}
+		return 0, ErrInjected
+	}
+	return writer(f.W).Write(p)
+}
+
+// Writes returns the number of writes and how many of them failed
+func (f *FailingWriter) Writes() (writes, failures int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.writes, f.failures
+}
+
+// PartialWriter writes at most N bytes of every write and reports
+// io.ErrShortWrite for the rest, like a full pipe or socket buffer
+type PartialWriter struct {
+	// W receives the bytes written
+	// Optional. Default: ioutil.Discard
+	W io.Writer
+	// N is the number of bytes written per write
+	N int
+}
+
+// Write writes the first N bytes of p to W
+func (w *PartialWriter) Write(p []byte) (int, error) {
+	if len(p) <= w.N {
+		return writer(w.W).Write(p)
+	}
+	n, err := writer(w.W).Write(p[:w.N])
+	if err != nil {
+		return n, err
+	}
+	return n, io.ErrShortWrite
+}
+
+func writer(w io.Writer) io.Writer {
+	if w == nil {
+		return ioutil.Discard
+	}
+	return w
+}
@@ -0,0 +1,69 @@
+package logtest_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/gofiber/logger"
+	"github.com/gofiber/logger/logtest"
+)
+
+func TestSlowWriter(t *testing.T) {
+	buf := &strings.Builder{}
+	w := &logtest.SlowWriter{W: buf, Delay: 10 * time.Millisecond}
+	start := time.Now()
+	w.Write([]byte("entry"))
+	if d := time.Since(start); d < 10*time.Millisecond || buf.String() != "entry" {
+		t.Errorf("Has: %s after %v, expected: entry after 10ms", buf.String(), d)
+	}
+}
+
+func TestFailingWriter(t *testing.T) {
+	buf := &strings.Builder{}
+	w := &logtest.FailingWriter{W: buf, After: 1, For: 2}
+	var errs []error
+	for _, p := range []string{"a", "b", "c", "d"} {
+		_, err := w.Write([]byte(p))
+		errs = append(errs, err)
+	}
+	if errs[0] != nil || errs[1] != logtest.ErrInjected || errs[2] != logtest.ErrInjected || errs[3] != nil {
+		t.Errorf("Has: %v, expected: [nil injected injected nil]", errs)
+	}
+	if writes, failures := w.Writes(); writes != 4 || failures != 2 || buf.String() != "ad" {
+		t.Errorf("Has: %d writes, %d failures, %s, expected: 4 writes, 2 failures, ad", writes, failures, buf.String())
+	}
+}
+
+func TestPartialWriter(t *testing.T) {
+	buf := &strings.Builder{}
+	w := &logtest.PartialWriter{W: buf, N: 3}
+	n, err := w.Write([]byte("entry"))
+	if n != 3 || err != io.ErrShortWrite || buf.String() != "ent" {
+		t.Errorf("Has: %d, %v, %s, expected: 3, short write, ent", n, err, buf.String())
+	}
+}
+
+func TestFailingWriter_health(t *testing.T) {
+	w := &logtest.FailingWriter{For: 1}
+	l := logger.NewLogger(logger.Config{
+		Output:      w,
+		Diagnostics: &strings.Builder{},
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/", func(ctx *fiber.Ctx) {})
+
+	for _, expected := range []string{"degraded", "ok"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+		if h := l.Health(); h.Status != expected {
+			t.Errorf("Has: %s, expected: %s", h.Status, expected)
+		}
+	}
+}
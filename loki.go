@@ -0,0 +1,357 @@
+package logger
+
+import (
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// errLokiBacklog is returned for lines dropped while pushes fail
+var errLokiBacklog = errors.New("logger: loki backlog full, entry dropped")
+
+// LokiConfig configures a LokiWriter
+type LokiConfig struct {
+	// URL of the push API, e.g. "http://loki:3100/loki/api/v1/push"
+	// Required
+	URL string
+	// Labels are the labels of all streams, e.g. {"service": "shop", "env": "prod"}
+	// Optional. Default: nil
+	Labels map[string]string
+	// EntryLabels map label names to a value of the entry: "route",
+	// "method", "status", "priority" or a tag, e.g. {"route": "route"}.
+	// Every combination of values is a stream of its own, so keep their
+	// cardinality low
+	// Optional. Default: nil
+	EntryLabels map[string]string
+	// TenantID is sent as X-Scope-OrgID to a multi-tenant Loki
+	// Optional. Default: ""
+	TenantID string
+	// BatchSize is the number of lines pushed at once
+	// Optional. Default: 1000
+	BatchSize int
+	// BatchWait is the longest a line waits before it is pushed
+	// Optional. Default: 1 * time.Second
+	BatchWait time.Duration
+	// Backlog is the number of lines held while pushes fail, further lines
+	// are dropped
+	// Optional. Default: 10 * BatchSize
+	Backlog int
+	// Retries is how often a push is retried after a network error, 429 or
+	// 5xx response
+	// Optional. Default: 5
+	Retries int
+	// MinBackoff is the wait before the first retry, doubled per retry
+	// Optional. Default: 500 * time.Millisecond
+	MinBackoff time.Duration
+	// MaxBackoff is the longest wait between retries
+	// Optional. Default: 30 * time.Second
+	MaxBackoff time.Duration
+	// Gzip compresses the pushed JSON
+	// Optional. Default: false
+	Gzip bool
+	// Client sends the pushes
+	// Optional. Default: a client with a 10 second timeout
+	Client *http.Client
+}
+
+// lokiLabel is a label with a static value or the entry value of source
+type lokiLabel struct {
+	name   string
+	value  string
+	source string
+}
+
+// lokiBatch holds the lines of a push by stream, keyed by the JSON of their
+// labels
+type lokiBatch struct {
+	streams map[string]*bytebufferpool.ByteBuffer
+	keys    []string
+	lines   int
+}
+
+func newLokiBatch() *lokiBatch {
+	return &lokiBatch{streams: make(map[string]*bytebufferpool.ByteBuffer)}
+}
+
+// LokiWriter pushes lines to Grafana Loki as JSON, each write being one
+// line. Lines are batched by stream and pushed by BatchSize or after
+// BatchWait, failed pushes are retried with exponential backoff. As an
+// EntryWriter the labels of EntryLabels are taken from the entry, lines
+// written by other means only have Labels. Writes only fail while Backlog
+// lines are held, failed pushes are reported by Connected
+type LokiWriter struct {
+	cfg    LokiConfig
+	labels []lokiLabel
+	mu     sync.Mutex
+	batch  *lokiBatch
+	failed bool
+	full   chan struct{}
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewLokiWriter starts pushing to the Loki of cfg
+func NewLokiWriter(cfg LokiConfig) (*LokiWriter, error) {
+	if cfg.URL == "" {
+		return nil, errors.New("logger: loki URL is required")
+	}
+	if cfg.BatchSize <= 0 {
+		cfg.BatchSize = 1000
+	}
+	if cfg.BatchWait <= 0 {
+		cfg.BatchWait = time.Second
+	}
+	if cfg.Backlog <= 0 {
+		cfg.Backlog = 10 * cfg.BatchSize
+	}
+	if cfg.Retries <= 0 {
+		cfg.Retries = 5
+	}
+	if cfg.MinBackoff <= 0 {
+		cfg.MinBackoff = 500 * time.Millisecond
+	}
+	if cfg.MaxBackoff <= 0 {
+		cfg.MaxBackoff = 30 * time.Second
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	w := &LokiWriter{
+		cfg:    cfg,
+		batch:  newLokiBatch(),
+		full:   make(chan struct{}, 1),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	for name, value := range cfg.Labels {
+		w.labels = append(w.labels, lokiLabel{name: name, value: value})
+	}
+	for name, source := range cfg.EntryLabels {
+		w.labels = append(w.labels, lokiLabel{name: name, source: source})
+	}
+	for _, l := range w.labels {
+		if !validLokiLabel(l.name) {
+			return nil, fmt.Errorf("logger: invalid loki label %q", l.name)
+		}
+	}
+	sort.Slice(w.labels, func(i, j int) bool { return w.labels[i].name < w.labels[j].name })
+	go w.run()
+	return w, nil
+}
+
+// validLokiLabel reports whether name matches [a-zA-Z_][a-zA-Z0-9_]*
+func validLokiLabel(name string) bool {
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c == '_' || i > 0 && c >= '0' && c <= '9') {
+			return false
+		}
+	}
+	return name != ""
+}
+
+// Tags returns the tags of EntryLabels, so they are in the entry
+func (w *LokiWriter) Tags() []string {
+	var sources []string
+	for _, l := range w.labels {
+		sources = append(sources, l.source)
+	}
+	return sourceTags(sources...)
+}
+
+// Write adds p as a line with the static labels
+func (w *LokiWriter) Write(p []byte) (int, error) {
+	return w.add(nil, time.Now(), p)
+}
+
+// WriteEntry adds p as a line with the labels of e
+func (w *LokiWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return w.add(e, e.Time, p)
+}
+
+func (w *LokiWriter) add(e *Entry, t time.Time, p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	key := bytebufferpool.Get()
+	defer bytebufferpool.Put(key)
+	w.appendLabels(key, e)
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	b := w.batch
+	if b.lines >= w.cfg.Backlog {
+		return 0, errLokiBacklog
+	}
+	values := b.streams[string(key.B)]
+	if values == nil {
+		values = bytebufferpool.Get()
+		b.streams[key.String()] = values
+		b.keys = append(b.keys, key.String())
+	} else {
+		values.WriteString(",")
+	}
+	values.WriteString(`["`)
+	values.B = strconv.AppendInt(values.B, t.UnixNano(), 10)
+	values.WriteString(`",`)
+	appendJSONString(values, string(p))
+	values.WriteString("]")
+	b.lines++
+	if b.lines == w.cfg.BatchSize {
+		select {
+		case w.full <- struct{}{}:
+		default:
+		}
+	}
+	return n, nil
+}
+
+// appendLabels writes the labels as a JSON object, labels taken from the
+// entry are left out without e or when empty
+func (w *LokiWriter) appendLabels(buf *bytebufferpool.ByteBuffer, e *Entry) {
+	buf.WriteString("{")
+	from := buf.Len()
+	for _, l := range w.labels {
+		value := l.value
+		if l.source != "" {
+			if e == nil {
+				continue
+			}
+			value = entryValue(e, l.source)
+		}
+		appendJSONKey(buf, l.name, value)
+	}
+	if buf.Len() > from {
+		// Drop the comma of the first key
+		buf.B = append(buf.B[:from], buf.B[from+1:]...)
+	}
+	buf.WriteString("}")
+}
+
+// run pushes the batch every BatchWait or once it is full, until Close
+func (w *LokiWriter) run() {
+	ticker := time.NewTicker(w.cfg.BatchWait)
+	defer ticker.Stop()
+	defer close(w.closed)
+	for {
+		select {
+		case <-ticker.C:
+		case <-w.full:
+		case <-w.done:
+			w.flush()
+			return
+		}
+		w.flush()
+	}
+}
+
+// flush pushes the lines of the batch, they are dropped when the push
+// still fails after the retries
+func (w *LokiWriter) flush() {
+	w.mu.Lock()
+	b := w.batch
+	if b.lines == 0 {
+		w.mu.Unlock()
+		return
+	}
+	w.batch = newLokiBatch()
+	w.mu.Unlock()
+	body := bytebufferpool.Get()
+	defer bytebufferpool.Put(body)
+	body.WriteString(`{"streams":[`)
+	for i, key := range b.keys {
+		if i > 0 {
+			body.WriteString(",")
+		}
+		body.WriteString(`{"stream":` + key + `,"values":[`)
+		body.Write(b.streams[key].B)
+		body.WriteString("]}")
+		bytebufferpool.Put(b.streams[key])
+	}
+	body.WriteString("]}")
+	err := w.push(body.B)
+	w.mu.Lock()
+	w.failed = err != nil
+	w.mu.Unlock()
+}
+
+// push sends body, retrying with exponential backoff. Retries stop on Close
+func (w *LokiWriter) push(body []byte) error {
+	backoff := w.cfg.MinBackoff
+	for retry := 0; ; retry++ {
+		temporary, err := w.send(body)
+		if err == nil || !temporary || retry == w.cfg.Retries {
+			return err
+		}
+		select {
+		case <-time.After(backoff):
+		case <-w.done:
+			return err
+		}
+		if backoff *= 2; backoff > w.cfg.MaxBackoff {
+			backoff = w.cfg.MaxBackoff
+		}
+	}
+}
+
+// send makes a single push, temporary reports whether it is worth retrying
+func (w *LokiWriter) send(body []byte) (temporary bool, err error) {
+	var r io.Reader = bytes.NewReader(body)
+	if w.cfg.Gzip {
+		var gz bytes.Buffer
+		zw := gzip.NewWriter(&gz)
+		zw.Write(body)
+		zw.Close()
+		r = &gz
+	}
+	req, err := http.NewRequest(http.MethodPost, w.cfg.URL, r)
+	if err != nil {
+		return false, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if w.cfg.Gzip {
+		req.Header.Set("Content-Encoding", "gzip")
+	}
+	if w.cfg.TenantID != "" {
+		req.Header.Set("X-Scope-OrgID", w.cfg.TenantID)
+	}
+	resp, err := w.cfg.Client.Do(req)
+	if err != nil {
+		return true, err
+	}
+	defer resp.Body.Close()
+	msg, _ := ioutil.ReadAll(io.LimitReader(resp.Body, 512))
+	if resp.StatusCode/100 == 2 {
+		return false, nil
+	}
+	err = fmt.Errorf("logger: loki push: %s: %s", resp.Status, bytes.TrimSpace(msg))
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500, err
+}
+
+// Connected reports whether the last push succeeded, for Health
+func (w *LokiWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return !w.failed
+}
+
+// Close pushes the pending lines without further retries and stops the
+// writer
+func (w *LokiWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.closed
+	return nil
+}
@@ -0,0 +1,174 @@
+package logger
+
+import (
+	"compress/gzip"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// lokiPush is the JSON body of a push
+type lokiPush struct {
+	Streams []struct {
+		Stream map[string]string `json:"stream"`
+		Values [][2]string       `json:"values"`
+	} `json:"streams"`
+}
+
+func TestLokiWriter(t *testing.T) {
+	pushes := make(chan lokiPush, 10)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Content-Type") != "application/json" || r.Header.Get("X-Scope-OrgID") != "acme" {
+			t.Errorf("Has: %v, expected: JSON for tenant acme", r.Header)
+		}
+		zr, err := gzip.NewReader(r.Body)
+		if err != nil {
+			t.Fatalf("Has: %v, expected: gzip", err)
+		}
+		var push lokiPush
+		if err := json.NewDecoder(zr).Decode(&push); err != nil {
+			t.Errorf("Has: %v, expected: JSON", err)
+		}
+		pushes <- push
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	lw, err := NewLokiWriter(LokiConfig{
+		URL:         server.URL,
+		Labels:      map[string]string{"service": "shop"},
+		EntryLabels: map[string]string{"route": "route", "tenant": "header:X-Tenant"},
+		TenantID:    "acme",
+		BatchSize:   3,
+		BatchWait:   time.Hour,
+		Gzip:        true,
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer lw.Close()
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${method} ${path}\n",
+		Output: lw,
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {})
+	app.Get("/health", func(ctx *fiber.Ctx) {})
+
+	for _, path := range []string{"/users/1", "/users/2", "/health"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Tenant", "t1")
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	var push lokiPush
+	select {
+	case push = <-pushes:
+	case <-time.After(time.Second):
+		t.Fatal("Has: no push, expected: a push of a full batch")
+	}
+	if len(push.Streams) != 2 {
+		t.Fatalf("Has: %+v, expected: a stream per route", push)
+	}
+	users := push.Streams[0]
+	if users.Stream["service"] != "shop" || users.Stream["route"] != "/users/:id" || users.Stream["tenant"] != "t1" {
+		t.Errorf("Has: %v, expected: service, route and tenant labels", users.Stream)
+	}
+	if len(users.Values) != 2 || users.Values[0][1] != "GET /users/1" || users.Values[1][1] != "GET /users/2" {
+		t.Errorf("Has: %v, expected: the lines without newlines", users.Values)
+	}
+	if push.Streams[1].Stream["route"] != "/health" {
+		t.Errorf("Has: %v, expected: route /health", push.Streams[1].Stream)
+	}
+}
+
+func TestLokiWriter_retry(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if atomic.AddInt32(&attempts, 1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	lw, err := NewLokiWriter(LokiConfig{
+		URL:        server.URL,
+		BatchSize:  1,
+		BatchWait:  time.Hour,
+		MinBackoff: time.Millisecond,
+	})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	lw.Write([]byte("line\n"))
+	deadline := time.Now().Add(time.Second)
+	for atomic.LoadInt32(&attempts) < 3 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	lw.Close()
+	if n := atomic.LoadInt32(&attempts); n != 3 {
+		t.Errorf("Has: %d attempts, expected: 3", n)
+	}
+	if !lw.Connected() {
+		t.Errorf("Has: disconnected, expected: connected after a successful retry")
+	}
+}
+
+func TestLokiWriter_badRequest(t *testing.T) {
+	var attempts int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&attempts, 1)
+		ioutil.ReadAll(r.Body)
+		http.Error(w, "entry out of order", http.StatusBadRequest)
+	}))
+	defer server.Close()
+
+	lw, err := NewLokiWriter(LokiConfig{URL: server.URL, BatchWait: time.Hour, MinBackoff: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	lw.Write([]byte("line\n"))
+	// Close pushes the pending line
+	lw.Close()
+	if n := atomic.LoadInt32(&attempts); n != 1 {
+		t.Errorf("Has: %d attempts, expected: 1 without retries", n)
+	}
+	if lw.Connected() {
+		t.Errorf("Has: connected, expected: disconnected after a failed push")
+	}
+}
+
+func TestLokiWriter_backlog(t *testing.T) {
+	lw, err := NewLokiWriter(LokiConfig{URL: "http://127.0.0.1:0", BatchSize: 10, Backlog: 2, BatchWait: time.Hour})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer lw.Close()
+	for i := 0; i < 2; i++ {
+		if _, err := lw.Write([]byte("line\n")); err != nil {
+			t.Errorf("Has: %v, expected: nil", err)
+		}
+	}
+	if _, err := lw.Write([]byte("line\n")); err != errLokiBacklog {
+		t.Errorf("Has: %v, expected: %v", err, errLokiBacklog)
+	}
+}
+
+func TestNewLokiWriter_invalid(t *testing.T) {
+	if _, err := NewLokiWriter(LokiConfig{}); err == nil {
+		t.Errorf("Has: nil, expected: an error without URL")
+	}
+	if _, err := NewLokiWriter(LokiConfig{URL: "http://loki", Labels: map[string]string{"service-name": "shop"}}); err == nil {
+		t.Errorf("Has: nil, expected: an error for an invalid label")
+	}
+}
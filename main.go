@@ -7,9 +7,11 @@ package logger
 import (
 	"fmt"
 	"io"
+	"net"
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"github.com/gofiber/fiber"
@@ -19,29 +21,97 @@ import (
 
 // Filter variables
 const (
-	strTime          = "time"
-	strReferer       = "referer"
-	strProtocol      = "protocol"
-	strIp            = "ip"
-	strIps           = "ips"
-	strHost          = "host"
-	strMethod        = "method"
-	strPath          = "path"
-	strUrl           = "url"
-	strUa            = "ua"
-	strLatency       = "latency"
-	strStatus        = "status"
-	strBody          = "body"
-	strBytesSent     = "bytesSent"
-	strBytesReceived = "bytesReceived"
-	strRoute         = "route"
-	strError         = "error"
-	strHeader        = "header:"
-	strQuery         = "query:"
-	strForm          = "form:"
-	strCookie        = "cookie:"
+	strTime            = "time"
+	strReferer         = "referer"
+	strProtocol        = "protocol"
+	strIp              = "ip"
+	strIps             = "ips"
+	strHost            = "host"
+	strMethod          = "method"
+	strPath            = "path"
+	strUrl             = "url"
+	strUa              = "ua"
+	strLatency         = "latency"
+	strStatus          = "status"
+	strBody            = "body"
+	strBytesSent       = "bytesSent"
+	strBytesReceived   = "bytesReceived"
+	strRoute           = "route"
+	strError           = "error"
+	strPriority        = "priority"
+	strErrorType       = "errorType"
+	strErrorCode       = "errorCode"
+	strErrorStack      = "errorStack"
+	strRetriable       = "retriable"
+	strUpstreamAddr    = "upstreamAddr"
+	strUpstreamStatus  = "upstreamStatus"
+	strUpstreamLatency = "upstreamLatency"
+	strAttempts        = "attempts"
+	strRetried         = "retriedUpstreams"
+	strIpHostname      = "ipHostname"
+	strIpType          = "ipType"
+	strStatusSymbol    = "statusSymbol"
+	strRequestID       = "requestId"
+	strTraceID         = "traceId"
+	strSpanID          = "spanId"
+	strConditional     = "conditional"
+	strRange           = "range"
+	strContentRange    = "contentRange"
+	strLocation        = "location"
+	strRedirect        = "redirect"
+	strFilePath        = "filePath"
+	strFileSize        = "fileSize"
+	strDeployment      = "deployment"
+	strFlags           = "flags"
+	strVariant         = "variant"
+	strIpAnon          = "ipAnon"
+	strUaFamily        = "uaFamily"
+	strRefererHost     = "refererHost"
+	strLatencyBucket   = "latencyBucket"
+	strLang            = "lang"
+	strReplayHeaders   = "replayHeaders"
+	strDeadline        = "deadline"
+	strTimedOut        = "timedOut"
+	strRetryAfter      = "retryAfter"
+	strThrottled       = "throttled"
+	strStatusColor     = "statusColor"
+	strLocalIp         = "localIp"
+	strRequestLine     = "requestLine"
+	strSeq             = "seq"
+	strPid             = "pid"
+	strResBody         = "resBody"
+	strGrpcService     = "grpcService"
+	strGrpcMethod      = "grpcMethod"
+	strGrpcStatus      = "grpcStatus"
+	strDeps            = "deps"
+	strHeader          = "header:"
+	strQuery           = "query:"
+	strForm            = "form:"
+	strCookie          = "cookie:"
+	strBaggage         = "baggage:"
+	strFlag            = "flag:"
+	strLocals          = "locals:"
+	strColor           = "color:"
+	strClf             = "clf:"
 )
 
+// tags lists all variables, prefixes end with a colon
+var tags = []string{
+	strTime, strReferer, strProtocol, strIp, strIps, strHost, strMethod, strPath,
+	strUrl, strUa, strLatency, strStatus, strBody, strBytesSent, strBytesReceived,
+	strRoute, strError, strPriority, strErrorType, strErrorCode, strErrorStack,
+	strRetriable, strUpstreamAddr, strUpstreamStatus, strUpstreamLatency,
+	strAttempts, strRetried, strIpHostname, strIpType, strStatusSymbol,
+	strRequestID, strTraceID, strSpanID,
+	strConditional, strRange, strContentRange, strLocation, strRedirect,
+	strFilePath, strFileSize, strDeployment, strFlags, strVariant,
+	strIpAnon, strUaFamily, strRefererHost, strLatencyBucket, strLang,
+	strReplayHeaders, strDeadline, strTimedOut, strRetryAfter, strThrottled,
+	strStatusColor, strLocalIp, strRequestLine, strSeq, strPid, strResBody,
+	strGrpcService, strGrpcMethod, strGrpcStatus, strDeps,
+	strHeader, strQuery, strForm, strCookie, strBaggage, strFlag, strLocals, strColor, strClf,
+}
+
 // Config ...
 type Config struct {
 	// Filter defines a function to skip middleware.
@@ -52,116 +122,931 @@ type Config struct {
 	// Possible values:
 	// time, ip, ips, url, host, method, path, protocol, route
 	// referer, ua, latency, status, body, error, bytesSent, bytesReceived
-	// header:<key>, query:<key>, form:<key>, cookie:<key>
+	// errorType, errorCode, errorStack, retriable, priority
+	// upstreamAddr, upstreamStatus, upstreamLatency, attempts, retriedUpstreams
+	// ipHostname, ipType, statusSymbol, requestId, traceId, spanId
+	// conditional, range, contentRange, location, redirect, filePath, fileSize
+	// deployment, flags, variant
+	// ipAnon, uaFamily, refererHost, latencyBucket, lang, replayHeaders
+	// deadline, timedOut, retryAfter, throttled, statusColor, localIp, requestLine
+	// seq
+	// header:<key>, query:<key>, form:<key>, cookie:<key>, baggage:<key>
+	// flag:<key>, locals:<key>, color:<name>, clf:<tag>
+	// partial:<name>
+	// FormatJSON ("json") writes JSON lines with a JSONEncoder instead,
+	// FormatGELF ("gelf") GELF messages with a GELFEncoder, FormatSyslog
+	// ("syslog") RFC 5424 messages with a SyslogEncoder, FormatCEF ("cef")
+	// Common Event Format with a CEFEncoder, FormatCSV ("csv") or FormatTSV
+	// ("tsv") rows with a CSVEncoder, FormatGCP ("gcp") Google Cloud Logging
+	// JSON with a GCPEncoder and FormatDatadog ("datadog") JSON with Datadog's
+	// standard attributes with a DatadogEncoder
 	Format string
+	// Encoder writes entries in a structured format instead of Format
+	// Optional. Default: the encoder of a structured Format, nil otherwise
+	Encoder Encoder
+	// Fields are the tags a structured Format writes besides the standard keys
+	// Optional. Default: nil
+	Fields []string
+	// SchemaVersion is stamped into the entries of FormatJSON and selects
+	// their layout, see JSONEncoder.SchemaVersion
+	// Optional. Default: "" (SchemaV1 without the schema key)
+	SchemaVersion string
+	// BufferSize is the initial capacity of the buffer entries are rendered
+	// in, set it when entries are much longer than estimated from the formats
+	// Optional. Default: estimated from the formats and encoders
+	BufferSize int
+	// Partials defines named sub-formats, included with ${partial:<name>}
+	// Optional. Default: nil
+	Partials map[string]string
 	// TimeFormat https://programming.guide/go/format-parse-string-time-date-example.html
 	// Optional. Default: 15:04:05
 	TimeFormat string
+	// RequestID takes the request id from X-Request-ID and the trace context
+	// from traceparent or generates new ones, see RequestID, Trace and Inject
+	// Optional. Default: false
+	RequestID bool
+	// Propagation are the formats the trace context is read from, the first
+	// one present in a request is used, and that Inject writes
+	// Optional. Default: []Propagation{PropagationW3C}
+	Propagation []Propagation
+	// Tracer receives a server span per request with the ids that are logged,
+	// setting it enables RequestID
+	// Optional. Default: nil
+	Tracer Tracer
+	// Human renders sizes and latencies for reading in a terminal, like
+	// 1.2 KB and 1.23ms, aligned in columns. Meant for local development
+	// Optional. Default: false
+	Human bool
+	// StatusSymbols renders ${statusSymbol} as ✓ for 2xx, ⚠ for 4xx, ⏳ for
+	// 429, ✗ for 5xx and 🐢 for slow requests. Only applies when Output is a
+	// terminal
+	// Optional. Default: false
+	StatusSymbols bool
+	// Colors enables ${statusColor} and ${color:<name>}. Escape codes are
+	// stripped for every output that is not a terminal
+	// Optional. Default: false
+	Colors bool
+	// FiberCompat renders tags like fiber's middleware/logger, so text
+	// formats copy between both without edits: ${latency} is rounded to
+	// milliseconds and right aligned in 7 columns, ${status} and ${method}
+	// are colored with Colors and ${black} to ${reset} are colors like
+	// ${color:<name>}. It implies Colors
+	// Optional. Default: false
+	FiberCompat bool
+	// Columns pads or truncates tags to a fixed width so plain text entries
+	// line up, a negative width aligns to the right. Example: {"path": 30}
+	// Optional. Default: nil
+	Columns map[string]int
+	// Deployment labels the entries of this instance for ${deployment}, such
+	// as "canary" or "stable", e.g. set from the environment with FromEnv
+	// Optional. Default: ""
+	Deployment string
+	// DeploymentHeader is a request header that overrides Deployment when
+	// present, for routers that mark the requests they send to a canary
+	// Optional. Default: ""
+	DeploymentHeader string
+	// Variant is the tag ${variant} is read from, such as "cookie:ab" or
+	// "locals:variant", to tie experiment variants to request performance
+	// Optional. Default: ""
+	Variant string
+	// ReplayHeaders are the request headers ${replayHeaders} includes in
+	// ReplayFormat entries, e.g. {"Authorization", "X-Account-ID"}
+	// Optional. Default: nil
+	ReplayHeaders []string
+	// Multiline folds line breaks in values into \n escapes or indents
+	// continuation lines, see MultilineFold and MultilineIndent
+	// Optional. Default: MultilineKeep
+	Multiline Multiline
 	// Output is a writter where logs are written
 	// Default: os.Stderr
 	Output io.Writer
+	// File writes the entries to a FileWriter with this config, replacing
+	// Output
+	// Optional. Default: nil
+	File *FileConfig
+	// Async hands the entries of Output to a background writer through a
+	// bounded queue, so a slow Output adds no latency to requests. Call
+	// Flush or Close on shutdown to write the queued entries. Outputs that
+	// are an EntryWriter batch on their own and are written directly
+	// Optional. Default: false
+	Async bool
+	// AsyncQueueSize is the number of entries queued with Async
+	// Optional. Default: 8192
+	AsyncQueueSize int
+	// OverflowPolicy is what Async does with an entry while the queue is
+	// full: wait for room, drop the entry or drop the oldest queued one
+	// Optional. Default: OverflowBlock
+	OverflowPolicy OverflowPolicy
+	// OutputStatuses limits Output to responses with a status in one of the
+	// ranges, e.g. {StatusSuccess} when errors go to a sink of their own
+	// Optional. Default: nil (all statuses)
+	OutputStatuses []StatusRange
+	// Sinks are further destinations, each with its own format, priority
+	// filter, status ranges and sampling
+	// Optional. Default: nil
+	Sinks []SinkConfig
+	// Outputs are further writers with their own format or encoder, sinks
+	// without filters
+	// Optional. Default: nil
+	Outputs []OutputConfig
+	// Processors transform entries before they are written, in order. With
+	// processors tags are rendered to the fields of an Entry first
+	// Optional. Default: nil
+	Processors []Processor
+	// Where logs only the entries matching the expression, e.g.
+	// `status >= 500 || latency > 2s && path =~ "^/api"`, see Expr. It is
+	// compiled by NewLogger and runs before Processors
+	// Optional. Default: "" (all entries)
+	Where string
+	// SlowThreshold marks requests taking at least this long as PrioritySlow
+	// Optional. Default: 0 (disabled)
+	SlowThreshold time.Duration
+	// Priority defines a function that assigns a priority class to an entry
+	// Optional. Default: PriorityError for 5xx responses, server side gRPC
+	// statuses and handler errors, PrioritySlow above SlowThreshold,
+	// PriorityOK otherwise
+	Priority func(c *fiber.Ctx, latency time.Duration) Priority
+	// FirstN logs only the first N entries per route and status within
+	// FirstNInterval, later entries are sampled and summarized
+	// Optional. Default: 0 (disabled)
+	FirstN int
+	// FirstNInterval is the window FirstN is counted in
+	// Optional. Default: 1 * time.Minute
+	FirstNInterval time.Duration
+	// FirstNSampleRate is the fraction (0..1) of entries logged after the first N
+	// Optional. Default: 0
+	FirstNSampleRate float64
+	// SampleSeed is mixed into the hash of the request id that sampling
+	// decisions are made by. Loggers with the same seed keep the same
+	// requests, so all replicas and hops agree
+	// Optional. Default: ""
+	SampleSeed string
+	// AggregateErrors logs the first PriorityError entry per AggregateKey and
+	// window verbatim and collapses the rest into a periodic aggregate line
+	// Optional. Default: false
+	AggregateErrors bool
+	// AggregateKey defines the key identical errors are grouped by
+	// Optional. Default: route and status
+	AggregateKey func(c *fiber.Ctx) string
+	// AggregateWindow is the interval aggregate lines are written in
+	// Optional. Default: 1 * time.Minute
+	AggregateWindow time.Duration
+	// ErrorClassifier maps handler errors to a domain code and whether the
+	// request can be retried, exposed as ${errorCode} and ${retriable}
+	// Optional. Default: a Code() method or field of the error and its
+	// Temporary() or Timeout() result
+	ErrorClassifier func(err error) (code string, retriable bool)
+	// Budgets limits the entries and bytes logged per route and interval,
+	// the first matching budget applies
+	// Optional. Default: nil
+	Budgets []Budget
+	// Captures dump the raw request and response of matching requests to a
+	// debug sink, the first matching capture applies
+	// Optional. Default: nil
+	Captures []Capture
+	// DNSCacheSize is the number of hostnames cached for ${ipHostname}
+	// Optional. Default: 1024
+	DNSCacheSize int
+	// DNSTimeout limits a reverse lookup for ${ipHostname}
+	// Optional. Default: 1 * time.Second
+	DNSTimeout time.Duration
+	// CloudRanges adds CIDRs classified as "cloud" by ${ipType}
+	// Optional. Default: nil
+	CloudRanges []string
+	// ThrottleReport writes the number of 429 responses per client every
+	// ThrottleReport interval, to audit rate limiting
+	// Optional. Default: 0 (disabled)
+	ThrottleReport time.Duration
+	// Rollup writes a JSON line per route and status with the request count
+	// and latency quantiles every Rollup interval
+	// Optional. Default: 0 (disabled)
+	Rollup time.Duration
+	// RollupOutput is a writer where rollups are written
+	// Optional. Default: Output
+	RollupOutput io.Writer
+	// RollupOnly writes rollups instead of the individual entries
+	// Optional. Default: false
+	RollupOnly bool
+	// CacheReport writes the ratio of 304 to 200 responses per route every
+	// CacheReport interval, to tune caching from the access log
+	// Optional. Default: 0 (disabled)
+	CacheReport time.Duration
+	// Clock tells the time of entries, set it in tests to get deterministic
+	// ${time} and ${latency} values
+	// Optional. Default: the system clock
+	Clock Clock
+	// Diagnostics is a writer for the logger's own messages, such as write
+	// errors, kept apart from the access log
+	// Optional. Default: os.Stderr
+	Diagnostics io.Writer
+	// DiagnosticsLevel is the minimum level of diagnostic messages written
+	// Optional. Default: LevelInfo
+	DiagnosticsLevel Level
+	// Remote polls the level, sample rate and redacted fields from a URL and
+	// applies them to all entries at once, for changing the logging of a
+	// fleet without restarts. Entries are built to redact their fields
+	// Optional. Default: nil
+	Remote *RemoteConfig
+	// Validate checks the configuration with Logger.Validate when the logger
+	// is created and panics on problems, failing at startup instead of later
+	// Optional. Default: false
+	Validate bool
 }
 
-// New ...
-func New(config ...Config) func(*fiber.Ctx) {
-	// Init config
-	var cfg Config
-	// Set config if provided
-	if len(config) > 0 {
-		cfg = config[0]
+// Priority classifies entries by how important they are to keep
+type Priority int
+
+// Priority classes, ordered from lowest to highest
+const (
+	PriorityOK Priority = iota
+	PrioritySlow
+	PriorityError
+)
+
+// String returns the lowercase name of the priority class
+func (p Priority) String() string {
+	switch p {
+	case PriorityOK:
+		return "ok"
+	case PrioritySlow:
+		return "slow"
+	case PriorityError:
+		return "error"
 	}
+	return strconv.Itoa(int(p))
+}
+
+// priority is the default Config.Priority
+func priority(slow time.Duration) func(*fiber.Ctx, time.Duration) Priority {
+	return func(c *fiber.Ctx, latency time.Duration) Priority {
+		if c.Fasthttp.Response.StatusCode() >= 500 || c.Error() != nil || grpcServerError(c) {
+			return PriorityError
+		}
+		if slow > 0 && latency >= slow {
+			return PrioritySlow
+		}
+		return PriorityOK
+	}
+}
+
+// Logger is an access logging middleware, use Handle as fiber handler
+type Logger struct {
+	// seq is the last sequence number, first for 64-bit alignment of atomic
+	// operations on 32-bit platforms
+	seq uint64
+	// pending is the number of entries queued with Async and not written
+	pending int64
+	// dropped is the number of entries dropped by OverflowPolicy
+	dropped   uint64
+	cfg       Config
+	tmpl      *fasttemplate.Template
+	timestamp string
+	first     *firstN
+	errs      *aggregate
+	budgets   []*budget
+	captures  []*capture
+	sinks     []*sink
+	entries   bool
+	fields    []string
+	headers   headerKeys
+	bufSize   int
+	mutes     mutes
+	rdns      *rdns
+	cloud     []*net.IPNet
+	rollup    *rollup
+	cache     *cacheReport
+	throttles *throttleReport
+	out       *output
+	rollupOut *output
+	symbols   bool
+	numbered  bool
+	timed     bool
+	control   control
+	async     *async
+	done      chan struct{}
+	once      sync.Once
+}
+
+// New creates the middleware handler, multiple configs are layered with Merge
+func New(config ...Config) func(*fiber.Ctx) {
+	return NewLogger(config...).Handle
+}
+
+// NewLogger creates a Logger for callers that need its methods besides the handler
+func NewLogger(config ...Config) *Logger {
+	// Init config, later configs override earlier ones
+	cfg := Merge(config...)
 	// Set config default values
 	if cfg.Format == "" {
 		cfg.Format = "${time} ${method} ${path} - ${ip} - ${status} - ${latency}\n"
 	}
+	if cfg.TimeFormat == "" && cfg.Format == FormatJSON {
+		cfg.TimeFormat = time.RFC3339Nano
+	}
 	if cfg.TimeFormat == "" {
 		cfg.TimeFormat = "15:04:05"
 	}
+	format, err := expandPartials(cfg.Format, cfg.Partials)
+	if err != nil {
+		panic(err)
+	}
+	cfg.Format = format
+	cfg.Processors = where(cfg.Where, cfg.Processors)
+	if cfg.File != nil {
+		fw, err := NewFileWriter(*cfg.File)
+		if err != nil {
+			panic(err)
+		}
+		cfg.Output = fw
+	}
 	if cfg.Output == nil {
 		cfg.Output = os.Stderr
 	}
+	if cfg.Tracer != nil {
+		cfg.RequestID = true
+	}
+	if len(cfg.Propagation) == 0 {
+		cfg.Propagation = []Propagation{PropagationW3C}
+	}
+	if cfg.Diagnostics == nil {
+		cfg.Diagnostics = os.Stderr
+	}
+	if cfg.FiberCompat {
+		cfg.Colors = true
+	}
+	if cfg.Clock == nil {
+		cfg.Clock = systemClock{}
+	}
+	if cfg.Priority == nil {
+		cfg.Priority = priority(cfg.SlowThreshold)
+	}
+	if cfg.FirstNInterval <= 0 {
+		cfg.FirstNInterval = time.Minute
+	}
+	if cfg.AggregateKey == nil {
+		cfg.AggregateKey = routeStatus
+	}
+	if cfg.AggregateWindow <= 0 {
+		cfg.AggregateWindow = time.Minute
+	}
+	if cfg.ErrorClassifier == nil {
+		cfg.ErrorClassifier = classifyError
+	}
+	if cfg.Encoder == nil {
+		cfg.Encoder = formatEncoder(cfg.Format, cfg.TimeFormat, &cfg)
+	}
+	if cfg.DNSCacheSize <= 0 {
+		cfg.DNSCacheSize = 1024
+	}
+	if cfg.DNSTimeout <= 0 {
+		cfg.DNSTimeout = time.Second
+	}
+	if cfg.AsyncQueueSize <= 0 {
+		cfg.AsyncQueueSize = 8192
+	}
 	// Middleware settings
-	tmpl := fasttemplate.New(cfg.Format, "${", "}")
-	timestamp := time.Now().Format(cfg.TimeFormat)
-	// Update date/time every second in a seperate go routine
-	if strings.Contains(cfg.Format, "${time}") {
+	l := &Logger{
+		cfg:       cfg,
+		tmpl:      fasttemplate.New(cfg.Format, "${", "}"),
+		timestamp: cfg.Clock.Now().Format(cfg.TimeFormat),
+		budgets:   newBudgets(cfg.Budgets),
+		captures:  newCaptures(cfg.Captures),
+		cloud:     mustCIDRs(cfg.CloudRanges...),
+		sinks:     newSinks(&cfg),
+		out:       newOutput("output", cfg.Output),
+		symbols:   cfg.StatusSymbols && isTerminal(cfg.Output),
+		done:      make(chan struct{}),
+	}
+	l.out.setHeader(cfg.Encoder)
+	l.rollupOut = l.out
+	if cfg.RollupOutput != nil {
+		l.rollupOut = newOutput("rollup", cfg.RollupOutput)
+	}
+	// Entries are built when processors, encoders or outputs need them
+	l.entries = len(cfg.Processors) > 0 || cfg.Encoder != nil || isEntryWriter(cfg.Output) || cfg.Remote != nil
+	for _, s := range l.sinks {
+		l.entries = l.entries || len(s.Processors) > 0 || s.Encoder != nil || isEntryWriter(s.Output)
+	}
+	if l.entries {
+		l.fields = l.tags()
+	}
+	l.headers = newHeaderKeys(append(l.tags(), cfg.Variant))
+	l.bufSize = l.bufferSize()
+	l.numbered = l.uses(strSeq)
+	l.timed = l.uses(strDeps)
+	// Strip colors from outputs that are not terminals
+	if cfg.Colors {
+		for _, o := range l.outputs() {
+			o.strip = !isTerminal(o.w)
+		}
+	}
+	if cfg.FirstN > 0 {
+		l.first = newFirstN(cfg.FirstN, cfg.FirstNSampleRate, cfg.SampleSeed, cfg.FirstNInterval)
+	}
+	if cfg.AggregateErrors {
+		l.errs = newAggregate(cfg.AggregateWindow)
+	}
+	if cfg.CacheReport > 0 {
+		l.cache = newCacheReport(cfg.CacheReport)
+	}
+	if cfg.ThrottleReport > 0 {
+		l.throttles = newThrottleReport(cfg.ThrottleReport)
+	}
+	if summaries := l.summarizers(); len(summaries) > 0 {
+		go l.summarize(summaries)
+	}
+	if cfg.Rollup > 0 {
+		l.rollup = newRollup(cfg.Rollup)
+		go l.rollups()
+	}
+	if cfg.Async && !isEntryWriter(cfg.Output) {
+		l.startAsync(cfg.AsyncQueueSize, cfg.OverflowPolicy)
+	}
+	if cfg.Remote != nil {
+		r := newRemote(*cfg.Remote)
+		go l.poll(r)
+	}
+	// Reverse lookups are only done when the hostname is logged
+	if l.uses(strIpHostname) || cfg.Variant == strIpHostname {
+		l.rdns = newRDNS(cfg.DNSCacheSize, cfg.DNSTimeout)
+	}
+	// Update date/time every second in a seperate go routine, other clocks
+	// are read per entry
+	if _, ok := cfg.Clock.(systemClock); ok && l.uses(strTime) {
 		go func() {
 			for {
-				timestamp = time.Now().Format(cfg.TimeFormat)
+				l.timestamp = time.Now().Format(cfg.TimeFormat)
 				time.Sleep(250 * time.Millisecond)
 			}
 		}()
 	}
-	// Middleware function
-	return func(c *fiber.Ctx) {
-		// Filter request to skip middleware
-		if cfg.Filter != nil && cfg.Filter(c) {
-			c.Next()
-			return
+	if cfg.Validate {
+		if err := l.Validate(); err != nil {
+			panic(err)
 		}
-		start := time.Now()
-		// handle request
+	}
+	return l
+}
+
+// Handle logs the request, it is the middleware function
+func (l *Logger) Handle(c *fiber.Ctx) {
+	cfg := &l.cfg
+	// Filter request to skip middleware
+	if cfg.Filter != nil && cfg.Filter(c) {
 		c.Next()
-		// build log
-		stop := time.Now()
-		// Get new buffer
-		buf := bytebufferpool.Get()
-		_, err := tmpl.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
-			switch tag {
-			case strTime:
-				return buf.WriteString(timestamp)
-			case strReferer:
-				return buf.WriteString(c.Get(fiber.HeaderReferer))
-			case strProtocol:
-				return buf.WriteString(c.Protocol())
-			case strIp:
-				return buf.WriteString(c.IP())
-			case strIps:
-				return buf.WriteString(c.Get(fiber.HeaderXForwardedFor))
-			case strHost:
-				return buf.WriteString(c.Hostname())
-			case strMethod:
-				return buf.WriteString(c.Method())
-			case strPath:
-				return buf.WriteString(c.Path())
-			case strUrl:
-				return buf.WriteString(c.OriginalURL())
-			case strUa:
-				return buf.WriteString(c.Get(fiber.HeaderUserAgent))
-			case strLatency:
-				return buf.WriteString(stop.Sub(start).String())
-			case strStatus:
-				return buf.WriteString(strconv.Itoa(c.Fasthttp.Response.StatusCode()))
-			case strBody:
-				return buf.WriteString(c.Body())
-			case strBytesReceived:
-				return buf.WriteString(strconv.Itoa(len(c.Fasthttp.Request.Body())))
-			case strBytesSent:
-				return buf.WriteString(strconv.Itoa(len(c.Fasthttp.Response.Body())))
-			case strRoute:
-				return buf.WriteString(c.Route().Path)
-			case strError:
-				return buf.WriteString(c.Error().Error())
-			default:
-				switch {
-				case strings.HasPrefix(tag, strHeader):
-					return buf.WriteString(c.Get(tag[7:]))
-				case strings.HasPrefix(tag, strQuery):
-					return buf.WriteString(c.Query(tag[6:]))
-				case strings.HasPrefix(tag, strForm):
-					return buf.WriteString(c.FormValue(tag[5:]))
-				case strings.HasPrefix(tag, strCookie):
-					return buf.WriteString(c.Cookies(tag[7:]))
-				}
+		return
+	}
+	if cfg.RequestID {
+		correlate(c, cfg.Propagation)
+	}
+	if l.timed {
+		// Set before the handler, so its goroutines share them
+		c.Locals(localsDeps, &deps{})
+	}
+	start := cfg.Clock.Now()
+	// handle request
+	c.Next()
+	// build log
+	stop := cfg.Clock.Now()
+	// Dump captured request
+	if cp := matchCapture(l.captures, c); cp != nil {
+		if err := cp.dump(c, stop); err != nil {
+			l.diag(LevelError, "writing capture: %v", err)
+		}
+	}
+	// Export span
+	if cfg.Tracer != nil {
+		cfg.Tracer.Export(newSpan(c, start, stop))
+	}
+	// Count response in cache report
+	if l.cache != nil {
+		l.cache.add(routePath(c), c.Fasthttp.Response.StatusCode(), stop, l)
+	}
+	// Count response in throttle report
+	if l.throttles != nil {
+		l.throttles.add(c.IP(), c.Fasthttp.Response.StatusCode(), stop, l)
+	}
+	// Count request in rollup
+	if l.rollup != nil {
+		var ex exemplar
+		if cfg.RequestID {
+			ex = exemplar{traceID: Trace(c).TraceID, requestID: RequestID(c), time: stop}
+		}
+		l.rollup.add(rollupKey{c.Method(), routePath(c), c.Fasthttp.Response.StatusCode()}, stop.Sub(start), ex)
+		if cfg.RollupOnly {
+			return
+		}
+	}
+	// Skip muted entries
+	if l.mutes.muted(c, stop) {
+		return
+	}
+	// Skip entries below the level or sample rate set at runtime
+	p := cfg.Priority(c, stop.Sub(start))
+	pol := l.policy()
+	if !pol.allows(c, p, cfg.SampleSeed) {
+		return
+	}
+	// Skip repeated entries
+	if l.errs != nil && p == PriorityError {
+		if !l.errs.add(cfg.AggregateKey(c), traceID(c), stop, l) {
+			return
+		}
+	} else if l.first != nil && !l.first.allow(routeStatus(c), traceID(c), stop, l) {
+		return
+	}
+	// Number entry, with Async entries are queued in the order of their
+	// numbers
+	ordered := l.numbered && l.async != nil
+	if ordered {
+		l.async.order.Lock()
+		defer func() {
+			if ordered {
+				l.async.order.Unlock()
+			}
+		}()
+	}
+	if l.numbered {
+		l.number(c)
+	}
+	// Run processors
+	var e *Entry
+	if l.entries {
+		e = l.newEntry(c, start, stop, p)
+		defer releaseEntry(e)
+		pol.redactEntry(e)
+		if !process(cfg.Processors, e) {
+			return
+		}
+	}
+	// Get new buffer
+	buf := l.buffer()
+	l.encode(buf, l.tmpl, cfg.Encoder, c, e, start, stop)
+	// Enforce output statuses and route budget
+	status := c.Fasthttp.Response.StatusCode()
+	if inStatuses(cfg.OutputStatuses, status) {
+		if b := matchBudget(l.budgets, c.Path()); b == nil || b.allow(buf.Len(), stop, l) {
+			if l.async != nil && l.enqueue(buf, p) {
+				// The buffer belongs to the background writer now
+				buf = l.buffer()
+			} else if _, err := l.out.writeEntry(e, buf.Bytes()); err != nil {
+				l.diag(LevelError, "writing entry: %v", err)
+			}
+		}
+	}
+	if ordered {
+		l.async.order.Unlock()
+		ordered = false
+	}
+	// Write to further sinks
+	for _, s := range l.sinks {
+		if !s.allow(p, status, traceID(c), cfg.SampleSeed) {
+			continue
+		}
+		se := e
+		if e != nil && len(s.Processors) > 0 {
+			se = acquireEntry()
+			e.copyTo(se)
+			if !process(s.Processors, se) {
+				releaseEntry(se)
+				continue
+			}
+		}
+		buf.Reset()
+		l.encode(buf, s.tmpl, s.Encoder, c, se, start, stop)
+		if _, err := s.out.writeEntry(se, buf.Bytes()); err != nil {
+			l.diag(LevelError, "writing entry to %s: %v", s.Name, err)
+		}
+		if se != e {
+			releaseEntry(se)
+		}
+	}
+	bytebufferpool.Put(buf)
+}
+
+// tags lists the tags of the formats, encoders, entry writers and
+// processors of the output and sinks
+func (l *Logger) tags() []string {
+	var tmpls []*fasttemplate.Template
+	var tags []string
+	add := func(tmpl *fasttemplate.Template, enc Encoder, w io.Writer) {
+		if enc == nil {
+			tmpls = append(tmpls, tmpl)
+		} else if t, ok := enc.(interface{ Tags() []string }); ok {
+			tags = append(tags, t.Tags()...)
+		}
+		if t, ok := w.(interface{ Tags() []string }); ok && isEntryWriter(w) {
+			tags = append(tags, t.Tags()...)
+		}
+	}
+	processors := func(ps []Processor) {
+		for _, p := range ps {
+			if t, ok := p.(interface{ Tags() []string }); ok {
+				tags = append(tags, t.Tags()...)
 			}
+		}
+	}
+	add(l.tmpl, l.cfg.Encoder, l.cfg.Output)
+	processors(l.cfg.Processors)
+	for _, s := range l.sinks {
+		add(s.tmpl, s.Encoder, s.Output)
+		processors(s.Processors)
+	}
+	result := templateTags(tmpls...)
+	seen := make(map[string]bool)
+	for _, tag := range result {
+		seen[tag] = true
+	}
+	for _, tag := range tags {
+		if !seen[tag] {
+			seen[tag] = true
+			result = append(result, tag)
+		}
+	}
+	return result
+}
+
+// uses reports whether the output or a sink renders tag
+func (l *Logger) uses(tag string) bool {
+	for _, t := range l.tags() {
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// render writes the entry of the request in the format of tmpl to buf
+func (l *Logger) render(buf *bytebufferpool.ByteBuffer, tmpl *fasttemplate.Template, c *fiber.Ctx, start, stop time.Time) {
+	_, err := tmpl.ExecuteFunc(buf, func(w io.Writer, tag string) (int, error) {
+		from := buf.Len()
+		n, err := l.tag(buf, c, start, stop, tag)
+		return l.layout(buf, from, tag, n, err)
+	})
+	if err != nil {
+		buf.WriteString(err.Error())
+	}
+}
+
+// layout folds and fits the value of tag written to buf since from
+func (l *Logger) layout(buf *bytebufferpool.ByteBuffer, from int, tag string, n int, err error) (int, error) {
+	if err == nil && l.cfg.Multiline != MultilineKeep {
+		n, err = fold(buf, from, l.cfg.Multiline)
+	}
+	if width, ok := l.cfg.Columns[tag]; ok {
+		return column(buf, from, width)
+	}
+	return n, err
+}
+
+// encode writes the entry with enc or in the format of tmpl to buf, from e
+// when the entry was built for processors or encoders
+func (l *Logger) encode(buf *bytebufferpool.ByteBuffer, tmpl *fasttemplate.Template, enc Encoder, c *fiber.Ctx, e *Entry, start, stop time.Time) {
+	if enc != nil {
+		if err := enc.Encode(buf, e); err != nil {
+			l.diag(LevelError, "encoding entry: %v", err)
+			buf.Reset()
+		}
+		return
+	}
+	if e != nil {
+		l.renderEntry(buf, tmpl, e)
+		return
+	}
+	l.render(buf, tmpl, c, start, stop)
+}
+
+// tag writes the value of a single tag to buf
+func (l *Logger) tag(buf *bytebufferpool.ByteBuffer, c *fiber.Ctx, start, stop time.Time, tag string) (int, error) {
+	cfg := &l.cfg
+	switch tag {
+	case strTime:
+		if _, ok := cfg.Clock.(systemClock); !ok {
+			return buf.WriteString(stop.Format(cfg.TimeFormat))
+		}
+		return buf.WriteString(l.timestamp)
+	case strReferer:
+		return buf.Write(c.Fasthttp.Request.Header.Peek(fiber.HeaderReferer))
+	case strProtocol:
+		return buf.WriteString(c.Protocol())
+	case strIp:
+		return buf.WriteString(c.IP())
+	case strIps:
+		return buf.Write(c.Fasthttp.Request.Header.Peek(fiber.HeaderXForwardedFor))
+	case strRequestLine:
+		return buf.WriteString(requestLine(c))
+	case strSeq:
+		return appendSeq(buf, c)
+	case strGrpcService:
+		service, _ := rpcMethod(c)
+		return buf.WriteString(service)
+	case strGrpcMethod:
+		_, method := rpcMethod(c)
+		return buf.WriteString(method)
+	case strGrpcStatus:
+		return buf.WriteString(grpcStatus(c))
+	case strDeps:
+		return appendDeps(buf, c)
+	case strLocalIp:
+		return buf.WriteString(c.Fasthttp.LocalIP().String())
+	case strHost:
+		return buf.WriteString(c.Hostname())
+	case strMethod:
+		if cfg.FiberCompat {
+			return appendFiberColored(buf, methodColor(c.Method()), padRight(c.Method(), 7))
+		}
+		if cfg.Human {
+			return buf.WriteString(padRight(c.Method(), 7))
+		}
+		return buf.WriteString(c.Method())
+	case strPath:
+		return buf.WriteString(c.Path())
+	case strUrl:
+		return buf.Write(c.Fasthttp.Request.Header.RequestURI())
+	case strUa:
+		return buf.Write(c.Fasthttp.Request.Header.UserAgent())
+	case strLatency:
+		if cfg.FiberCompat {
+			return appendFiberLatency(buf, stop.Sub(start))
+		}
+		if cfg.Human {
+			return buf.WriteString(padLeft(humanDuration(stop.Sub(start)), 9))
+		}
+		return appendDuration(buf, stop.Sub(start))
+	case strStatus:
+		if cfg.FiberCompat {
+			status := c.Fasthttp.Response.StatusCode()
+			return appendFiberColored(buf, statusColor(status), strconv.Itoa(status))
+		}
+		return appendInt(buf, c.Fasthttp.Response.StatusCode())
+	case strBody:
+		return buf.Write(c.Fasthttp.Request.Body())
+	case strResBody:
+		return buf.Write(c.Fasthttp.Response.Body())
+	case strPid:
+		return buf.WriteString(pid)
+	case strBytesReceived:
+		if cfg.Human {
+			return buf.WriteString(padLeft(humanBytes(len(c.Fasthttp.Request.Body())), 8))
+		}
+		return appendInt(buf, len(c.Fasthttp.Request.Body()))
+	case strBytesSent:
+		if cfg.Human {
+			return buf.WriteString(padLeft(humanBytes(len(c.Fasthttp.Response.Body())), 8))
+		}
+		return appendInt(buf, len(c.Fasthttp.Response.Body()))
+	case strRoute:
+		return buf.WriteString(routePath(c))
+	case strError:
+		if c.Error() != nil {
+			return buf.WriteString(c.Error().Error())
+		}
+	case strErrorType:
+		return buf.WriteString(newErrorInfo(c.Error(), cfg.ErrorClassifier).Type)
+	case strErrorCode:
+		return buf.WriteString(newErrorInfo(c.Error(), cfg.ErrorClassifier).Code)
+	case strErrorStack:
+		return buf.WriteString(newErrorInfo(c.Error(), cfg.ErrorClassifier).Stack)
+	case strRetriable:
+		if c.Error() != nil {
+			return buf.WriteString(strconv.FormatBool(newErrorInfo(c.Error(), cfg.ErrorClassifier).Retriable))
+		}
+	case strUpstreamAddr:
+		return buf.WriteString(upstreamAddr(c))
+	case strUpstreamStatus:
+		return buf.WriteString(upstreamStatus(c))
+	case strUpstreamLatency:
+		return buf.WriteString(upstreamLatency(c))
+	case strAttempts:
+		return buf.WriteString(attempts(c))
+	case strRetried:
+		return buf.WriteString(retriedUpstreams(c))
+	case strIpHostname:
+		// Hostnames are resolved for the tags found in the formats only
+		if l.rdns == nil {
 			return 0, nil
-		})
-		if err != nil {
-			buf.WriteString(err.Error())
 		}
-		if _, err := cfg.Output.Write(buf.Bytes()); err != nil {
-			fmt.Println(err)
+		return buf.WriteString(l.rdns.hostname(c.IP()))
+	case strIpType:
+		return buf.WriteString(ipType(c.Fasthttp.RemoteIP(), l.cloud))
+	case strStatusSymbol:
+		if l.symbols {
+			return buf.WriteString(statusSymbol(c.Fasthttp.Response.StatusCode(), cfg.Priority(c, stop.Sub(start))))
+		}
+	case strRequestID:
+		return buf.WriteString(RequestID(c))
+	case strTraceID:
+		return buf.WriteString(Trace(c).TraceID)
+	case strSpanID:
+		return buf.WriteString(Trace(c).SpanID)
+	case strPriority:
+		return buf.WriteString(cfg.Priority(c, stop.Sub(start)).String())
+	case strConditional:
+		res := &c.Fasthttp.Response.Header
+		return buf.WriteString(conditional(c.Get(fiber.HeaderIfNoneMatch), string(res.Peek(fiber.HeaderETag)),
+			c.Get(fiber.HeaderIfModifiedSince), string(res.Peek(fiber.HeaderLastModified)), res.StatusCode()))
+	case strRange:
+		return buf.Write(c.Fasthttp.Request.Header.Peek(fiber.HeaderRange))
+	case strContentRange:
+		return buf.Write(c.Fasthttp.Response.Header.Peek(fiber.HeaderContentRange))
+	case strLocation:
+		return buf.Write(c.Fasthttp.Response.Header.Peek(fiber.HeaderLocation))
+	case strRedirect:
+		status := c.Fasthttp.Response.StatusCode()
+		return buf.WriteString(strconv.FormatBool(status >= 300 && status < 400))
+	case strFilePath:
+		return buf.WriteString(filePath(c))
+	case strFileSize:
+		if size, ok := fileSize(c); ok {
+			if cfg.Human {
+				return buf.WriteString(padLeft(humanBytes(int(size)), 8))
+			}
+			return appendInt(buf, int(size))
+		}
+	case strDeployment:
+		if cfg.DeploymentHeader != "" {
+			if d := c.Get(cfg.DeploymentHeader); d != "" {
+				return buf.WriteString(d)
+			}
+		}
+		return buf.WriteString(cfg.Deployment)
+	case strFlags:
+		return buf.WriteString(flags(c))
+	case strVariant:
+		if cfg.Variant != "" && cfg.Variant != strVariant {
+			return l.tag(buf, c, start, stop, cfg.Variant)
+		}
+	case strIpAnon:
+		return buf.WriteString(anonIP(c.Fasthttp.RemoteIP()))
+	case strUaFamily:
+		return buf.WriteString(uaFamily(c.Get(fiber.HeaderUserAgent)))
+	case strRefererHost:
+		return buf.WriteString(refererHost(c.Get(fiber.HeaderReferer)))
+	case strLatencyBucket:
+		return buf.WriteString(latencyBucket(stop.Sub(start)))
+	case strLang:
+		return buf.WriteString(lang(c.Get(fiber.HeaderAcceptLanguage)))
+	case strReplayHeaders:
+		return replayHeaders(buf, c, cfg.ReplayHeaders)
+	case strDeadline:
+		return buf.WriteString(timeout(c, start))
+	case strTimedOut:
+		return buf.WriteString(timedOut(c, stop))
+	case strRetryAfter:
+		return buf.Write(c.Fasthttp.Response.Header.Peek(fiber.HeaderRetryAfter))
+	case strThrottled:
+		return buf.WriteString(strconv.FormatBool(c.Fasthttp.Response.StatusCode() == fiber.StatusTooManyRequests))
+	case strStatusColor:
+		if cfg.Colors {
+			return buf.WriteString(statusColor(c.Fasthttp.Response.StatusCode()))
+		}
+	default:
+		switch {
+		case strings.HasPrefix(tag, strHeader):
+			if key, ok := l.headers[tag]; ok {
+				return buf.Write(c.Fasthttp.Request.Header.PeekBytes(key))
+			}
+			return buf.Write(c.Fasthttp.Request.Header.Peek(tag[7:]))
+		case strings.HasPrefix(tag, strQuery):
+			return buf.Write(c.Fasthttp.QueryArgs().Peek(tag[6:]))
+		case strings.HasPrefix(tag, strForm):
+			return buf.Write(c.Fasthttp.FormValue(tag[5:]))
+		case strings.HasPrefix(tag, strCookie):
+			return buf.Write(c.Fasthttp.Request.Header.Cookie(tag[7:]))
+		case strings.HasPrefix(tag, strBaggage):
+			return buf.WriteString(baggage(c.Get(HeaderBaggage), tag[8:]))
+		case strings.HasPrefix(tag, strFlag):
+			return buf.WriteString(flag(c, tag[5:]))
+		case strings.HasPrefix(tag, strColor):
+			if cfg.Colors {
+				return buf.WriteString(colors[tag[6:]])
+			}
+		case cfg.FiberCompat && contains(fiberColors, tag):
+			return buf.WriteString(colors[tag])
+		case strings.HasPrefix(tag, strClf):
+			return l.clf(buf, c, start, stop, tag[4:])
+		case strings.HasPrefix(tag, strLocals):
+			switch v := c.Locals(tag[7:]).(type) {
+			case nil:
+			case string:
+				return buf.WriteString(v)
+			default:
+				return fmt.Fprint(buf, v)
+			}
 		}
-		bytebufferpool.Put(buf)
 	}
+	return 0, nil
+}
+
+// routePath returns the registered route path or the request path
+func routePath(c *fiber.Ctx) string {
+	if route := c.Route(); route != nil {
+		return route.Path
+	}
+	return c.Path()
+}
+
+// routeStatus returns the registered route path and response status
+func routeStatus(c *fiber.Ctx) string {
+	return routePath(c) + " " + strconv.Itoa(c.Fasthttp.Response.StatusCode())
 }
@@ -1,7 +1,11 @@
 package logger
 
 import (
+	"io/ioutil"
 	"testing"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/fasthttp"
 )
 
 // go test -v ./... -run=^$ -bench=Benchmark_Logger -benchmem -count=3
@@ -11,3 +15,54 @@ func Benchmark_Logger(b *testing.B) {
 
 	}
 }
+
+func Benchmark_Logger_entries(b *testing.B) {
+	l := NewLogger(Config{
+		Format: FormatJSON,
+		Fields: []string{"ua", "header:X-Tenant"},
+		Output: ioutil.Discard,
+	})
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/users/1")
+	fctx.Request.Header.SetUserAgent("bench")
+	fctx.Request.Header.Set("X-Tenant", "acme")
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		c := fiber.AcquireCtx(fctx)
+		l.Handle(c)
+		fiber.ReleaseCtx(c)
+	}
+}
+
+func Benchmark_Logger_template(b *testing.B) {
+	l := NewLogger(Config{
+		Format: "${status} ${latency} ${bytesSent} ${bytesReceived}\n",
+		Output: ioutil.Discard,
+	})
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/users/1")
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		c := fiber.AcquireCtx(fctx)
+		l.Handle(c)
+		fiber.ReleaseCtx(c)
+	}
+}
+
+func Benchmark_Logger_body(b *testing.B) {
+	l := NewLogger(Config{
+		Format: "${header:X-Tenant} ${ua} ${body}\n",
+		Output: ioutil.Discard,
+	})
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/upload")
+	fctx.Request.Header.SetUserAgent("bench")
+	fctx.Request.Header.Set("X-Tenant", "acme")
+	fctx.Request.SetBody(make([]byte, 64*1024))
+	b.ReportAllocs()
+	for n := 0; n < b.N; n++ {
+		c := fiber.AcquireCtx(fctx)
+		l.Handle(c)
+		fiber.ReleaseCtx(c)
+	}
+}
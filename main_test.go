@@ -11,6 +11,7 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestNew_withRoutePath(t *testing.T) {
@@ -44,3 +45,183 @@ func TestNew_withRoutePath(t *testing.T) {
 		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
 	}
 }
+
+func TestNew_withPriority(t *testing.T) {
+	buf := &strings.Builder{}
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${status}=${priority} ",
+		Output: buf,
+	}))
+	app.Get("/ok", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(200)
+	})
+	app.Get("/fail", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(502)
+	})
+
+	for _, path := range []string{"/ok", "/fail"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	expectedOutput := "200=ok 502=error "
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestNew_withUpstream(t *testing.T) {
+	buf := &strings.Builder{}
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${upstreamAddr} ${upstreamStatus} ${upstreamLatency} ${attempts} ${retriedUpstreams}",
+		Output: buf,
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		AddAttempt(ctx, "10.0.0.2:8080")
+		AddAttempt(ctx, "10.0.0.1:8080")
+		SetUpstream(ctx, "10.0.0.1:8080", 502, 1500*time.Millisecond)
+		ctx.SendStatus(502)
+	})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "10.0.0.1:8080 502 1.5s 2 10.0.0.2:8080"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestNew_withRange(t *testing.T) {
+	buf := &strings.Builder{}
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${status} ${range} ${contentRange}",
+		Output: buf,
+	}))
+	app.Get("/video.mp4", func(ctx *fiber.Ctx) {
+		ctx.Set(fiber.HeaderContentRange, "bytes 1048576-2097151/73400320")
+		ctx.SendStatus(206)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/video.mp4", nil)
+	req.Header.Set("Range", "bytes=1048576-2097151")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "206 bytes=1048576-2097151 bytes 1048576-2097151/73400320"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestNew_withRedirect(t *testing.T) {
+	buf := &strings.Builder{}
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${path} ${redirect} ${location};",
+		Output: buf,
+	}))
+	app.Get("/old", func(ctx *fiber.Ctx) {
+		ctx.Redirect("/new", 301)
+	})
+	app.Get("/new", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(200)
+	})
+
+	for _, path := range []string{"/old", "/new"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	expectedOutput := "/old true /new;/new false ;"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestNew_withDeployment(t *testing.T) {
+	buf := &strings.Builder{}
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Format:           "${deployment};",
+		Output:           buf,
+		Deployment:       "stable",
+		DeploymentHeader: "X-Canary",
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(200)
+	})
+
+	for _, canary := range []string{"", "canary-7"} {
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.Header.Set("X-Canary", canary)
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	expectedOutput := "stable;canary-7;"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestNew_withVariant(t *testing.T) {
+	buf := &strings.Builder{}
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Format:  "${variant} ${locals:cart};",
+		Output:  buf,
+		Variant: "cookie:ab",
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		ctx.Locals("cart", 3)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Cookie", "ab=checkout-b")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "checkout-b 3;"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestNew_withColors(t *testing.T) {
+	buf := &strings.Builder{}
+
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${statusColor}${status}${color:reset} ${path}",
+		Output: buf,
+		Colors: true,
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(404)
+	})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "404 /"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %q, expected: %q", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,118 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+// Metrics is a handler exposing the rollup counters in the OpenMetrics text
+// format, so the logger can be scraped by Prometheus without its client
+// libraries. It requires Config.Rollup, quantiles cover the last interval.
+func (l *Logger) Metrics(c *fiber.Ctx) {
+	if l.rollup == nil {
+		c.Status(fiber.StatusNotFound).SendString("logger: metrics require Config.Rollup")
+		return
+	}
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	l.rollup.writeOpenMetrics(buf)
+	c.Set(fiber.HeaderContentType, "application/openmetrics-text; version=1.0.0; charset=utf-8")
+	c.SendBytes(append([]byte(nil), buf.B...))
+}
+
+// writeOpenMetrics writes the counters of all intervals so far
+func (r *rollup) writeOpenMetrics(w io.Writer) {
+	r.mu.Lock()
+	keys := make([]rollupKey, 0, len(r.totals))
+	totals := make(map[rollupKey]rollupTotal, len(r.totals))
+	for key, t := range r.totals {
+		keys = append(keys, key)
+		totals[key] = *t
+	}
+	r.mu.Unlock()
+	sort.Slice(keys, func(i, j int) bool {
+		if keys[i].Route != keys[j].Route {
+			return keys[i].Route < keys[j].Route
+		}
+		if keys[i].Method != keys[j].Method {
+			return keys[i].Method < keys[j].Method
+		}
+		return keys[i].Status < keys[j].Status
+	})
+
+	fmt.Fprint(w, "# TYPE http_requests counter\n# HELP http_requests Requests handled.\n")
+	for _, key := range keys {
+		fmt.Fprintf(w, "http_requests_total{%s} %d\n", labels(key), totals[key].count)
+	}
+	fmt.Fprint(w, "# TYPE http_request_duration_seconds summary\n# UNIT http_request_duration_seconds seconds\n# HELP http_request_duration_seconds Request latency.\n")
+	for _, key := range keys {
+		t := totals[key]
+		for i, q := range rollupQuantiles {
+			fmt.Fprintf(w, "http_request_duration_seconds{%s,quantile=\"%s\"} %s\n",
+				labels(key), strconv.FormatFloat(q, 'g', -1, 64), seconds(t.quantiles[i].Seconds()))
+		}
+		fmt.Fprintf(w, "http_request_duration_seconds_sum{%s} %s\n", labels(key), seconds(t.sum.Seconds()))
+		fmt.Fprintf(w, "http_request_duration_seconds_count{%s} %d\n", labels(key), t.count)
+	}
+	fmt.Fprint(w, "# TYPE http_request_latency_seconds histogram\n# UNIT http_request_latency_seconds seconds\n# HELP http_request_latency_seconds Request latency.\n")
+	for _, key := range keys {
+		t := totals[key]
+		var cumulative int64
+		for i := range t.buckets {
+			le := "+Inf"
+			if i < len(rollupBuckets) {
+				le = seconds(rollupBuckets[i].Seconds())
+			}
+			cumulative += t.buckets[i]
+			fmt.Fprintf(w, "http_request_latency_seconds_bucket{%s,le=\"%s\"} %d%s\n", labels(key), le, cumulative, t.exemplars[i])
+		}
+		fmt.Fprintf(w, "http_request_latency_seconds_sum{%s} %s\n", labels(key), seconds(t.sum.Seconds()))
+		fmt.Fprintf(w, "http_request_latency_seconds_count{%s} %d\n", labels(key), t.count)
+	}
+	fmt.Fprint(w, "# EOF\n")
+}
+
+// labelEscaper escapes OpenMetrics label values
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func labels(key rollupKey) string {
+	return `method="` + labelEscaper.Replace(key.Method) +
+		`",route="` + labelEscaper.Replace(key.Route) +
+		`",status="` + strconv.Itoa(key.Status) + `"`
+}
+
+// exemplarLabels is the most UTF-8 characters of the names and values of the
+// labels of an exemplar allowed by OpenMetrics
+const exemplarLabels = 128
+
+// String formats the exemplar as a suffix of a bucket sample, empty without
+// ids. A request id that would exceed exemplarLabels is left out
+func (e exemplar) String() string {
+	requestID := e.requestID
+	if utf8.RuneCountInString("trace_id"+e.traceID+"request_id"+requestID) > exemplarLabels {
+		requestID = ""
+	}
+	if e.traceID == "" && requestID == "" {
+		return ""
+	}
+	var ids []string
+	if e.traceID != "" {
+		ids = append(ids, `trace_id="`+labelEscaper.Replace(e.traceID)+`"`)
+	}
+	if requestID != "" {
+		ids = append(ids, `request_id="`+labelEscaper.Replace(requestID)+`"`)
+	}
+	return " # {" + strings.Join(ids, ",") + "} " + seconds(e.latency.Seconds()) +
+		" " + strconv.FormatFloat(float64(e.time.UnixNano())/1e9, 'f', 3, 64)
+}
+
+func seconds(s float64) string {
+	return strconv.FormatFloat(s, 'g', -1, 64)
+}
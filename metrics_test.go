@@ -0,0 +1,63 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRollup_writeOpenMetrics(t *testing.T) {
+	buf := &strings.Builder{}
+	r := newRollup(time.Minute)
+	key := rollupKey{Method: "GET", Route: `/a"b`, Status: 200}
+	r.add(key, 100*time.Millisecond, exemplar{})
+	r.add(key, 300*time.Millisecond, exemplar{traceID: "4bf92f3577b34da6a3ce929d0e0e4736", requestID: "req-1", time: time.Unix(1600000000, 0)})
+	r.flush(ioutil.Discard, time.Now())
+	r.writeOpenMetrics(buf)
+
+	expectedOutput := `# TYPE http_requests counter
+# HELP http_requests Requests handled.
+http_requests_total{method="GET",route="/a\"b",status="200"} 2
+# TYPE http_request_duration_seconds summary
+# UNIT http_request_duration_seconds seconds
+# HELP http_request_duration_seconds Request latency.
+http_request_duration_seconds{method="GET",route="/a\"b",status="200",quantile="0.5"} 0.3
+http_request_duration_seconds{method="GET",route="/a\"b",status="200",quantile="0.9"} 0.3
+http_request_duration_seconds{method="GET",route="/a\"b",status="200",quantile="0.99"} 0.3
+http_request_duration_seconds_sum{method="GET",route="/a\"b",status="200"} 0.4
+http_request_duration_seconds_count{method="GET",route="/a\"b",status="200"} 2
+# TYPE http_request_latency_seconds histogram
+# UNIT http_request_latency_seconds seconds
+# HELP http_request_latency_seconds Request latency.
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="0.005"} 0
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="0.01"} 0
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="0.025"} 0
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="0.05"} 0
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="0.1"} 1
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="0.25"} 1
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="0.5"} 2 # {trace_id="4bf92f3577b34da6a3ce929d0e0e4736",request_id="req-1"} 0.3 1600000000.000
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="1"} 2
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="2.5"} 2
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="5"} 2
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="10"} 2
+http_request_latency_seconds_bucket{method="GET",route="/a\"b",status="200",le="+Inf"} 2
+http_request_latency_seconds_sum{method="GET",route="/a\"b",status="200"} 0.4
+http_request_latency_seconds_count{method="GET",route="/a\"b",status="200"} 2
+# EOF
+`
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestExemplar_String(t *testing.T) {
+	e := exemplar{traceID: "4bf92f3577b34da6a3ce929d0e0e4736", requestID: strings.Repeat("x", 100), time: time.Unix(1600000000, 0)}
+	if s := e.String(); strings.Contains(s, "request_id") || !strings.Contains(s, "trace_id") {
+		t.Errorf("Has: %s, expected: the request id left out over 128 characters", s)
+	}
+	e.traceID = ""
+	if s := e.String(); !strings.Contains(s, "request_id") {
+		t.Errorf("Has: %s, expected: the request id within 128 characters", s)
+	}
+}
@@ -0,0 +1,194 @@
+package logger
+
+import (
+	"bytes"
+	"errors"
+	"os"
+	"sync"
+	"time"
+)
+
+// errMmapUnsupported is returned by NewMmapWriter on systems without mmap
+// support
+var errMmapUnsupported = errors.New("logger: mmap writer is not supported on this system")
+
+// errMmapClosed is returned for writes after Close
+var errMmapClosed = errors.New("logger: mmap writer is closed")
+
+// MmapConfig configures an MmapWriter
+type MmapConfig struct {
+	// Size is the step the file is grown and mapped by
+	// Optional. Default: 64 << 20 (64 MiB)
+	Size int
+	// SyncInterval is how often written lines are synced to disk
+	// Optional. Default: 1 * time.Second
+	SyncInterval time.Duration
+}
+
+// MmapWriter is an experimental append-only writer copying lines into a
+// memory-mapped file, so a write is a copy without a system call. The file
+// is grown by Size and synced every SyncInterval, Close truncates it to the
+// written lines. When the process crashed the file ends with unused space
+// and possibly a torn line, which are truncated when it is opened again.
+// Writes are expected to be lines ending with a newline. Only Linux is
+// supported
+type MmapWriter struct {
+	cfg    MmapConfig
+	f      *os.File
+	mu     sync.Mutex
+	data   []byte
+	off    int
+	synced int
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewMmapWriter opens or creates the file at path and maps it, truncating
+// the torn tail of a previous run
+func NewMmapWriter(path string, cfg MmapConfig) (*MmapWriter, error) {
+	if !mmapSupported {
+		return nil, errMmapUnsupported
+	}
+	if cfg.Size <= 0 {
+		cfg.Size = 64 << 20
+	}
+	if cfg.SyncInterval <= 0 {
+		cfg.SyncInterval = time.Second
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	w := &MmapWriter{
+		cfg:    cfg,
+		f:      f,
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	if err := w.remap(int(info.Size()) + cfg.Size); err != nil {
+		f.Close()
+		return nil, err
+	}
+	w.off = recoverTail(w.data[:info.Size()])
+	tail := w.data[w.off:info.Size()]
+	for i := range tail {
+		tail[i] = 0
+	}
+	w.synced = w.off
+	go w.run()
+	return w, nil
+}
+
+// recoverTail returns the end of the last complete line in data, dropping
+// the unused space and torn line a crash leaves behind
+func recoverTail(data []byte) int {
+	return bytes.LastIndexByte(data, '\n') + 1
+}
+
+// remap grows the file to size and maps it
+func (w *MmapWriter) remap(size int) error {
+	if w.data != nil {
+		if err := w.sync(); err != nil {
+			return err
+		}
+		if err := munmap(w.data); err != nil {
+			return err
+		}
+		w.data = nil
+	}
+	if err := w.f.Truncate(int64(size)); err != nil {
+		return err
+	}
+	data, err := mmap(w.f, size)
+	if err != nil {
+		return err
+	}
+	w.data = data
+	return nil
+}
+
+// Write copies p to the mapping, growing the file by Size when it is full
+func (w *MmapWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.data == nil {
+		return 0, errMmapClosed
+	}
+	if w.off+len(p) > len(w.data) {
+		size := len(w.data)
+		for w.off+len(p) > size {
+			size += w.cfg.Size
+		}
+		if err := w.remap(size); err != nil {
+			return 0, err
+		}
+	}
+	w.off += copy(w.data[w.off:], p)
+	return len(p), nil
+}
+
+// Sync writes the lines written since the last sync to disk
+func (w *MmapWriter) Sync() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.sync()
+}
+
+func (w *MmapWriter) sync() error {
+	if w.data == nil || w.synced == w.off {
+		return nil
+	}
+	// msync takes a page aligned address
+	from := w.synced &^ (os.Getpagesize() - 1)
+	if err := msync(w.data[from:w.off]); err != nil {
+		return err
+	}
+	w.synced = w.off
+	return nil
+}
+
+// run syncs every SyncInterval until Close
+func (w *MmapWriter) run() {
+	ticker := time.NewTicker(w.cfg.SyncInterval)
+	defer ticker.Stop()
+	defer close(w.closed)
+	for {
+		select {
+		case <-ticker.C:
+			w.Sync()
+		case <-w.done:
+			return
+		}
+	}
+}
+
+// Close syncs and unmaps the file and truncates it to the written lines
+func (w *MmapWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.closed
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.data == nil {
+		return nil
+	}
+	err := w.sync()
+	if e := munmap(w.data); err == nil {
+		err = e
+	}
+	w.data = nil
+	if e := w.f.Truncate(int64(w.off)); err == nil {
+		err = e
+	}
+	if e := w.f.Close(); err == nil {
+		err = e
+	}
+	return err
+}
@@ -0,0 +1,20 @@
+//go:build !linux
+// +build !linux
+
+package logger
+
+import "os"
+
+const mmapSupported = false
+
+func mmap(f *os.File, size int) ([]byte, error) {
+	return nil, errMmapUnsupported
+}
+
+func munmap(b []byte) error {
+	return errMmapUnsupported
+}
+
+func msync(b []byte) error {
+	return errMmapUnsupported
+}
@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestMmapWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access.log")
+
+	w, err := NewMmapWriter(path, MmapConfig{Size: 16})
+	if err == errMmapUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	// The second line grows the file
+	for _, line := range []string{"GET /a 200\n", "GET /users/1 200\n"} {
+		if _, err := w.Write([]byte(line)); err != nil {
+			t.Errorf("Has: %v, expected: nil", err)
+		}
+	}
+	if err := w.Close(); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	if data, _ := ioutil.ReadFile(path); string(data) != "GET /a 200\nGET /users/1 200\n" {
+		t.Errorf("Has: %q, expected: the lines without unused space", data)
+	}
+	if _, err := w.Write([]byte("GET /b 200\n")); err != errMmapClosed {
+		t.Errorf("Has: %v, expected: %v", err, errMmapClosed)
+	}
+}
+
+func TestMmapWriter_recover(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "access.log")
+	// A crash leaves a torn line and unused space
+	crashed := append([]byte("GET /a 200\nGET /b 200\nGET /c"), make([]byte, 64)...)
+	if err := ioutil.WriteFile(path, crashed, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	w, err := NewMmapWriter(path, MmapConfig{})
+	if err == errMmapUnsupported {
+		t.Skip(err)
+	}
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	w.Write([]byte("GET /d 200\n"))
+	if err := w.Sync(); err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	w.Close()
+	if data, _ := ioutil.ReadFile(path); string(data) != "GET /a 200\nGET /b 200\nGET /d 200\n" {
+		t.Errorf("Has: %q, expected: the torn tail truncated", data)
+	}
+}
@@ -0,0 +1,54 @@
+package logger
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// Multiline controls how values spanning several lines, like stack traces
+// and bodies, are written
+type Multiline int
+
+// Multiline modes
+const (
+	// MultilineKeep writes values as they are
+	MultilineKeep Multiline = iota
+	// MultilineFold escapes line breaks as \n and \r, so every entry is a
+	// single line for collectors expecting one line per event
+	MultilineFold
+	// MultilineIndent writes every further line of a value on an indented
+	// continuation line, for reading stacks
+	MultilineIndent
+)
+
+// fold rewrites the line breaks of what was written to buf since from
+func fold(buf *bytebufferpool.ByteBuffer, from int, mode Multiline) (int, error) {
+	value := buf.B[from:]
+	if mode == MultilineKeep || bytes.IndexAny(value, "\r\n") < 0 {
+		return len(value), nil
+	}
+	s := string(value)
+	buf.B = buf.B[:from]
+	switch mode {
+	case MultilineFold:
+		return foldEscaper.WriteString(buf, s)
+	default:
+		return indentReplacer.WriteString(buf, trimNewlines(s))
+	}
+}
+
+var (
+	foldEscaper    = strings.NewReplacer("\r", `\r`, "\n", `\n`)
+	indentReplacer = strings.NewReplacer("\r\n", "\n\t", "\n", "\n\t")
+)
+
+// trimNewlines strips trailing line breaks, which would leave an empty
+// continuation line
+func trimNewlines(s string) string {
+	for len(s) > 0 && (s[len(s)-1] == '\n' || s[len(s)-1] == '\r') {
+		s = s[:len(s)-1]
+	}
+	return s
+}
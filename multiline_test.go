@@ -0,0 +1,25 @@
+package logger
+
+import (
+	"testing"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+func TestFold(t *testing.T) {
+	stack := "panic: boom\ngoroutine 1:\r\nmain.main()\n"
+	for mode, expected := range map[Multiline]string{
+		MultilineKeep:   "error=" + stack,
+		MultilineFold:   `error=panic: boom\ngoroutine 1:\r\nmain.main()\n`,
+		MultilineIndent: "error=panic: boom\n\tgoroutine 1:\n\tmain.main()",
+	} {
+		buf := bytebufferpool.Get()
+		buf.WriteString("error=")
+		buf.WriteString(stack)
+		fold(buf, 6, mode)
+		if buf.String() != expected {
+			t.Errorf("Has: %q, expected: %q", buf.String(), expected)
+		}
+		bytebufferpool.Put(buf)
+	}
+}
@@ -0,0 +1,72 @@
+package logger
+
+import (
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// mute is a single Mute call
+type mute struct {
+	until   time.Time
+	matcher func(*fiber.Ctx) bool
+	count   int64
+}
+
+// mutes holds the active mutes of a Logger
+type mutes struct {
+	mu   sync.RWMutex
+	list []*mute
+}
+
+// muted reports whether an active mute matches the request and counts it
+func (m *mutes) muted(c *fiber.Ctx, now time.Time) bool {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	for _, mt := range m.list {
+		if now.Before(mt.until) && (mt.matcher == nil || mt.matcher(c)) {
+			atomic.AddInt64(&mt.count, 1)
+			return true
+		}
+	}
+	return false
+}
+
+// remove deactivates mt and reports whether it was still active
+func (m *mutes) remove(mt *mute) bool {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range m.list {
+		if m.list[i] == mt {
+			m.list = append(m.list[:i], m.list[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// Mute silences the entries matching matcher for d, a nil matcher mutes all
+// entries. When the mute ends a note with the number of muted entries is
+// written to the output. The returned function ends the mute early.
+func (l *Logger) Mute(d time.Duration, matcher func(c *fiber.Ctx) bool) (unmute func()) {
+	start := l.cfg.Clock.Now()
+	mt := &mute{until: start.Add(d), matcher: matcher}
+	l.mutes.mu.Lock()
+	l.mutes.list = append(l.mutes.list, mt)
+	l.mutes.mu.Unlock()
+	unmute = func() {
+		if !l.mutes.remove(mt) {
+			return
+		}
+		now := l.cfg.Clock.Now()
+		d, muted := now.Sub(start).Round(time.Second), atomic.LoadInt64(&mt.count)
+		l.writeSummary(&Entry{Time: now, Fields: []Field{{"summary", "mute"}, {"duration", d.String()}, {"muted", muted}}},
+			fmt.Sprintf("unmuted after %s, muted %d entries", d, muted))
+	}
+	l.diag(LevelInfo, "muted for %s", d)
+	time.AfterFunc(d, unmute)
+	return unmute
+}
@@ -0,0 +1,40 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestLogger_Mute(t *testing.T) {
+	buf := &strings.Builder{}
+	l := NewLogger(Config{
+		Format:      "${path} ",
+		Output:      buf,
+		Diagnostics: ioutil.Discard,
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(200)
+	})
+
+	unmute := l.Mute(time.Hour, func(c *fiber.Ctx) bool {
+		return c.Path() == "/noisy"
+	})
+	for _, path := range []string{"/noisy", "/quiet", "/noisy"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+	unmute()
+
+	if !strings.HasPrefix(buf.String(), "/quiet ") || !strings.HasSuffix(buf.String(), "muted 2 entries\n") {
+		t.Errorf("Has: %s, expected: /quiet followed by a note of 2 muted entries", buf.String())
+	}
+}
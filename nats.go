@@ -0,0 +1,392 @@
+package logger
+
+import (
+	"bufio"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// errNATSDropped is returned for entries dropped while the queue is full
+var errNATSDropped = errors.New("logger: nats queue full, entry dropped")
+
+// NATSConfig configures a NATSWriter
+type NATSConfig struct {
+	// Address of the NATS server
+	// Optional. Default: "127.0.0.1:4222"
+	Address string
+	// TLS upgrades the connection to TLS after the server's INFO
+	// Optional. Default: nil
+	TLS *tls.Config
+	// User and Password authenticate the connection
+	// Optional. Default: ""
+	User, Password string
+	// Token authenticates the connection
+	// Optional. Default: ""
+	Token string
+	// Subject the lines are published to
+	// Required
+	Subject string
+	// JetStream waits for the stream to acknowledge every line, so lines
+	// are persisted before the next one is published
+	// Optional. Default: false
+	JetStream bool
+	// AckTimeout is the longest wait for a JetStream acknowledgement, the
+	// line is dropped after it
+	// Optional. Default: 5 * time.Second
+	AckTimeout time.Duration
+	// QueueSize is the number of lines queued for publishing
+	// Optional. Default: 1024
+	QueueSize int
+	// Block makes writes wait while the queue is full, e.g. while the
+	// connection is down, instead of dropping the line
+	// Optional. Default: false
+	Block bool
+	// ReconnectWait is the wait between connection attempts
+	// Optional. Default: 1 * time.Second
+	ReconnectWait time.Duration
+}
+
+// natsAck is a message received on the inbox of JetStream acks
+type natsAck struct {
+	subject string
+	payload []byte
+}
+
+// NATSWriter publishes lines to a NATS subject, optionally with JetStream
+// acknowledgements. Writes are queued and published in the background, a
+// lost connection is reconnected every ReconnectWait and the line retried
+type NATSWriter struct {
+	cfg    NATSConfig
+	queue  chan []byte
+	mu     sync.Mutex
+	conn   net.Conn
+	w      *bufio.Writer
+	inbox  string
+	seq    int
+	acks   chan natsAck
+	done   chan struct{}
+	closed chan struct{}
+	once   sync.Once
+}
+
+// NewNATSWriter connects to the NATS server of cfg
+func NewNATSWriter(cfg NATSConfig) (*NATSWriter, error) {
+	if cfg.Subject == "" {
+		return nil, errors.New("logger: nats subject is required")
+	}
+	if cfg.Address == "" {
+		cfg.Address = "127.0.0.1:4222"
+	}
+	if cfg.AckTimeout <= 0 {
+		cfg.AckTimeout = 5 * time.Second
+	}
+	if cfg.QueueSize <= 0 {
+		cfg.QueueSize = 1024
+	}
+	if cfg.ReconnectWait <= 0 {
+		cfg.ReconnectWait = time.Second
+	}
+	w := &NATSWriter{
+		cfg:    cfg,
+		queue:  make(chan []byte, cfg.QueueSize),
+		acks:   make(chan natsAck, 1),
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	if err := w.connect(); err != nil {
+		return nil, err
+	}
+	go w.run()
+	return w, nil
+}
+
+// connect dials the server, authenticates and subscribes to the inbox of
+// JetStream acks
+func (w *NATSWriter) connect() error {
+	conn, err := net.DialTimeout("tcp", w.cfg.Address, 5*time.Second)
+	if err != nil {
+		return err
+	}
+	conn.SetDeadline(time.Now().Add(5 * time.Second))
+	r := bufio.NewReader(conn)
+	line, err := r.ReadString('\n')
+	if err != nil {
+		conn.Close()
+		return err
+	}
+	if !strings.HasPrefix(line, "INFO ") {
+		conn.Close()
+		return errors.New("logger: nats server sent " + strings.TrimSpace(line) + " instead of INFO")
+	}
+	if w.cfg.TLS != nil {
+		tlsConn := tls.Client(conn, w.cfg.TLS)
+		if err := tlsConn.Handshake(); err != nil {
+			conn.Close()
+			return err
+		}
+		conn = tlsConn
+		r = bufio.NewReader(conn)
+	}
+	options, _ := json.Marshal(struct {
+		Verbose     bool   `json:"verbose"`
+		Pedantic    bool   `json:"pedantic"`
+		TLSRequired bool   `json:"tls_required"`
+		Name        string `json:"name"`
+		Lang        string `json:"lang"`
+		Protocol    int    `json:"protocol"`
+		User        string `json:"user,omitempty"`
+		Pass        string `json:"pass,omitempty"`
+		Token       string `json:"auth_token,omitempty"`
+	}{
+		TLSRequired: w.cfg.TLS != nil,
+		Name:        "gofiber-logger",
+		Lang:        "go",
+		Protocol:    1,
+		User:        w.cfg.User,
+		Pass:        w.cfg.Password,
+		Token:       w.cfg.Token,
+	})
+	bw := bufio.NewWriter(conn)
+	bw.WriteString("CONNECT " + string(options) + "\r\nPING\r\n")
+	inbox := ""
+	if w.cfg.JetStream {
+		var id [8]byte
+		rand.Read(id[:])
+		inbox = "_INBOX." + hex.EncodeToString(id[:])
+		bw.WriteString("SUB " + inbox + ".* 1\r\n")
+	}
+	if err := bw.Flush(); err != nil {
+		conn.Close()
+		return err
+	}
+	// The server answers the PING once the connection is accepted
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			conn.Close()
+			return err
+		}
+		if strings.HasPrefix(line, "-ERR") {
+			conn.Close()
+			return errors.New("logger: nats: " + strings.TrimSpace(line[4:]))
+		}
+		if strings.HasPrefix(line, "PONG") {
+			break
+		}
+	}
+	conn.SetDeadline(time.Time{})
+	w.mu.Lock()
+	w.conn, w.w, w.inbox = conn, bw, inbox
+	w.mu.Unlock()
+	go w.read(conn, r)
+	return nil
+}
+
+// read answers the server's PINGs and passes JetStream acks on until the
+// connection is closed
+func (w *NATSWriter) read(conn net.Conn, r *bufio.Reader) {
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			return
+		}
+		switch {
+		case strings.HasPrefix(line, "PING"):
+			w.mu.Lock()
+			if w.conn == conn {
+				w.w.WriteString("PONG\r\n")
+				w.w.Flush()
+			}
+			w.mu.Unlock()
+		case strings.HasPrefix(line, "MSG "):
+			// MSG <subject> <sid> [reply-to] <#bytes>
+			fields := strings.Fields(line)
+			n, err := strconv.Atoi(fields[len(fields)-1])
+			if err != nil {
+				return
+			}
+			payload := make([]byte, n+2)
+			if _, err := io.ReadFull(r, payload); err != nil {
+				return
+			}
+			select {
+			case w.acks <- natsAck{fields[1], payload[:n]}:
+			default:
+			}
+		}
+	}
+}
+
+// Write queues a copy of p for publishing, a trailing newline is dropped.
+// While the queue is full it waits with Block or drops the line
+func (w *NATSWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	msg := append([]byte(nil), p...)
+	if w.cfg.Block {
+		select {
+		case w.queue <- msg:
+			return n, nil
+		case <-w.done:
+			return 0, errNATSDropped
+		}
+	}
+	select {
+	case w.queue <- msg:
+		return n, nil
+	default:
+		return 0, errNATSDropped
+	}
+}
+
+// run publishes the queued lines until Close, then publishes the rest as
+// long as the connection holds
+func (w *NATSWriter) run() {
+	defer close(w.closed)
+	for {
+		select {
+		case msg := <-w.queue:
+			w.send(msg)
+		case <-w.done:
+			for {
+				select {
+				case msg := <-w.queue:
+					if err := w.publish(msg); isNetError(err) {
+						w.disconnect()
+						return
+					}
+				default:
+					w.disconnect()
+					return
+				}
+			}
+		}
+	}
+}
+
+// send publishes msg, reconnecting and retrying it after network errors
+// until Close
+func (w *NATSWriter) send(msg []byte) {
+	for {
+		err := w.publish(msg)
+		if !isNetError(err) {
+			return
+		}
+		w.disconnect()
+		for {
+			select {
+			case <-time.After(w.cfg.ReconnectWait):
+			case <-w.done:
+				return
+			}
+			if w.connect() == nil {
+				break
+			}
+		}
+	}
+}
+
+// publish writes msg, flushed once the queue is empty or to wait for the
+// JetStream ack
+func (w *NATSWriter) publish(msg []byte) error {
+	w.mu.Lock()
+	if w.conn == nil {
+		w.mu.Unlock()
+		return io.ErrClosedPipe
+	}
+	reply := ""
+	if w.cfg.JetStream {
+		w.seq++
+		reply = w.inbox + "." + strconv.Itoa(w.seq) + " "
+	}
+	w.w.WriteString("PUB " + w.cfg.Subject + " " + reply + strconv.Itoa(len(msg)) + "\r\n")
+	w.w.Write(msg)
+	w.w.WriteString("\r\n")
+	var err error
+	if reply != "" || len(w.queue) == 0 {
+		err = w.w.Flush()
+	}
+	w.mu.Unlock()
+	if err != nil || reply == "" {
+		return err
+	}
+	return w.ack(strings.TrimSpace(reply))
+}
+
+// ack waits for the JetStream ack sent to reply
+func (w *NATSWriter) ack(reply string) error {
+	timeout := time.NewTimer(w.cfg.AckTimeout)
+	defer timeout.Stop()
+	for {
+		select {
+		case a := <-w.acks:
+			if a.subject != reply {
+				continue
+			}
+			var resp struct {
+				Error *struct {
+					Description string `json:"description"`
+				} `json:"error"`
+			}
+			if err := json.Unmarshal(a.payload, &resp); err != nil {
+				return err
+			}
+			if resp.Error != nil {
+				return errors.New("logger: jetstream: " + resp.Error.Description)
+			}
+			return nil
+		case <-timeout.C:
+			return errors.New("logger: jetstream ack timed out")
+		}
+	}
+}
+
+// isNetError reports whether err is a failure of the connection, after
+// which the line is retried on a new one
+func isNetError(err error) bool {
+	if err == nil {
+		return false
+	}
+	if err == io.ErrClosedPipe {
+		return true
+	}
+	_, ok := err.(net.Error)
+	return ok
+}
+
+func (w *NATSWriter) disconnect() {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn != nil {
+		w.conn.Close()
+		w.conn = nil
+	}
+}
+
+// Connected reports whether the writer holds a connection, for Health
+func (w *NATSWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn != nil
+}
+
+// Close publishes the queued lines while connected and closes the
+// connection
+func (w *NATSWriter) Close() error {
+	w.once.Do(func() {
+		close(w.done)
+	})
+	<-w.closed
+	return nil
+}
@@ -0,0 +1,131 @@
+package logger
+
+import (
+	"bufio"
+	"io"
+	"net"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+// natsServer accepts connections speaking enough of the NATS protocol for
+// NATSWriter, acknowledging publishes with a reply subject as JetStream
+func natsServer(t *testing.T) (net.Listener, chan string) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := make(chan string, 10)
+	go func() {
+		for {
+			conn, err := ln.Accept()
+			if err != nil {
+				return
+			}
+			go func() {
+				defer conn.Close()
+				conn.Write([]byte("INFO {\"server_id\":\"test\"}\r\n"))
+				r := bufio.NewReader(conn)
+				for {
+					line, err := r.ReadString('\n')
+					if err != nil {
+						return
+					}
+					fields := strings.Fields(line)
+					switch fields[0] {
+					case "PING":
+						conn.Write([]byte("PONG\r\n"))
+					case "PUB":
+						n, _ := strconv.Atoi(fields[len(fields)-1])
+						payload := make([]byte, n+2)
+						if _, err := io.ReadFull(r, payload); err != nil {
+							return
+						}
+						msgs <- fields[1] + " " + string(payload[:n])
+						if len(fields) == 4 {
+							ack := `{"stream":"ACCESS","seq":1}`
+							conn.Write([]byte("MSG " + fields[2] + " 1 " + strconv.Itoa(len(ack)) + "\r\n" + ack + "\r\n"))
+						}
+					}
+				}
+			}()
+		}
+	}()
+	return ln, msgs
+}
+
+func TestNATSWriter(t *testing.T) {
+	ln, msgs := natsServer(t)
+	defer ln.Close()
+	for _, jetStream := range []bool{false, true} {
+		nw, err := NewNATSWriter(NATSConfig{Address: ln.Addr().String(), Subject: "access", JetStream: jetStream, AckTimeout: time.Second})
+		if err != nil {
+			t.Fatalf("Has: %v, expected: nil", err)
+		}
+		if _, err := nw.Write([]byte("GET / 200\n")); err != nil {
+			t.Errorf("Has: %v, expected: nil", err)
+		}
+		if msg := <-msgs; msg != "access GET / 200" {
+			t.Errorf("Has: %q, expected: the line on subject access", msg)
+		}
+		if !nw.Connected() {
+			t.Errorf("Has: disconnected, expected: connected")
+		}
+		nw.Close()
+	}
+}
+
+func TestNATSWriter_reconnect(t *testing.T) {
+	ln, msgs := natsServer(t)
+	defer ln.Close()
+	nw, err := NewNATSWriter(NATSConfig{Address: ln.Addr().String(), Subject: "access", ReconnectWait: time.Millisecond})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer nw.Close()
+
+	nw.mu.Lock()
+	nw.conn.Close()
+	nw.mu.Unlock()
+	nw.Write([]byte("GET / 200\n"))
+	select {
+	case msg := <-msgs:
+		if msg != "access GET / 200" {
+			t.Errorf("Has: %q, expected: the line", msg)
+		}
+	case <-time.After(time.Second):
+		t.Errorf("Has: no message, expected: the line after reconnecting")
+	}
+}
+
+func TestNATSWriter_drop(t *testing.T) {
+	ln, _ := natsServer(t)
+	nw, err := NewNATSWriter(NATSConfig{Address: ln.Addr().String(), Subject: "access", QueueSize: 1, ReconnectWait: time.Hour})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer nw.Close()
+	// Take the server down, the first line waits to be retried and the
+	// second fills the queue
+	ln.Close()
+	nw.mu.Lock()
+	nw.conn.Close()
+	nw.mu.Unlock()
+	nw.Write([]byte("GET /a 200\n"))
+	deadline := time.Now().Add(time.Second)
+	for nw.Connected() && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	nw.Write([]byte("GET /b 200\n"))
+	if _, err := nw.Write([]byte("GET /c 200\n")); err != errNATSDropped {
+		t.Errorf("Has: %v, expected: %v", err, errNATSDropped)
+	}
+}
+
+func TestNewNATSWriter_invalid(t *testing.T) {
+	if _, err := NewNATSWriter(NATSConfig{}); err == nil {
+		t.Errorf("Has: nil, expected: an error without subject")
+	}
+}
@@ -0,0 +1,48 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+)
+
+// strPartial is the prefix of a named sub-format defined in Config.Partials
+const strPartial = "partial:"
+
+// expandPartials replaces each ${partial:<name>} in format with the named
+// format, partials may use other partials
+func expandPartials(format string, partials map[string]string) (string, error) {
+	return expand(format, partials, nil)
+}
+
+func expand(format string, partials map[string]string, stack []string) (string, error) {
+	const open = "${" + strPartial
+	var b strings.Builder
+	for {
+		i := strings.Index(format, open)
+		if i < 0 {
+			b.WriteString(format)
+			return b.String(), nil
+		}
+		j := strings.IndexByte(format[i:], '}')
+		if j < 0 {
+			return "", errors.New("logger: unclosed partial in " + format)
+		}
+		name := format[i+len(open) : i+j]
+		partial, ok := partials[name]
+		if !ok {
+			return "", errors.New("logger: unknown partial " + name)
+		}
+		for _, n := range stack {
+			if n == name {
+				return "", errors.New("logger: partial " + name + " includes itself")
+			}
+		}
+		expanded, err := expand(partial, partials, append(stack, name))
+		if err != nil {
+			return "", err
+		}
+		b.WriteString(format[:i])
+		b.WriteString(expanded)
+		format = format[i+j+1:]
+	}
+}
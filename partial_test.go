@@ -0,0 +1,27 @@
+package logger
+
+import (
+	"testing"
+)
+
+func TestExpandPartials(t *testing.T) {
+	partials := map[string]string{
+		"client":  "${ip} \"${ua}\" ${partial:referer}",
+		"referer": "${referer}",
+		"loop":    "${partial:loop}",
+	}
+	format, err := expandPartials("${status} ${partial:client}\n", partials)
+	if err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	expected := "${status} ${ip} \"${ua}\" ${referer}\n"
+	if format != expected {
+		t.Errorf("Has: %s, expected: %s", format, expected)
+	}
+
+	for _, format := range []string{"${partial:loop}", "${partial:missing}", "${partial:client"} {
+		if _, err := expandPartials(format, partials); err == nil {
+			t.Errorf("%s Has: nil, expected: error", format)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// clearers remove the core fields of an entry that can be left out
+var clearers = map[string]func(e *Entry){
+	"method": func(e *Entry) { e.Method = "" },
+	"path":   func(e *Entry) { e.Path = "" },
+	"route":  func(e *Entry) { e.Route = "" },
+	"ip":     func(e *Entry) { e.IP = "" },
+	"error":  func(e *Entry) { e.Err = nil },
+}
+
+// AllowFields returns a processor keeping only the listed fields, such as
+// {"status", "route", "latency"} for an analytics sink. Of the core fields
+// method, path, route, ip and error are removed unless listed
+func AllowFields(keys ...string) Processor {
+	allowed := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		allowed[key] = true
+	}
+	return ProcessorFunc(func(e *Entry) bool {
+		fields := e.Fields[:0]
+		for _, f := range e.Fields {
+			if allowed[f.Key] {
+				fields = append(fields, f)
+			}
+		}
+		e.Fields = fields
+		for key, clear := range clearers {
+			if !allowed[key] {
+				clear(e)
+			}
+		}
+		return true
+	})
+}
+
+// DenyFields returns a processor removing the listed fields, such as
+// {"body", "ip"} for a SIEM sink. The core fields method, path, route, ip and
+// error are removed as well when listed
+func DenyFields(keys ...string) Processor {
+	return ProcessorFunc(func(e *Entry) bool {
+		for _, key := range keys {
+			e.Delete(key)
+			if clear, ok := clearers[key]; ok {
+				clear(e)
+			}
+		}
+		return true
+	})
+}
+
+// DefaultFieldLimits bound the fields that grow with the request
+var DefaultFieldLimits = map[string]int{
+	"ua":       256,
+	"body":     4096,
+	"header:*": 1024,
+}
+
+// LimitFields returns a processor truncating string fields to a maximum
+// number of bytes, marking the cut with the number of bytes left out. Keys
+// ending in * match any suffix, "*" alone matches all fields
+func LimitFields(limits map[string]int) Processor {
+	return ProcessorFunc(func(e *Entry) bool {
+		for i := range e.Fields {
+			s, ok := e.Fields[i].Value.(string)
+			if !ok {
+				continue
+			}
+			if max, ok := fieldLimit(limits, e.Fields[i].Key); ok && len(s) > max {
+				e.Fields[i].Value = truncate(s, max)
+			}
+		}
+		return true
+	})
+}
+
+// fieldLimit returns the limit of key, exact keys take precedence over the
+// longest matching pattern
+func fieldLimit(limits map[string]int, key string) (int, bool) {
+	if max, ok := limits[key]; ok {
+		return max, true
+	}
+	limit, found, longest := 0, false, -1
+	for pattern, max := range limits {
+		if strings.HasSuffix(pattern, "*") && len(pattern) > longest && matchPath(pattern, key) {
+			limit, found, longest = max, true, len(pattern)
+		}
+	}
+	return limit, found
+}
+
+// truncate cuts s to max bytes at a rune boundary and appends a marker
+func truncate(s string, max int) string {
+	cut := max
+	for cut > 0 && !utf8.RuneStart(s[cut]) {
+		cut--
+	}
+	return s[:cut] + "…[+" + strconv.Itoa(len(s)-cut) + " bytes]"
+}
+
+// Kinds of fields CoerceFields converts
+var (
+	intFields      = []string{strStatus, strBytesSent, strBytesReceived, strFileSize, strUpstreamStatus, strAttempts, strSeq}
+	durationFields = []string{strLatency, strUpstreamLatency, strDeadline}
+	boolFields     = []string{strRetriable, strRedirect, strThrottled, strTimedOut}
+)
+
+// CoerceFields returns a processor converting fields to native types for
+// structured sinks: statuses and sizes to integers, latencies to float
+// milliseconds, flags to booleans and time to RFC 3339. Values that do not
+// parse are left as they are
+func CoerceFields() Processor {
+	return ProcessorFunc(func(e *Entry) bool {
+		for i := range e.Fields {
+			f := &e.Fields[i]
+			if s, ok := f.Value.(string); ok {
+				f.Value = coerce(f.Key, s, e.Time)
+			}
+		}
+		return true
+	})
+}
+
+// coerce converts the string value s of the field key to its native type,
+// leaving it as it is when it does not parse
+func coerce(key, s string, t time.Time) interface{} {
+	switch {
+	case key == strTime:
+		return t.Format(time.RFC3339Nano)
+	case contains(intFields, key):
+		if n, err := strconv.ParseInt(strings.TrimSpace(s), 10, 64); err == nil {
+			return n
+		}
+	case contains(durationFields, key):
+		if d, err := time.ParseDuration(strings.TrimSpace(s)); err == nil {
+			return milliseconds(d)
+		}
+	case contains(boolFields, key):
+		if b, err := strconv.ParseBool(s); err == nil {
+			return b
+		}
+	}
+	return s
+}
+
+func contains(list []string, s string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
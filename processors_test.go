@@ -0,0 +1,70 @@
+package logger
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAllowFields(t *testing.T) {
+	e := &Entry{IP: "203.0.113.7", Path: "/users/1", Route: "/users/:id", Err: errors.New("boom"),
+		Fields: []Field{{"ua", "curl"}, {"status", "200"}, {"body", "secret"}}}
+	AllowFields("status", "route").Process(e)
+	if len(e.Fields) != 1 || e.Fields[0].Key != "status" {
+		t.Errorf("Has: %v, expected: status", e.Fields)
+	}
+	if e.IP != "" || e.Path != "" || e.Err != nil || e.Route != "/users/:id" {
+		t.Errorf("Has: %+v, expected: route only", e)
+	}
+}
+
+func TestDenyFields(t *testing.T) {
+	e := &Entry{IP: "203.0.113.7", Path: "/users/1",
+		Fields: []Field{{"ua", "curl"}, {"body", "secret"}}}
+	DenyFields("body", "ip").Process(e)
+	if len(e.Fields) != 1 || e.Fields[0].Key != "ua" {
+		t.Errorf("Has: %v, expected: ua", e.Fields)
+	}
+	if e.IP != "" || e.Path != "/users/1" {
+		t.Errorf("Has: %+v, expected: no ip", e)
+	}
+}
+
+func TestLimitFields(t *testing.T) {
+	e := &Entry{Fields: []Field{
+		{"ua", strings.Repeat("a", 300)},
+		{"header:Cookie", "µµµµ"},
+		{"header:X-Short", "ok"},
+		{"status", 200},
+	}}
+	LimitFields(map[string]int{"ua": 256, "header:*": 5}).Process(e)
+	if v := e.Fields[0].Value; v != strings.Repeat("a", 256)+"…[+44 bytes]" {
+		t.Errorf("Has: %v, expected: 256 bytes and marker", v)
+	}
+	if v := e.Fields[1].Value; v != "µµ…[+4 bytes]" {
+		t.Errorf("Has: %v, expected: µµ…[+4 bytes]", v)
+	}
+	if v := e.Fields[2].Value; v != "ok" {
+		t.Errorf("Has: %v, expected: ok", v)
+	}
+}
+
+func TestCoerceFields(t *testing.T) {
+	e := &Entry{Time: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC), Fields: []Field{
+		{"time", "12:00:00"},
+		{"status", "502"},
+		{"bytesSent", "1024"},
+		{"latency", "1.5ms"},
+		{"retriable", "true"},
+		{"upstreamStatus", ""},
+		{"ua", "200"},
+	}}
+	CoerceFields().Process(e)
+	expected := []interface{}{"2020-01-01T12:00:00Z", int64(502), int64(1024), 1.5, true, "", "200"}
+	for i, v := range expected {
+		if e.Fields[i].Value != v {
+			t.Errorf("Has: %s=%#v, expected: %#v", e.Fields[i].Key, e.Fields[i].Value, v)
+		}
+	}
+}
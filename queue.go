@@ -0,0 +1,325 @@
+package logger
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// ringMinProcs is the GOMAXPROCS from which the async queue is a lock-free
+// ring. With fewer processors few requests log at the same time and a
+// buffered channel is as fast, without the consumer spinning while it is
+// empty
+const ringMinProcs = 4
+
+// queue hands encoded entries from the requests to the background writer
+// of the async mode, with many producers and a single consumer
+type queue interface {
+	// push adds b of priority p, false when the queue is full. Only the
+	// classQueue of the drop policies keeps p
+	push(b *bytebufferpool.ByteBuffer, p Priority) bool
+	// pop returns the next buffer, waiting for one to be pushed. It returns
+	// nil once the queue is closed and drained
+	pop() *bytebufferpool.ByteBuffer
+	// close makes pop return nil once the queue is drained, pushes after
+	// close are not popped
+	close()
+	// len returns the number of queued buffers
+	len() int
+}
+
+// newQueue returns a queue of at least size buffers, a ring from
+// ringMinProcs processors and a channel below
+func newQueue(size int) queue {
+	if runtime.GOMAXPROCS(0) < ringMinProcs {
+		return newChanQueue(size)
+	}
+	return newRing(size)
+}
+
+// chanQueue is a queue on a buffered channel
+type chanQueue struct {
+	ch   chan *bytebufferpool.ByteBuffer
+	done chan struct{}
+}
+
+func newChanQueue(size int) *chanQueue {
+	return &chanQueue{
+		ch:   make(chan *bytebufferpool.ByteBuffer, size),
+		done: make(chan struct{}),
+	}
+}
+
+func (q *chanQueue) push(b *bytebufferpool.ByteBuffer, _ Priority) bool {
+	select {
+	case q.ch <- b:
+		return true
+	default:
+		return false
+	}
+}
+
+func (q *chanQueue) pop() *bytebufferpool.ByteBuffer {
+	select {
+	case b := <-q.ch:
+		return b
+	case <-q.done:
+		select {
+		case b := <-q.ch:
+			return b
+		default:
+			return nil
+		}
+	}
+}
+
+func (q *chanQueue) close() {
+	close(q.done)
+}
+
+func (q *chanQueue) len() int {
+	return len(q.ch)
+}
+
+// slot is an element of the ring, seq tells producers and the consumer
+// whose turn it is
+type slot struct {
+	seq uint64
+	b   *bytebufferpool.ByteBuffer
+}
+
+// ring is a bounded lock-free queue after Dmitry Vyukov's MPMC queue,
+// simplified for a single consumer. Producers claim a slot by advancing
+// tail and publish it by its sequence, the consumer parks on wake when the
+// ring is empty and the producer seeing it sleeping wakes it
+type ring struct {
+	tail     uint64
+	_        [56]byte // keep tail and head on separate cache lines
+	head     uint64
+	sleeping int32
+	mask     uint64
+	slots    []slot
+	wake     chan struct{}
+	done     chan struct{}
+}
+
+func newRing(size int) *ring {
+	n := 2
+	for n < size {
+		n <<= 1
+	}
+	r := &ring{
+		mask:  uint64(n - 1),
+		slots: make([]slot, n),
+		wake:  make(chan struct{}, 1),
+		done:  make(chan struct{}),
+	}
+	for i := range r.slots {
+		r.slots[i].seq = uint64(i)
+	}
+	return r
+}
+
+func (r *ring) push(b *bytebufferpool.ByteBuffer, _ Priority) bool {
+	for {
+		pos := atomic.LoadUint64(&r.tail)
+		s := &r.slots[pos&r.mask]
+		seq := atomic.LoadUint64(&s.seq)
+		if seq < pos {
+			// The slot of the previous round is not consumed yet
+			return false
+		}
+		if seq == pos && atomic.CompareAndSwapUint64(&r.tail, pos, pos+1) {
+			s.b = b
+			atomic.StoreUint64(&s.seq, pos+1)
+			if atomic.LoadInt32(&r.sleeping) == 1 && atomic.CompareAndSwapInt32(&r.sleeping, 1, 0) {
+				select {
+				case r.wake <- struct{}{}:
+				default:
+				}
+			}
+			return true
+		}
+	}
+}
+
+// tryPop returns the next buffer or nil when the ring is empty
+func (r *ring) tryPop() *bytebufferpool.ByteBuffer {
+	pos := atomic.LoadUint64(&r.head)
+	s := &r.slots[pos&r.mask]
+	if atomic.LoadUint64(&s.seq) != pos+1 {
+		return nil
+	}
+	b := s.b
+	s.b = nil
+	atomic.StoreUint64(&s.seq, pos+r.mask+1)
+	atomic.StoreUint64(&r.head, pos+1)
+	return b
+}
+
+func (r *ring) pop() *bytebufferpool.ByteBuffer {
+	for {
+		// Spin shortly, under load the next entry is about to be pushed
+		for i := 0; i < 16; i++ {
+			if b := r.tryPop(); b != nil {
+				return b
+			}
+			runtime.Gosched()
+		}
+		// Announce sleeping before checking again, so a producer pushing
+		// after the check sees it and wakes the consumer
+		atomic.StoreInt32(&r.sleeping, 1)
+		if b := r.tryPop(); b != nil {
+			atomic.StoreInt32(&r.sleeping, 0)
+			return b
+		}
+		select {
+		case <-r.wake:
+		case <-r.done:
+			atomic.StoreInt32(&r.sleeping, 0)
+			return r.tryPop()
+		}
+	}
+}
+
+func (r *ring) close() {
+	close(r.done)
+}
+
+func (r *ring) len() int {
+	head := atomic.LoadUint64(&r.head)
+	return int(atomic.LoadUint64(&r.tail) - head)
+}
+
+// queued is an entry of a classQueue, seq is the order it was pushed in
+type queued struct {
+	seq uint64
+	b   *bytebufferpool.ByteBuffer
+}
+
+// classQueue is the queue of the drop policies, a FIFO per priority class
+// so a full queue can evict an entry of the lowest class and a flood of ok
+// entries does not push out the errors. Entries are popped in the order
+// they were pushed across classes
+type classQueue struct {
+	mu      sync.Mutex
+	classes [PriorityError + 1][]queued
+	size    int
+	n       int
+	seq     uint64
+	wake    chan struct{}
+	done    chan struct{}
+}
+
+func newClassQueue(size int) *classQueue {
+	return &classQueue{
+		size: size,
+		wake: make(chan struct{}, 1),
+		done: make(chan struct{}),
+	}
+}
+
+// class returns the index of p, priorities of custom rules outside the
+// classes count as the nearest one
+func class(p Priority) int {
+	if p < PriorityOK {
+		return int(PriorityOK)
+	}
+	if p > PriorityError {
+		return int(PriorityError)
+	}
+	return int(p)
+}
+
+func (q *classQueue) push(b *bytebufferpool.ByteBuffer, p Priority) bool {
+	q.mu.Lock()
+	if q.n >= q.size {
+		q.mu.Unlock()
+		return false
+	}
+	q.seq++
+	c := class(p)
+	q.classes[c] = append(q.classes[c], queued{q.seq, b})
+	q.n++
+	q.mu.Unlock()
+	select {
+	case q.wake <- struct{}{}:
+	default:
+	}
+	return true
+}
+
+// next removes the entry pushed first, nil when the queue is empty
+func (q *classQueue) next() *bytebufferpool.ByteBuffer {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	first := -1
+	for c := range q.classes {
+		if len(q.classes[c]) > 0 && (first < 0 || q.classes[c][0].seq < q.classes[first][0].seq) {
+			first = c
+		}
+	}
+	if first < 0 {
+		return nil
+	}
+	b := q.classes[first][0].b
+	q.classes[first][0].b = nil
+	q.classes[first] = q.classes[first][1:]
+	q.n--
+	return b
+}
+
+func (q *classQueue) pop() *bytebufferpool.ByteBuffer {
+	for {
+		if b := q.next(); b != nil {
+			return b
+		}
+		select {
+		case <-q.wake:
+		case <-q.done:
+			return q.next()
+		}
+	}
+}
+
+// evict removes an entry of the lowest class below p, or up to p with
+// oldest set, to make room for an entry of priority p. With oldest the
+// oldest entry of the class is removed, else the newest. It returns nil
+// when no queued entry ranks low enough, the entry of p is then dropped
+func (q *classQueue) evict(p Priority, oldest bool) *bytebufferpool.ByteBuffer {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	limit := class(p)
+	if oldest {
+		limit++
+	}
+	for c := 0; c < limit; c++ {
+		entries := q.classes[c]
+		if len(entries) == 0 {
+			continue
+		}
+		var b *bytebufferpool.ByteBuffer
+		if oldest {
+			b, entries[0].b = entries[0].b, nil
+			q.classes[c] = entries[1:]
+		} else {
+			b, entries[len(entries)-1].b = entries[len(entries)-1].b, nil
+			q.classes[c] = entries[:len(entries)-1]
+		}
+		q.n--
+		return b
+	}
+	return nil
+}
+
+func (q *classQueue) close() {
+	close(q.done)
+}
+
+func (q *classQueue) len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return q.n
+}
@@ -0,0 +1,103 @@
+package logger
+
+import (
+	"runtime"
+	"strconv"
+	"sync"
+	"testing"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// queues returns a queue of each implementation
+func queues(size int) map[string]queue {
+	return map[string]queue{
+		"ring":    newRing(size),
+		"channel": newChanQueue(size),
+	}
+}
+
+func TestQueue(t *testing.T) {
+	const producers, entries = 8, 2000
+	for name, q := range queues(64) {
+		seen := make(map[string]bool)
+		consumed := make(chan struct{})
+		go func() {
+			for b := q.pop(); b != nil; b = q.pop() {
+				seen[b.String()] = true
+			}
+			close(consumed)
+		}()
+		var wg sync.WaitGroup
+		for p := 0; p < producers; p++ {
+			wg.Add(1)
+			go func(p int) {
+				defer wg.Done()
+				for i := 0; i < entries; i++ {
+					b := &bytebufferpool.ByteBuffer{}
+					b.WriteString(strconv.Itoa(p) + "-" + strconv.Itoa(i))
+					for !q.push(b, PriorityOK) {
+						runtime.Gosched()
+					}
+				}
+			}(p)
+		}
+		wg.Wait()
+		q.close()
+		<-consumed
+		if len(seen) != producers*entries {
+			t.Errorf("Has: %d entries from the %s, expected: %d", len(seen), name, producers*entries)
+		}
+	}
+}
+
+func TestQueue_full(t *testing.T) {
+	for name, q := range queues(4) {
+		b := &bytebufferpool.ByteBuffer{}
+		for i := 0; i < 4; i++ {
+			if !q.push(b, PriorityOK) {
+				t.Errorf("Has: full %s after %d buffers, expected: room for 4", name, i)
+			}
+		}
+		if q.push(b, PriorityOK) {
+			t.Errorf("Has: push to a full %s, expected: false", name)
+		}
+		if q.len() != 4 {
+			t.Errorf("Has: %d, expected: 4 queued in the %s", q.len(), name)
+		}
+		q.pop()
+		if !q.push(b, PriorityOK) {
+			t.Errorf("Has: full %s after a pop, expected: room", name)
+		}
+		q.close()
+		for i := 0; i < 4; i++ {
+			if q.pop() == nil {
+				t.Errorf("Has: nil, expected: the %s drained after close", name)
+			}
+		}
+		if q.pop() != nil {
+			t.Errorf("Has: a buffer, expected: nil from the drained %s", name)
+		}
+	}
+}
+
+func BenchmarkQueue(b *testing.B) {
+	for name := range queues(0) {
+		b.Run(name, func(b *testing.B) {
+			q := queues(1024)[name]
+			go func() {
+				for buf := q.pop(); buf != nil; buf = q.pop() {
+				}
+			}()
+			buf := &bytebufferpool.ByteBuffer{}
+			b.RunParallel(func(pb *testing.PB) {
+				for pb.Next() {
+					for !q.push(buf, PriorityOK) {
+						runtime.Gosched()
+					}
+				}
+			})
+			q.close()
+		})
+	}
+}
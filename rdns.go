@@ -0,0 +1,87 @@
+package logger
+
+import (
+	"container/list"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"time"
+)
+
+// rdnsLookups is the most lookups in flight, IPs seen while they are all
+// busy are logged as is and looked up by a later request
+const rdnsLookups = 16
+
+// rdns resolves client IPs to hostnames in the background and keeps the
+// most recently used results. Until a lookup finished the IP is returned
+type rdns struct {
+	mu      sync.Mutex
+	size    int
+	timeout time.Duration
+	lru     *list.List
+	items   map[string]*list.Element
+	pending map[string]bool
+	lookups chan struct{}
+	lookup  func(ctx context.Context, addr string) ([]string, error)
+}
+
+type rdnsEntry struct {
+	ip   string
+	host string
+}
+
+func newRDNS(size int, timeout time.Duration) *rdns {
+	return &rdns{
+		size:    size,
+		timeout: timeout,
+		lru:     list.New(),
+		items:   make(map[string]*list.Element),
+		pending: make(map[string]bool),
+		lookups: make(chan struct{}, rdnsLookups),
+		lookup:  net.DefaultResolver.LookupAddr,
+	}
+}
+
+// hostname returns the cached hostname of ip or ip itself
+func (r *rdns) hostname(ip string) string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if e, ok := r.items[ip]; ok {
+		r.lru.MoveToFront(e)
+		return e.Value.(*rdnsEntry).host
+	}
+	if r.pending[ip] {
+		return ip
+	}
+	select {
+	case r.lookups <- struct{}{}:
+		r.pending[ip] = true
+		go func() {
+			r.resolve(ip)
+			<-r.lookups
+		}()
+	default:
+	}
+	return ip
+}
+
+// resolve looks up ip and caches the result, failed lookups are cached as
+// the IP so they are not retried on every request
+func (r *rdns) resolve(ip string) {
+	ctx, cancel := context.WithTimeout(context.Background(), r.timeout)
+	defer cancel()
+	host := ip
+	if names, err := r.lookup(ctx, ip); err == nil && len(names) > 0 {
+		host = strings.TrimSuffix(names[0], ".")
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.pending, ip)
+	r.items[ip] = r.lru.PushFront(&rdnsEntry{ip: ip, host: host})
+	for r.lru.Len() > r.size {
+		e := r.lru.Back()
+		r.lru.Remove(e)
+		delete(r.items, e.Value.(*rdnsEntry).ip)
+	}
+}
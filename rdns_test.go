@@ -0,0 +1,59 @@
+package logger
+
+import (
+	"context"
+	"io/ioutil"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func TestRDNS_hostname(t *testing.T) {
+	r := newRDNS(1, time.Second)
+	r.lookup = func(ctx context.Context, addr string) ([]string, error) {
+		return []string{"host-" + addr + "."}, nil
+	}
+
+	if host := r.hostname("10.0.0.1"); host != "10.0.0.1" {
+		t.Errorf("Has: %s, expected: 10.0.0.1", host)
+	}
+	for i := 0; i < 100 && r.hostname("10.0.0.1") == "10.0.0.1"; i++ {
+		time.Sleep(time.Millisecond)
+	}
+	if host := r.hostname("10.0.0.1"); host != "host-10.0.0.1" {
+		t.Errorf("Has: %s, expected: host-10.0.0.1", host)
+	}
+
+	// Resolving a second IP evicts the first one
+	r.resolve("10.0.0.2")
+	if _, ok := r.items["10.0.0.1"]; ok {
+		t.Errorf("Has: cached, expected: evicted")
+	}
+}
+
+func TestRDNS_lookups(t *testing.T) {
+	r := newRDNS(1024, time.Second)
+	release := make(chan struct{})
+	r.lookup = func(ctx context.Context, addr string) ([]string, error) {
+		<-release
+		return nil, ctx.Err()
+	}
+	for i := 0; i < 2*rdnsLookups; i++ {
+		r.hostname("10.0.1." + strconv.Itoa(i))
+	}
+	// Lookups beyond the limit are skipped, not queued
+	r.mu.Lock()
+	pending := len(r.pending)
+	r.mu.Unlock()
+	close(release)
+	if pending != rdnsLookups {
+		t.Errorf("Has: %d, expected: %d lookups in flight", pending, rdnsLookups)
+	}
+}
+
+func TestLogger_VariantHostname(t *testing.T) {
+	l := NewLogger(Config{Format: "${variant}", Variant: strIpHostname, Output: ioutil.Discard})
+	if l.rdns == nil {
+		t.Errorf("Has: nil, expected: lookups for the hostname variant")
+	}
+}
@@ -0,0 +1,143 @@
+package logger
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"time"
+)
+
+// RemoteConfig configures polling the logging policy from a URL
+type RemoteConfig struct {
+	// URL serves the policy as JSON, e.g. {"version": "42", "level":
+	// "slow", "sampling": 0.5, "redact": ["ip", "header:authorization"]}.
+	// Settings left out are reset to their defaults
+	// Required
+	URL string
+	// Interval is the time between polls
+	// Optional. Default: 1 * time.Minute
+	Interval time.Duration
+	// Header is sent with every poll, e.g. for authorization
+	// Optional. Default: nil
+	Header map[string]string
+	// Client makes the polls
+	// Optional. Default: a client with a 10 second timeout
+	Client *http.Client
+}
+
+// remotePolicy is the JSON form of a policy
+type remotePolicy struct {
+	Version  string   `json:"version"`
+	Level    string   `json:"level"`
+	Sampling *float64 `json:"sampling"`
+	Redact   []string `json:"redact"`
+}
+
+// remote polls the policy of a RemoteConfig
+type remote struct {
+	cfg  RemoteConfig
+	etag string
+}
+
+func newRemote(cfg RemoteConfig) *remote {
+	if cfg.URL == "" {
+		panic("logger: remote config URL is required")
+	}
+	if cfg.Interval <= 0 {
+		cfg.Interval = time.Minute
+	}
+	if cfg.Client == nil {
+		cfg.Client = &http.Client{Timeout: 10 * time.Second}
+	}
+	return &remote{cfg: cfg}
+}
+
+// poll applies the remote policy now and every Interval until Close. A
+// policy that cannot be fetched or is invalid is reported and the current
+// one is kept
+func (l *Logger) poll(r *remote) {
+	for {
+		p, err := r.fetch()
+		if err != nil {
+			l.diag(LevelError, "polling remote config: %v", err)
+		} else if p != nil {
+			l.control.mu.Lock()
+			l.control.p.Store(p)
+			l.control.mu.Unlock()
+			l.diag(LevelInfo, "applied remote config version %q", p.version)
+		}
+		select {
+		case <-time.After(r.cfg.Interval):
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// fetch returns the policy at URL, nil when it did not change since the
+// last fetch
+func (r *remote) fetch() (*policy, error) {
+	req, err := http.NewRequest(http.MethodGet, r.cfg.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	for name, value := range r.cfg.Header {
+		req.Header.Set(name, value)
+	}
+	if r.etag != "" {
+		req.Header.Set("If-None-Match", r.etag)
+	}
+	resp, err := r.cfg.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	body, err := ioutil.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusNotModified {
+		return nil, nil
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("logger: remote config: %s: %s", resp.Status, bytes.TrimSpace(body))
+	}
+	p, err := parsePolicy(body)
+	if err != nil {
+		return nil, err
+	}
+	r.etag = resp.Header.Get("ETag")
+	return p, nil
+}
+
+// parsePolicy validates the JSON policy in b
+func parsePolicy(b []byte) (*policy, error) {
+	var rp remotePolicy
+	if err := json.Unmarshal(b, &rp); err != nil {
+		return nil, fmt.Errorf("logger: remote config: %v", err)
+	}
+	p := &policy{rate: 1, redact: rp.Redact, version: rp.Version}
+	if rp.Level != "" {
+		level, err := parseLevel(rp.Level)
+		if err != nil {
+			return nil, err
+		}
+		p.level = level
+	}
+	if rp.Sampling != nil {
+		if *rp.Sampling < 0 || *rp.Sampling > 1 {
+			return nil, fmt.Errorf("logger: invalid sample rate %g, expected 0 to 1", *rp.Sampling)
+		}
+		p.rate = *rp.Sampling
+	}
+	return p, nil
+}
+
+// PolicyVersion returns the version of the last remote policy applied, ""
+// before the first one
+func (l *Logger) PolicyVersion() string {
+	return l.policy().version
+}
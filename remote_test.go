@@ -0,0 +1,75 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestLogger_Remote(t *testing.T) {
+	var unchanged int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer token" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"1"` {
+			atomic.AddInt32(&unchanged, 1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Header().Set("ETag", `"1"`)
+		w.Write([]byte(`{"version": "1", "level": "slow", "redact": ["ip", "header:*"]}`))
+	}))
+	defer srv.Close()
+
+	buf := &strings.Builder{}
+	l := NewLogger(Config{
+		Format:      "${path} ${ip} ${header:x-token} ${status}\n",
+		Output:      buf,
+		Diagnostics: ioutil.Discard,
+		Remote: &RemoteConfig{
+			URL:      srv.URL,
+			Interval: time.Millisecond,
+			Header:   map[string]string{"Authorization": "Bearer token"},
+		},
+	})
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) && atomic.LoadInt32(&unchanged) == 0 {
+		time.Sleep(time.Millisecond)
+	}
+	if v := l.PolicyVersion(); v != "1" {
+		t.Fatalf("Has: %q, expected: the remote version", v)
+	}
+
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/ok", func(ctx *fiber.Ctx) {})
+	app.Get("/error", func(ctx *fiber.Ctx) { ctx.SendStatus(500) })
+	for _, path := range []string{"/ok", "/error"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("X-Token", "secret")
+		app.Test(req, 1000)
+	}
+	if expected := "/error [REDACTED] [REDACTED] 500\n"; buf.String() != expected {
+		t.Errorf("Has: %q, expected: %q", buf.String(), expected)
+	}
+}
+
+func TestParsePolicy(t *testing.T) {
+	p, err := parsePolicy([]byte(`{"version": "2"}`))
+	if err != nil || p.level != PriorityOK || p.rate != 1 || p.version != "2" {
+		t.Errorf("Has: %+v %v, expected: the defaults for left out settings", p, err)
+	}
+	for _, body := range []string{`{"level": "loud"}`, `{"sampling": 2}`, `[]`} {
+		if _, err := parsePolicy([]byte(body)); err == nil {
+			t.Errorf("Has: nil, expected: an error for %s", body)
+		}
+	}
+}
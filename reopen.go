@@ -0,0 +1,68 @@
+package logger
+
+import (
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// Reopen closes the file and opens Filename again, for external rotation by
+// logrotate without copytruncate: once the file was moved aside, Reopen
+// starts a new one under the name. The new file is opened before the old one
+// is closed and writes wait meanwhile, so no line is lost. When the file
+// cannot be opened the old one is kept
+func (w *FileWriter) Reopen() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.f == nil {
+		return os.ErrClosed
+	}
+	return w.open()
+}
+
+// Reopen reopens the outputs that support it, like a FileWriter, and
+// returns the first error
+func (l *Logger) Reopen() error {
+	var first error
+	for _, o := range l.outputs() {
+		o.swap.RLock()
+		r, ok := o.w.(interface{ Reopen() error })
+		o.swap.RUnlock()
+		if !ok {
+			continue
+		}
+		if err := r.Reopen(); err != nil {
+			l.diag(LevelError, "reopening %s: %v", o.name, err)
+			if first == nil {
+				first = err
+			}
+		}
+	}
+	return first
+}
+
+// ReopenOnSignal calls Reopen whenever one of sig is received, SIGHUP when
+// none is given, like the postrotate script of logrotate sends. The
+// returned function stops it
+func (l *Logger) ReopenOnSignal(sig ...os.Signal) (stop func()) {
+	if len(sig) == 0 {
+		sig = []os.Signal{syscall.SIGHUP}
+	}
+	ch := make(chan os.Signal, 1)
+	done := make(chan struct{})
+	signal.Notify(ch, sig...)
+	go func() {
+		for {
+			select {
+			case <-ch:
+				l.Reopen()
+			case <-done:
+				return
+			}
+		}
+	}()
+	return func() {
+		signal.Stop(ch)
+		close(done)
+	}
+}
@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestLogger_ReopenOnSignal(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	l := NewLogger(Config{File: &FileConfig{Filename: name}})
+	fw := l.cfg.Output.(*FileWriter)
+	defer fw.Close()
+	stop := l.ReopenOnSignal()
+	defer stop()
+
+	os.Rename(name, name+".1")
+	syscall.Kill(os.Getpid(), syscall.SIGHUP)
+	deadline := time.Now().Add(time.Second)
+	for time.Now().Before(deadline) {
+		if _, err := os.Stat(name); err == nil {
+			return
+		}
+		time.Sleep(time.Millisecond)
+	}
+	t.Errorf("Has: no file, expected: %s reopened on SIGHUP", name)
+}
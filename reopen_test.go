@@ -0,0 +1,42 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestLogger_Reopen(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	name := filepath.Join(dir, "access.log")
+	l := NewLogger(Config{Format: "${path}\n", File: &FileConfig{Filename: name}})
+	defer l.cfg.Output.(*FileWriter).Close()
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {})
+
+	app.Test(httptest.NewRequest(http.MethodGet, "/a", nil), 1000)
+	// Moved aside like logrotate does, lines go to the moved file until Reopen
+	os.Rename(name, name+".1")
+	app.Test(httptest.NewRequest(http.MethodGet, "/b", nil), 1000)
+	if err := l.Reopen(); err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	app.Test(httptest.NewRequest(http.MethodGet, "/c", nil), 1000)
+
+	if b, _ := ioutil.ReadFile(name + ".1"); string(b) != "/a\n/b\n" {
+		t.Errorf("Has: %q, expected: the lines before Reopen", b)
+	}
+	if b, _ := ioutil.ReadFile(name); string(b) != "/c\n" {
+		t.Errorf("Has: %q, expected: the lines after Reopen", b)
+	}
+}
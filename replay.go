@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+// ReplayFormat writes entries as a vegeta target list, so production traffic
+// can be replayed in staging with `vegeta attack -targets access.log`. The
+// headers listed in ReplayHeaders are included
+const ReplayFormat = "${method} ${protocol}://${host}${url}\n${replayHeaders}\n"
+
+// replayHeaders writes the given request headers as "Key: value" lines,
+// leaving out those the request did not have
+func replayHeaders(buf *bytebufferpool.ByteBuffer, c *fiber.Ctx, keys []string) (int, error) {
+	n := 0
+	for _, key := range keys {
+		value := c.Get(key)
+		if value == "" {
+			continue
+		}
+		m, _ := buf.WriteString(key + ": " + value + "\n")
+		n += m
+	}
+	return n, nil
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestReplayFormat(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format:        ReplayFormat,
+		Output:        buf,
+		ReplayHeaders: []string{"X-Account-ID", "Authorization"},
+	}))
+	app.Get("/api/items", func(ctx *fiber.Ctx) {})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/items?page=2", nil)
+	req.Header.Set("X-Account-ID", "8675309")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := "GET http://example.com/api/items?page=2\nX-Account-ID: 8675309\n\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %q, expected: %q", buf.String(), expectedOutput)
+	}
+}
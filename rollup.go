@@ -0,0 +1,193 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"math/rand"
+	"sort"
+	"sync"
+	"time"
+)
+
+// rollupSamples is the number of latencies kept per key and interval
+// to compute quantiles, later requests are reservoir sampled
+const rollupSamples = 1024
+
+// rollupKey groups requests in a rollup
+type rollupKey struct {
+	Method string
+	Route  string
+	Status int
+}
+
+type rollupStats struct {
+	count     int
+	latencies []time.Duration
+}
+
+// rollupTotal holds the counters kept across intervals for Metrics
+type rollupTotal struct {
+	count     int64
+	sum       time.Duration
+	quantiles [len(rollupQuantiles)]time.Duration
+	buckets   [len(rollupBuckets) + 1]int64
+	exemplars [len(rollupBuckets) + 1]exemplar
+}
+
+// exemplar links a latency bucket to the last request counted in it
+type exemplar struct {
+	traceID   string
+	requestID string
+	latency   time.Duration
+	time      time.Time
+}
+
+// rollupBuckets are the upper bounds of the latency histogram of Metrics
+var rollupBuckets = [...]time.Duration{
+	5 * time.Millisecond, 10 * time.Millisecond, 25 * time.Millisecond, 50 * time.Millisecond,
+	100 * time.Millisecond, 250 * time.Millisecond, 500 * time.Millisecond,
+	time.Second, 2500 * time.Millisecond, 5 * time.Second, 10 * time.Second,
+}
+
+// rollupQuantiles are exposed by Metrics, computed over the last interval
+var rollupQuantiles = [...]float64{0.5, 0.9, 0.99}
+
+// rollupLine is a rollup as written to the output
+type rollupLine struct {
+	Time     string  `json:"time"`
+	Interval string  `json:"interval"`
+	Method   string  `json:"method"`
+	Route    string  `json:"route"`
+	Status   int     `json:"status"`
+	Count    int     `json:"count"`
+	P50      float64 `json:"p50_ms"`
+	P90      float64 `json:"p90_ms"`
+	P99      float64 `json:"p99_ms"`
+	Max      float64 `json:"max_ms"`
+}
+
+// rollup counts requests and their latencies by route and status
+type rollup struct {
+	mu       sync.Mutex
+	interval time.Duration
+	stats    map[rollupKey]*rollupStats
+	totals   map[rollupKey]*rollupTotal
+}
+
+func newRollup(interval time.Duration) *rollup {
+	return &rollup{
+		interval: interval,
+		stats:    make(map[rollupKey]*rollupStats),
+		totals:   make(map[rollupKey]*rollupTotal),
+	}
+}
+
+// add counts a request, ex is kept as exemplar of its latency bucket when it
+// has an id
+func (r *rollup) add(key rollupKey, latency time.Duration, ex exemplar) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.stats[key]
+	if s == nil {
+		s = &rollupStats{}
+		r.stats[key] = s
+	}
+	s.count++
+	t := r.totals[key]
+	if t == nil {
+		t = &rollupTotal{}
+		r.totals[key] = t
+	}
+	t.count++
+	t.sum += latency
+	b := sort.Search(len(rollupBuckets), func(i int) bool { return latency <= rollupBuckets[i] })
+	t.buckets[b]++
+	if ex.traceID != "" || ex.requestID != "" {
+		ex.latency = latency
+		t.exemplars[b] = ex
+	}
+	if len(s.latencies) < rollupSamples {
+		s.latencies = append(s.latencies, latency)
+	} else if i := rand.Intn(s.count); i < rollupSamples {
+		s.latencies[i] = latency
+	}
+}
+
+// rollups writes the rollups every interval until Close
+func (l *Logger) rollups() {
+	ticker := time.NewTicker(l.cfg.Rollup)
+	defer ticker.Stop()
+	for {
+		select {
+		case now := <-ticker.C:
+			l.flushRollup(now)
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// flushRollup writes the rollups of the interval ending at now
+func (l *Logger) flushRollup(now time.Time) {
+	if err := l.rollup.flush(l.rollupOut, now); err != nil {
+		l.diag(LevelError, "writing rollup: %v", err)
+	}
+}
+
+// flush writes one JSON line per key and starts a new interval
+func (r *rollup) flush(w io.Writer, now time.Time) error {
+	r.mu.Lock()
+	stats := r.stats
+	r.stats = make(map[rollupKey]*rollupStats, len(stats))
+	r.mu.Unlock()
+
+	lines := make([]rollupLine, 0, len(stats))
+	for key, s := range stats {
+		sort.Slice(s.latencies, func(i, j int) bool { return s.latencies[i] < s.latencies[j] })
+		r.mu.Lock()
+		for i, q := range rollupQuantiles {
+			r.totals[key].quantiles[i] = quantile(s.latencies, q)
+		}
+		r.mu.Unlock()
+		lines = append(lines, rollupLine{
+			Time:     now.Format(time.RFC3339),
+			Interval: r.interval.String(),
+			Method:   key.Method,
+			Route:    key.Route,
+			Status:   key.Status,
+			Count:    s.count,
+			P50:      milliseconds(quantile(s.latencies, 0.5)),
+			P90:      milliseconds(quantile(s.latencies, 0.9)),
+			P99:      milliseconds(quantile(s.latencies, 0.99)),
+			Max:      milliseconds(quantile(s.latencies, 1)),
+		})
+	}
+	sort.Slice(lines, func(i, j int) bool {
+		if lines[i].Route != lines[j].Route {
+			return lines[i].Route < lines[j].Route
+		}
+		if lines[i].Method != lines[j].Method {
+			return lines[i].Method < lines[j].Method
+		}
+		return lines[i].Status < lines[j].Status
+	})
+	enc := json.NewEncoder(w)
+	for i := range lines {
+		if err := enc.Encode(&lines[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// quantile returns the q quantile of the sorted durations
+func quantile(sorted []time.Duration, q float64) time.Duration {
+	if len(sorted) == 0 {
+		return 0
+	}
+	return sorted[int(q*float64(len(sorted)-1)+0.5)]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
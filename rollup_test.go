@@ -0,0 +1,49 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestRollup_flush(t *testing.T) {
+	buf := &strings.Builder{}
+	r := newRollup(time.Minute)
+	key := rollupKey{Method: "GET", Route: "/api/:id", Status: 200}
+	for i := 1; i <= 100; i++ {
+		r.add(key, time.Duration(i)*time.Millisecond, exemplar{})
+	}
+	if err := r.flush(buf, time.Date(2020, 1, 1, 12, 1, 0, 0, time.UTC)); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := `{"time":"2020-01-01T12:01:00Z","interval":"1m0s","method":"GET","route":"/api/:id","status":200,"count":100,"p50_ms":51,"p90_ms":90,"p99_ms":99,"max_ms":100}` + "\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+
+	buf.Reset()
+	r.flush(buf, time.Now())
+	if buf.Len() != 0 {
+		t.Errorf("Has: %s, expected: empty interval", buf.String())
+	}
+}
+
+func TestLogger_CloseRollup(t *testing.T) {
+	out := &strings.Builder{}
+	l := NewLogger(Config{Output: out, Rollup: time.Hour, RollupOnly: true})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/", func(ctx *fiber.Ctx) {})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Fatalf("Has: %+v, expected: nil", err)
+	}
+	l.Close()
+	if !strings.Contains(out.String(), `"count":1`) {
+		t.Errorf("Has: %q, expected: the rollup written on Close", out.String())
+	}
+}
@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"hash/fnv"
+	"math"
+	"math/rand"
+)
+
+// sample reports whether an entry is kept at rate. Entries with an id are
+// kept by a hash of seed and id, so every replica and every hop of a request
+// makes the same decision, others are kept at random
+func sample(seed, id string, rate float64) bool {
+	if rate <= 0 {
+		return false
+	}
+	if id == "" {
+		return rand.Float64() < rate
+	}
+	h := fnv.New64a()
+	h.Write([]byte(seed))
+	h.Write([]byte{0})
+	h.Write([]byte(id))
+	// Mix the bits, FNV alone spreads similar ids unevenly
+	x := h.Sum64()
+	x = (x ^ x>>30) * 0xbf58476d1ce4e5b9
+	x = (x ^ x>>27) * 0x94d049bb133111eb
+	x ^= x >> 31
+	return float64(x)/math.MaxUint64 < rate
+}
@@ -0,0 +1,26 @@
+package logger
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestSample(t *testing.T) {
+	kept := 0
+	for i := 0; i < 10000; i++ {
+		id := "req-" + strconv.Itoa(i)
+		keep := sample("", id, 0.25)
+		if sample("", id, 0.25) != keep {
+			t.Fatalf("Has: different decisions for %s, expected: same", id)
+		}
+		if keep {
+			kept++
+		}
+	}
+	if kept < 2300 || kept > 2700 {
+		t.Errorf("Has: %d, expected: about 2500", kept)
+	}
+	if sample("", "req-1", 0) || !sample("", "req-1", 1) {
+		t.Errorf("Has: sampled at rate 0 or dropped at rate 1, expected: neither")
+	}
+}
@@ -0,0 +1,80 @@
+package logger
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+)
+
+// FieldSchema describes a field of the entries written by a JSONEncoder,
+// for generating mappings and table schemas of downstream stores
+type FieldSchema struct {
+	// Name is the key of the field, for prefix tags the prefix the key is
+	// appended to, e.g. "header:" for "header:x-request-id"
+	Name string `json:"name"`
+	// Type is "string", "integer", "number", "boolean", "timestamp" or
+	// "object"
+	Type string `json:"type"`
+	// Unit is the unit of numbers, "ms" for latencies
+	Unit string `json:"unit,omitempty"`
+	// Core fields are in every entry, the others when they are tags of
+	// JSONEncoder.Fields
+	Core bool `json:"core,omitempty"`
+	// Prefix marks tags that take a key
+	Prefix bool `json:"prefix,omitempty"`
+	// Fields are the fields of objects
+	Fields []FieldSchema `json:"fields,omitempty"`
+}
+
+// coreSchema are the fields of every JSON entry
+var coreSchema = []FieldSchema{
+	{Name: "schema", Type: "string", Core: true},
+	{Name: "time", Type: "timestamp", Core: true},
+	{Name: "method", Type: "string", Core: true},
+	{Name: "path", Type: "string", Core: true},
+	{Name: "route", Type: "string", Core: true},
+	{Name: "status", Type: "integer", Core: true},
+	{Name: "latency_ms", Type: "number", Unit: "ms", Core: true},
+	{Name: "ip", Type: "string", Core: true},
+	{Name: "error", Type: "object", Core: true, Fields: []FieldSchema{
+		{Name: "message", Type: "string"},
+		{Name: "type", Type: "string"},
+		{Name: "code", Type: "string"},
+		{Name: "retriable", Type: "boolean"},
+		{Name: "stack", Type: "string"},
+	}},
+}
+
+// Schema describes every field the logger can write with a JSONEncoder:
+// the core fields followed by the tags. Types are those of SchemaV2 and
+// CoerceFields, SchemaV1 writes tags as strings. The schema key is only
+// written with a SchemaVersion
+func Schema() []FieldSchema {
+	schema := append([]FieldSchema(nil), coreSchema...)
+	for _, tag := range tags {
+		if jsonKeys[tag] {
+			continue
+		}
+		f := FieldSchema{Name: tag, Type: "string", Prefix: strings.HasSuffix(tag, ":")}
+		switch {
+		case contains(intFields, tag):
+			f.Type = "integer"
+		case contains(durationFields, tag):
+			f.Type, f.Unit = "number", "ms"
+		case contains(boolFields, tag):
+			f.Type = "boolean"
+		}
+		schema = append(schema, f)
+	}
+	return schema
+}
+
+// WriteSchema writes Schema as indented JSON
+func WriteSchema(w io.Writer) error {
+	b, err := json.MarshalIndent(Schema(), "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(b, '\n'))
+	return err
+}
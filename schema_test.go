@@ -0,0 +1,43 @@
+package logger
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestSchema(t *testing.T) {
+	buf := &strings.Builder{}
+	if err := WriteSchema(buf); err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	var schema []FieldSchema
+	if err := json.Unmarshal([]byte(buf.String()), &schema); err != nil {
+		t.Fatalf("Has: %v, expected: JSON", err)
+	}
+	byName := make(map[string]FieldSchema)
+	for _, f := range schema {
+		if _, ok := byName[f.Name]; ok {
+			t.Errorf("Has: %s twice, expected: once", f.Name)
+		}
+		byName[f.Name] = f
+	}
+	for _, tag := range tags {
+		if _, ok := byName[tag]; !ok && !jsonKeys[tag] {
+			t.Errorf("Has: no %s, expected: every tag", tag)
+		}
+	}
+	for name, expected := range map[string]FieldSchema{
+		"status":          {Name: "status", Type: "integer", Core: true},
+		"upstreamLatency": {Name: "upstreamLatency", Type: "number", Unit: "ms"},
+		"retriable":       {Name: "retriable", Type: "boolean"},
+		"header:":         {Name: "header:", Type: "string", Prefix: true},
+	} {
+		if f := byName[name]; f.Type != expected.Type || f.Unit != expected.Unit || f.Core != expected.Core || f.Prefix != expected.Prefix {
+			t.Errorf("Has: %+v, expected: %+v", f, expected)
+		}
+	}
+	if len(byName["error"].Fields) == 0 {
+		t.Errorf("Has: no fields, expected: the fields of the error object")
+	}
+}
@@ -0,0 +1,32 @@
+package logger
+
+import (
+	"strconv"
+	"sync/atomic"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+// localsSeq is the Locals key of the sequence number of the entry, shared by
+// the output and sinks
+const localsSeq = "logger.seq"
+
+// number gives the entry of the request the next sequence number. Numbers
+// are taken once an entry passes muting and sampling, so entries dropped
+// later by processors, budgets or failed writes leave a gap. With Async the
+// caller holds the order lock until the entry is queued
+func (l *Logger) number(c *fiber.Ctx) {
+	c.Locals(localsSeq, atomic.AddUint64(&l.seq, 1))
+}
+
+// appendSeq writes the sequence number of the entry of the request to buf
+func appendSeq(buf *bytebufferpool.ByteBuffer, c *fiber.Ctx) (int, error) {
+	n, ok := c.Locals(localsSeq).(uint64)
+	if !ok {
+		return 0, nil
+	}
+	from := len(buf.B)
+	buf.B = strconv.AppendUint(buf.B, n, 10)
+	return len(buf.B) - from, nil
+}
@@ -0,0 +1,62 @@
+package logger
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestSeq(t *testing.T) {
+	buf := &strings.Builder{}
+	sink := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${seq} ${path};",
+		Output: buf,
+		Sinks:  []SinkConfig{{Encoder: &JSONEncoder{Fields: []string{strSeq}}, Output: sink, Processors: []Processor{CoerceFields()}}},
+	}))
+	app.Get("/*", func(ctx *fiber.Ctx) {})
+
+	for _, path := range []string{"/a", "/b", "/c"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	expectedOutput := "1 /a;2 /b;3 /c;"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+	if !strings.Contains(sink.String(), `"seq":3`) {
+		t.Errorf("Has: %s, expected: the sink with the same numbers", sink.String())
+	}
+}
+
+func TestSeq_gap(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${seq} ${path};",
+		Output: buf,
+		Filter: func(c *fiber.Ctx) bool { return c.Path() == "/health" },
+		Processors: []Processor{ProcessorFunc(func(e *Entry) bool {
+			return e.Path != "/dropped"
+		})},
+	}))
+	app.Get("/*", func(ctx *fiber.Ctx) {})
+
+	for _, path := range []string{"/a", "/health", "/dropped", "/b"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	// Filtered requests are not numbered, dropped entries leave a gap
+	expectedOutput := "1 /a;3 /b;"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,144 @@
+package logger
+
+import (
+	"io"
+	"strconv"
+
+	"github.com/valyala/fasttemplate"
+)
+
+// SinkConfig is a further destination of entries with its own format,
+// priority filter and sampling, written next to Output
+type SinkConfig struct {
+	// Name identifies the sink in Health and diagnostics
+	// Optional. Default: "sink <index>"
+	Name string
+	// Output is a writer where the entries of the sink are written
+	// Optional. Default: Config.Output
+	Output io.Writer
+	// Format defines the logging format of the sink, see Config.Format
+	// Optional. Default: Config.Format
+	Format string
+	// Encoder writes the entries of the sink in a structured format
+	// Optional. Default: the encoder of a structured Format, Config.Encoder
+	// when Format is not set either
+	Encoder Encoder
+	// MinPriority skips entries below this priority, e.g. PriorityError for
+	// an alerting sink
+	// Optional. Default: PriorityOK
+	MinPriority Priority
+	// Statuses limits the sink to responses with a status in one of the
+	// ranges, e.g. {{400, 599}} for an error file
+	// Optional. Default: nil (all statuses)
+	Statuses []StatusRange
+	// SampleRate is the fraction (0..1) of entries written, sampled by
+	// request id like FirstNSampleRate
+	// Optional. Default: 0 (all entries)
+	SampleRate float64
+	// Processors transform the entries of the sink after Config.Processors,
+	// without affecting other sinks
+	// Optional. Default: nil
+	Processors []Processor
+	// Where routes only the entries matching the expression to the sink,
+	// see Config.Where
+	// Optional. Default: "" (all entries)
+	Where string
+}
+
+// OutputConfig is a further writer with its own format or encoder, like a
+// JSON file next to colored text on the terminal. It is a SinkConfig
+// without filters, outputs are added to Config.Sinks
+type OutputConfig struct {
+	// Name identifies the output in Health, diagnostics and Swap
+	// Optional. Default: "sink <index>", counted after Config.Sinks
+	Name string
+	// Output is the writer the entries are written to
+	// Required
+	Output io.Writer
+	// Format defines the logging format of the output, see Config.Format
+	// Optional. Default: Config.Format
+	Format string
+	// Encoder writes the entries in a structured format
+	// Optional. Default: the encoder of a structured Format, Config.Encoder
+	// when Format is not set either
+	Encoder Encoder
+}
+
+// StatusRange is an inclusive range of response statuses
+type StatusRange struct {
+	From int
+	To   int
+}
+
+// Common status ranges
+var (
+	StatusSuccess     = StatusRange{200, 399}
+	StatusClientError = StatusRange{400, 499}
+	StatusServerError = StatusRange{500, 599}
+	StatusError       = StatusRange{400, 599}
+)
+
+// inStatuses reports whether status is in one of ranges, true without
+// ranges
+func inStatuses(ranges []StatusRange, status int) bool {
+	for _, r := range ranges {
+		if status >= r.From && status <= r.To {
+			return true
+		}
+	}
+	return len(ranges) == 0
+}
+
+// sink is a SinkConfig with its template and tracked output
+type sink struct {
+	SinkConfig
+	tmpl *fasttemplate.Template
+	out  *output
+}
+
+func newSinks(cfg *Config) []*sink {
+	configs := append([]SinkConfig(nil), cfg.Sinks...)
+	for _, o := range cfg.Outputs {
+		if o.Output == nil {
+			panic("logger: output " + o.Name + " has no writer")
+		}
+		configs = append(configs, SinkConfig{Name: o.Name, Output: o.Output, Format: o.Format, Encoder: o.Encoder})
+	}
+	sinks := make([]*sink, len(configs))
+	for i, sc := range configs {
+		if sc.Name == "" {
+			sc.Name = "sink " + strconv.Itoa(i)
+		}
+		if sc.Output == nil {
+			sc.Output = cfg.Output
+		}
+		if sc.Format == "" && sc.Encoder == nil {
+			sc.Format, sc.Encoder = cfg.Format, cfg.Encoder
+		}
+		if sc.Encoder == nil {
+			sc.Encoder = formatEncoder(sc.Format, "", cfg)
+		}
+		format, err := expandPartials(sc.Format, cfg.Partials)
+		if err != nil {
+			panic(err)
+		}
+		sc.Format = format
+		sc.Processors = where(sc.Where, sc.Processors)
+		sinks[i] = &sink{
+			SinkConfig: sc,
+			tmpl:       fasttemplate.New(sc.Format, "${", "}"),
+			out:        newOutput(sc.Name, sc.Output),
+		}
+		sinks[i].out.setHeader(sc.Encoder)
+	}
+	return sinks
+}
+
+// allow reports whether the sink takes an entry of priority p and status,
+// the sampling decision is made by the request id and differs between sinks
+func (s *sink) allow(p Priority, status int, id, seed string) bool {
+	if p < s.MinPriority || !inStatuses(s.Statuses, status) {
+		return false
+	}
+	return s.SampleRate <= 0 || sample(seed+s.Name, id, s.SampleRate)
+}
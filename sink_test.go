@@ -0,0 +1,126 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestSinks(t *testing.T) {
+	out, alerts, sampled := &strings.Builder{}, &strings.Builder{}, &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${status} ",
+		Output: out,
+		Sinks: []SinkConfig{
+			{Name: "alerts", Output: alerts, Format: "${path}=${status} ", MinPriority: PriorityError},
+			{Name: "sampled", Output: sampled, SampleRate: 0.5},
+		},
+	}))
+	app.Get("/ok", func(ctx *fiber.Ctx) {})
+	app.Get("/fail", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(503)
+	})
+
+	for i := 0; i < 100; i++ {
+		for _, path := range []string{"/ok", "/fail"} {
+			if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+				t.Errorf("Has: %+v, expected: nil", err)
+			}
+		}
+	}
+
+	if n := strings.Count(out.String(), " "); n != 200 {
+		t.Errorf("Has: %d, expected: 200", n)
+	}
+	if alerts.String() != strings.Repeat("/fail=503 ", 100) {
+		t.Errorf("Has: %s, expected: 100x /fail=503", alerts.String())
+	}
+	if n := strings.Count(sampled.String(), " "); n < 60 || n > 140 {
+		t.Errorf("Has: %d, expected: about 100", n)
+	}
+}
+
+func TestSinks_formats(t *testing.T) {
+	text, file := &strings.Builder{}, &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${statusColor}${status}${color:reset} ${path}\n",
+		Colors: true,
+		Output: text,
+		Sinks:  []SinkConfig{{Name: "file", Output: file, Format: FormatJSON}},
+	}))
+	app.Get("/", func(ctx *fiber.Ctx) {})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	if text.String() != "200 /\n" {
+		t.Errorf("Has: %q, expected: text without colors for a writer that is not a terminal", text.String())
+	}
+	var entry struct {
+		Status int    `json:"status"`
+		Path   string `json:"path"`
+	}
+	if err := json.Unmarshal([]byte(file.String()), &entry); err != nil || entry.Status != 200 || entry.Path != "/" {
+		t.Errorf("Has: %q, expected: the entry as JSON", file.String())
+	}
+}
+
+func TestSinks_Statuses(t *testing.T) {
+	access, errs := &strings.Builder{}, &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format:         "${status} ",
+		Output:         access,
+		OutputStatuses: []StatusRange{StatusSuccess},
+		Sinks:          []SinkConfig{{Output: errs, Statuses: []StatusRange{StatusError}}},
+	}))
+	for _, status := range []int{200, 302, 404, 503} {
+		status := status
+		app.Get("/"+strconv.Itoa(status), func(ctx *fiber.Ctx) { ctx.SendStatus(status) })
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/"+strconv.Itoa(status), nil), 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+
+	if access.String() != "200 302 " {
+		t.Errorf("Has: %q, expected: 2xx and 3xx", access.String())
+	}
+	if errs.String() != "404 503 " {
+		t.Errorf("Has: %q, expected: 4xx and 5xx", errs.String())
+	}
+}
+
+func TestOutputs(t *testing.T) {
+	text, file := &strings.Builder{}, &strings.Builder{}
+	l := NewLogger(Config{
+		Format:  "${status} ${path}\n",
+		Output:  text,
+		Outputs: []OutputConfig{{Name: "file", Output: file, Format: FormatJSON}},
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/", func(ctx *fiber.Ctx) {})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	if text.String() != "200 /\n" {
+		t.Errorf("Has: %q, expected: the text line", text.String())
+	}
+	var entry struct {
+		Status int `json:"status"`
+	}
+	if err := json.Unmarshal([]byte(file.String()), &entry); err != nil || entry.Status != 200 {
+		t.Errorf("Has: %q, expected: the entry as JSON", file.String())
+	}
+	if _, err := l.Swap("file", &strings.Builder{}); err != nil {
+		t.Errorf("Has: %v, expected: the output swapped by name", err)
+	}
+}
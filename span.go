@@ -0,0 +1,51 @@
+package logger
+
+import (
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// Span is the server span of a request
+type Span struct {
+	// Name is the method and route, e.g. "GET /users/:id"
+	Name string
+	// Trace is the trace context of the request, Trace.SpanID is the id of
+	// this span and Trace.ParentID the id of the caller's span
+	Trace TraceContext
+	// Start and End are the times the request was handled between
+	Start, End time.Time
+	// Attributes follow the OpenTelemetry HTTP semantic conventions
+	Attributes map[string]interface{}
+	// Err is the error the handler set, if any
+	Err error
+}
+
+// Tracer exports the server spans of requests. It is implemented by a small
+// adapter around an OpenTelemetry tracer or exporter, so apps without other
+// instrumentation get traces and logs from the same middleware
+type Tracer interface {
+	Export(span Span)
+}
+
+// newSpan builds the span of a handled request
+func newSpan(c *fiber.Ctx, start, stop time.Time) Span {
+	route := routePath(c)
+	return Span{
+		Name:  c.Method() + " " + route,
+		Trace: Trace(c),
+		Start: start,
+		End:   stop,
+		Attributes: map[string]interface{}{
+			"http.method":                  c.Method(),
+			"http.route":                   route,
+			"http.target":                  string(c.Fasthttp.RequestURI()),
+			"http.status_code":             c.Fasthttp.Response.StatusCode(),
+			"http.user_agent":              c.Get(fiber.HeaderUserAgent),
+			"http.response_content_length": len(c.Fasthttp.Response.Body()),
+			"net.peer.ip":                  c.IP(),
+			"request.id":                   RequestID(c),
+		},
+		Err: c.Error(),
+	}
+}
@@ -0,0 +1,53 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+type spans []Span
+
+func (s *spans) Export(span Span) {
+	*s = append(*s, span)
+}
+
+func TestTracer(t *testing.T) {
+	exported := &spans{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${traceId} ${spanId}",
+		Output: ioutil.Discard,
+		Tracer: exported,
+	}))
+	var trace TraceContext
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		trace = Trace(ctx)
+		ctx.Status(http.StatusTeapot)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("traceparent", "00-4bf92f3577b34da6a3ce929d0e0e4736-00f067aa0ba902b7-01")
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	if len(*exported) != 1 {
+		t.Fatalf("Has: %d spans, expected: 1", len(*exported))
+	}
+	span := (*exported)[0]
+	if span.Name != "GET /users/:id" {
+		t.Errorf("Has: %s, expected: GET /users/:id", span.Name)
+	}
+	if span.Trace.TraceID != trace.TraceID || span.Trace.SpanID != trace.SpanID || span.Trace.ParentID != "00f067aa0ba902b7" {
+		t.Errorf("Has: %+v, expected: %+v", span.Trace, trace)
+	}
+	if span.Attributes["http.status_code"] != http.StatusTeapot || span.Attributes["http.target"] != "/users/1" {
+		t.Errorf("Has: %v, expected: status 418 and target /users/1", span.Attributes)
+	}
+	if span.End.Before(span.Start) {
+		t.Errorf("Has: %s, expected: after %s", span.End, span.Start)
+	}
+}
@@ -0,0 +1,415 @@
+package logger
+
+import (
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// errSpoolFull is returned for entries dropped while the spool is full
+var errSpoolFull = errors.New("logger: spool full, entry dropped")
+
+// spoolHeader is the size of the header of a spool file, the offset of the
+// next entry to replay
+const spoolHeader = 8
+
+// spoolEntry is the entry of a spooled line, so it is replayed with the
+// time and the labels it was written with. Fields are the tags of the
+// writer, by their value as a string
+type spoolEntry struct {
+	Time     time.Time     `json:"time"`
+	Method   string        `json:"method,omitempty"`
+	Path     string        `json:"path,omitempty"`
+	Route    string        `json:"route,omitempty"`
+	Status   int           `json:"status,omitempty"`
+	Latency  time.Duration `json:"latency,omitempty"`
+	Priority Priority      `json:"priority,omitempty"`
+	IP       string        `json:"ip,omitempty"`
+	ID       string        `json:"id,omitempty"`
+	Fields   [][2]string   `json:"fields,omitempty"`
+}
+
+// SpoolConfig configures a SpoolWriter
+type SpoolConfig struct {
+	// Path of the spool file, created with its directory. Entries left by a
+	// previous run are replayed first
+	// Required
+	Path string
+	// MaxSize is the most bytes the spool file takes, further entries are
+	// dropped until it is replayed
+	// Optional. Default: 64 << 20 (64 MB)
+	MaxSize int64
+	// RetryInterval is the wait between attempts to replay the spool
+	// Optional. Default: 1 * time.Second
+	RetryInterval time.Duration
+}
+
+// SpoolWriter keeps entries on disk while a network output like a
+// LokiWriter or a TCP SyslogWriter fails, so an outage does not lose them.
+// Once a write fails, it and later entries are appended to the spool file
+// and replayed in order every RetryInterval until the writer takes them
+// again. Lines written with WriteEntry are spooled with the time, the
+// request fields and the tags of the writer of their entry, and replayed
+// with WriteEntry, so Loki labels, Elastic index dates and timestamps are
+// those of the request. The spool is a file of length-prefixed records
+// after the offset of the next one, updated as they are replayed, so an
+// entry is replayed twice at most after a crash. A record is the length of
+// the JSON of its entry, empty for lines written with Write, the JSON and
+// the line.
+//
+// Writers that batch in the background like the LokiWriter only fail
+// writes while their Backlog is full, failed pushes are retried by the
+// writer itself and reported by its Connected. Set a small Backlog to have
+// an outage spooled early
+type SpoolWriter struct {
+	w        io.Writer
+	cfg      SpoolConfig
+	mu       sync.Mutex
+	f        *os.File
+	size     int64
+	offset   int64
+	entries  int
+	spooling bool
+	done     chan struct{}
+	closed   chan struct{}
+	once     sync.Once
+}
+
+// NewSpoolWriter spools the entries w fails to write
+func NewSpoolWriter(w io.Writer, cfg SpoolConfig) (*SpoolWriter, error) {
+	if cfg.Path == "" {
+		return nil, errors.New("logger: spool path is required")
+	}
+	if cfg.MaxSize <= 0 {
+		cfg.MaxSize = 64 << 20
+	}
+	if cfg.RetryInterval <= 0 {
+		cfg.RetryInterval = time.Second
+	}
+	if err := os.MkdirAll(filepath.Dir(cfg.Path), 0755); err != nil {
+		return nil, err
+	}
+	f, err := os.OpenFile(cfg.Path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, err
+	}
+	s := &SpoolWriter{
+		w:      w,
+		cfg:    cfg,
+		f:      f,
+		done:   make(chan struct{}),
+		closed: make(chan struct{}),
+	}
+	if err := s.recover(); err != nil {
+		f.Close()
+		return nil, err
+	}
+	go s.run()
+	return s, nil
+}
+
+// recover reads the spool of a previous run, cutting off an entry it was
+// appending when it stopped
+func (s *SpoolWriter) recover() error {
+	fi, err := s.f.Stat()
+	if err != nil {
+		return err
+	}
+	s.size, s.offset = fi.Size(), spoolHeader
+	var header [spoolHeader]byte
+	if s.size >= spoolHeader {
+		if _, err := s.f.ReadAt(header[:], 0); err != nil {
+			return err
+		}
+		if offset := int64(binary.BigEndian.Uint64(header[:])); offset >= spoolHeader && offset <= s.size {
+			s.offset = offset
+		}
+	}
+	end := s.offset
+	for {
+		p, err := s.read(end)
+		if err != nil {
+			break
+		}
+		end += 4 + int64(len(p))
+		s.entries++
+	}
+	if end != s.size {
+		if err := s.f.Truncate(end); err != nil {
+			return err
+		}
+		s.size = end
+	}
+	s.spooling = s.offset < s.size
+	return s.writeOffset()
+}
+
+// read returns the record at offset
+func (s *SpoolWriter) read(offset int64) ([]byte, error) {
+	var prefix [4]byte
+	if _, err := s.f.ReadAt(prefix[:], offset); err != nil {
+		return nil, err
+	}
+	n := int64(binary.BigEndian.Uint32(prefix[:]))
+	if offset+4+n > s.size {
+		return nil, io.ErrUnexpectedEOF
+	}
+	p := make([]byte, n)
+	if _, err := s.f.ReadAt(p, offset+4); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// writeOffset stores the offset of the next entry to replay
+func (s *SpoolWriter) writeOffset() error {
+	var header [spoolHeader]byte
+	binary.BigEndian.PutUint64(header[:], uint64(s.offset))
+	_, err := s.f.WriteAt(header[:], 0)
+	if err == nil && s.size < spoolHeader {
+		s.size = spoolHeader
+	}
+	return err
+}
+
+// Write writes p to the writer, or to the spool while the writer fails or
+// earlier entries are spooled
+func (s *SpoolWriter) Write(p []byte) (int, error) {
+	return s.write(nil, p)
+}
+
+// WriteEntry passes e along to an EntryWriter unless p is spooled
+func (s *SpoolWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	return s.write(e, p)
+}
+
+func (s *SpoolWriter) write(e *Entry, p []byte) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if !s.spooling && s.forward(e, p) == nil {
+		return len(p), nil
+	}
+	return s.spool(e, p)
+}
+
+// forward writes the line p of e to the writer, with WriteEntry when e is
+// set and the writer takes entries
+func (s *SpoolWriter) forward(e *Entry, p []byte) error {
+	var err error
+	if ew, ok := s.w.(EntryWriter); ok && e != nil {
+		_, err = ew.WriteEntry(e, p)
+	} else {
+		_, err = s.w.Write(p)
+	}
+	return err
+}
+
+// spool appends p and its entry to the spool file, making room by dropping
+// the replayed entries when it is full
+func (s *SpoolWriter) spool(e *Entry, p []byte) (int, error) {
+	var meta []byte
+	if e != nil {
+		var err error
+		if meta, err = json.Marshal(s.spoolEntry(e)); err != nil {
+			return 0, err
+		}
+	}
+	n := 8 + int64(len(meta)) + int64(len(p))
+	if s.size+n > s.cfg.MaxSize && s.offset > spoolHeader {
+		if err := s.compact(); err != nil {
+			return 0, err
+		}
+	}
+	if s.size+n > s.cfg.MaxSize {
+		return 0, errSpoolFull
+	}
+	b := make([]byte, n)
+	binary.BigEndian.PutUint32(b, uint32(n-4))
+	binary.BigEndian.PutUint32(b[4:], uint32(len(meta)))
+	copy(b[8:], meta)
+	copy(b[8+len(meta):], p)
+	if _, err := s.f.WriteAt(b, s.size); err != nil {
+		return 0, err
+	}
+	s.size += n
+	s.entries++
+	s.spooling = true
+	return len(p), nil
+}
+
+// spoolEntry returns the fields of e kept in the spool, the fields besides
+// the tags of the writer are left out
+func (s *SpoolWriter) spoolEntry(e *Entry) *spoolEntry {
+	se := &spoolEntry{
+		Time:     e.Time,
+		Method:   e.Method,
+		Path:     e.Path,
+		Route:    e.Route,
+		Status:   e.Status,
+		Latency:  e.Latency,
+		Priority: e.Priority,
+		IP:       e.IP,
+		ID:       e.ID,
+	}
+	for _, tag := range s.Tags() {
+		if _, ok := e.Get(tag); ok {
+			se.Fields = append(se.Fields, [2]string{tag, entryValue(e, tag)})
+		}
+	}
+	return se
+}
+
+// decode splits a record into its entry, nil for lines written with Write,
+// and its line
+func decode(record []byte) (*Entry, []byte, error) {
+	if len(record) < 4 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	n := int(binary.BigEndian.Uint32(record))
+	if n > len(record)-4 {
+		return nil, nil, io.ErrUnexpectedEOF
+	}
+	if n == 0 {
+		return nil, record[4:], nil
+	}
+	var se spoolEntry
+	if err := json.Unmarshal(record[4:4+n], &se); err != nil {
+		return nil, nil, err
+	}
+	e := &Entry{
+		Time:     se.Time,
+		Method:   se.Method,
+		Path:     se.Path,
+		Route:    se.Route,
+		Status:   se.Status,
+		Latency:  se.Latency,
+		Priority: se.Priority,
+		IP:       se.IP,
+		ID:       se.ID,
+	}
+	for _, f := range se.Fields {
+		e.Fields = append(e.Fields, Field{f[0], f[1]})
+	}
+	return e, record[4+n:], nil
+}
+
+// compact moves the entries left to replay to the start of the file
+func (s *SpoolWriter) compact() error {
+	rest := make([]byte, s.size-s.offset)
+	if _, err := s.f.ReadAt(rest, s.offset); err != nil {
+		return err
+	}
+	if _, err := s.f.WriteAt(rest, spoolHeader); err != nil {
+		return err
+	}
+	s.size, s.offset = spoolHeader+int64(len(rest)), spoolHeader
+	if err := s.f.Truncate(s.size); err != nil {
+		return err
+	}
+	return s.writeOffset()
+}
+
+// run replays the spool every RetryInterval until Close
+func (s *SpoolWriter) run() {
+	ticker := time.NewTicker(s.cfg.RetryInterval)
+	defer ticker.Stop()
+	defer close(s.closed)
+	for {
+		select {
+		case <-ticker.C:
+			s.replay()
+		case <-s.done:
+			return
+		}
+	}
+}
+
+// replay writes the spooled entries in order until the writer fails or
+// the spool is empty, which is then truncated
+func (s *SpoolWriter) replay() {
+	for {
+		select {
+		case <-s.done:
+			return
+		default:
+		}
+		s.mu.Lock()
+		if !s.spooling {
+			s.mu.Unlock()
+			return
+		}
+		record, err := s.read(s.offset)
+		s.mu.Unlock()
+		// Writes are spooled meanwhile, the writer has the entry to itself.
+		// A compaction moves the entry but keeps the offset at it
+		if err != nil {
+			return
+		}
+		// Records that do not decode are skipped rather than blocking the
+		// spool
+		if e, p, err := decode(record); err == nil && s.forward(e, p) != nil {
+			return
+		}
+		s.mu.Lock()
+		s.offset += 4 + int64(len(record))
+		s.entries--
+		if s.offset == s.size {
+			s.offset, s.size, s.entries = spoolHeader, spoolHeader, 0
+			s.spooling = false
+			s.f.Truncate(spoolHeader)
+		}
+		s.writeOffset()
+		s.mu.Unlock()
+	}
+}
+
+// Spooled returns the number of bytes waiting to be replayed
+func (s *SpoolWriter) Spooled() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.size - s.offset
+}
+
+// SpooledEntries returns the number of entries waiting to be replayed
+func (s *SpoolWriter) SpooledEntries() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.entries
+}
+
+// Tags returns the tags of the writer, so they are in the entry
+func (s *SpoolWriter) Tags() []string {
+	if t, ok := s.w.(interface{ Tags() []string }); ok {
+		return t.Tags()
+	}
+	return nil
+}
+
+// Connected reports whether nothing is spooled and the writer is connected,
+// for Health
+func (s *SpoolWriter) Connected() bool {
+	s.mu.Lock()
+	spooling := s.spooling
+	s.mu.Unlock()
+	if c, ok := s.w.(interface{ Connected() bool }); ok && !spooling {
+		return c.Connected()
+	}
+	return !spooling
+}
+
+// Close stops replaying and closes the spool file, spooled entries are
+// replayed by the next SpoolWriter of Path. The writer is left open
+func (s *SpoolWriter) Close() error {
+	s.once.Do(func() {
+		close(s.done)
+	})
+	<-s.closed
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.f.Close()
+}
@@ -0,0 +1,208 @@
+package logger
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// downWriter fails writes while down is set, but for the next pass writes
+type downWriter struct {
+	mu   sync.Mutex
+	down bool
+	pass int
+	buf  strings.Builder
+}
+
+func (w *downWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.down && w.pass == 0 {
+		return 0, errors.New("connection refused")
+	}
+	if w.down {
+		w.pass--
+	}
+	return w.buf.Write(p)
+}
+
+func (w *downWriter) set(down bool) {
+	w.mu.Lock()
+	w.down = down
+	w.mu.Unlock()
+}
+
+func (w *downWriter) String() string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.buf.String()
+}
+
+// waitSpooled waits until s has no spooled entries
+func waitSpooled(t *testing.T, s *SpoolWriter) {
+	for deadline := time.Now().Add(time.Second); s.Spooled() > 0; time.Sleep(time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatalf("Has: %d bytes spooled, expected: replayed", s.Spooled())
+		}
+	}
+}
+
+func TestSpoolWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w := &downWriter{}
+	s, err := NewSpoolWriter(w, SpoolConfig{Path: filepath.Join(dir, "loki", "spool"), RetryInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer s.Close()
+	s.Write([]byte("a\n"))
+	w.set(true)
+	for _, line := range []string{"b\n", "c\n"} {
+		if _, err := s.Write([]byte(line)); err != nil {
+			t.Errorf("Has: %v, expected: nil while spooling", err)
+		}
+	}
+	if s.Connected() || s.Spooled() != 20 {
+		t.Errorf("Has: %d bytes spooled, expected: 20 and disconnected", s.Spooled())
+	}
+	w.set(false)
+	// Entries written before the spool is replayed queue behind it
+	s.Write([]byte("d\n"))
+	waitSpooled(t, s)
+	s.Write([]byte("e\n"))
+	if w.String() != "a\nb\nc\nd\ne\n" {
+		t.Errorf("Has: %q, expected: entries in order", w.String())
+	}
+	if !s.Connected() {
+		t.Errorf("Has: false, expected: connected after replay")
+	}
+	if fi, _ := os.Stat(filepath.Join(dir, "loki", "spool")); fi.Size() != spoolHeader {
+		t.Errorf("Has: %d, expected: truncated spool", fi.Size())
+	}
+}
+
+func TestSpoolWriter_restart(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "spool")
+	w := &downWriter{down: true}
+	s, err := NewSpoolWriter(w, SpoolConfig{Path: path, RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	s.Write([]byte("a\n"))
+	s.Write([]byte("b\n"))
+	s.Close()
+	// A crash while appending leaves a partial entry
+	f, _ := os.OpenFile(path, os.O_WRONLY|os.O_APPEND, 0)
+	f.Write([]byte{0, 0, 0, 9, 'c'})
+	f.Close()
+
+	w.set(false)
+	s, err = NewSpoolWriter(w, SpoolConfig{Path: path, RetryInterval: 5 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer s.Close()
+	waitSpooled(t, s)
+	if w.String() != "a\nb\n" {
+		t.Errorf("Has: %q, expected: spool of the previous run", w.String())
+	}
+}
+
+func TestSpoolWriter_MaxSize(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w := &downWriter{down: true}
+	s, err := NewSpoolWriter(w, SpoolConfig{Path: filepath.Join(dir, "spool"), MaxSize: spoolHeader + 20, RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer s.Close()
+	for i, line := range []string{"a\n", "b\n", "c\n"} {
+		if _, err := s.Write([]byte(line)); (err == errSpoolFull) != (i == 2) {
+			t.Errorf("Has: %v, expected: full at the third entry", err)
+		}
+	}
+	// A replayed entry makes room
+	w.mu.Lock()
+	w.pass = 1
+	w.mu.Unlock()
+	s.replay()
+	if _, err := s.Write([]byte("d\n")); err != nil {
+		t.Errorf("Has: %v, expected: nil after compaction", err)
+	}
+	w.set(false)
+	s.replay()
+	if w.String() != "a\nb\nd\n" {
+		t.Errorf("Has: %q, expected: a b d", w.String())
+	}
+}
+
+func TestNewSpoolWriter_Path(t *testing.T) {
+	if _, err := NewSpoolWriter(&downWriter{}, SpoolConfig{}); err == nil {
+		t.Errorf("Has: nil, expected: an error without Path")
+	}
+}
+
+// entryDownWriter is a downWriter taking entries, it records their time
+// and route label
+type entryDownWriter struct {
+	downWriter
+	entries []string
+}
+
+func (w *entryDownWriter) WriteEntry(e *Entry, p []byte) (int, error) {
+	n, err := w.Write(p)
+	if err == nil {
+		w.mu.Lock()
+		w.entries = append(w.entries, e.Time.Format(time.RFC3339)+" "+e.Route+" "+e.getString("header:x-tenant"))
+		w.mu.Unlock()
+	}
+	return n, err
+}
+
+func (w *entryDownWriter) Tags() []string {
+	return []string{"header:x-tenant"}
+}
+
+func TestSpoolWriter_entries(t *testing.T) {
+	dir, err := ioutil.TempDir("", "spool")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	w := &entryDownWriter{downWriter: downWriter{down: true}}
+	s, err := NewSpoolWriter(w, SpoolConfig{Path: filepath.Join(dir, "spool"), RetryInterval: time.Hour})
+	if err != nil {
+		t.Fatalf("Has: %v, expected: nil", err)
+	}
+	defer s.Close()
+	e := &Entry{
+		Time:   time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC),
+		Route:  "/api/:id",
+		Fields: []Field{{"header:x-tenant", "acme"}, {"ua", "curl"}},
+	}
+	s.WriteEntry(e, []byte("a\n"))
+	w.set(false)
+	s.replay()
+	// The entry is replayed with its time and the tags of the writer
+	if len(w.entries) != 1 || w.entries[0] != "2020-01-01T12:00:00Z /api/:id acme" {
+		t.Errorf("Has: %q, expected: the spooled entry", w.entries)
+	}
+}
@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"github.com/gofiber/fiber"
+)
+
+// Locals keys read by the file tags, handlers serving files from disk can
+// set them directly or through SetFile
+const (
+	LocalsFilePath = "logger.filePath" // string
+	LocalsFileSize = "logger.fileSize" // int64
+)
+
+// SetFile records the file on disk a handler served for the request
+func SetFile(c *fiber.Ctx, path string, size int64) {
+	c.Locals(LocalsFilePath, path)
+	c.Locals(LocalsFileSize, size)
+}
+
+// SendFile sends the file like c.SendFile and records it with SetFile
+func SendFile(c *fiber.Ctx, path string) {
+	c.SendFile(path)
+	if c.Fasthttp.Response.StatusCode() == fiber.StatusOK {
+		SetFile(c, path, int64(c.Fasthttp.Response.Header.ContentLength()))
+	}
+}
+
+func filePath(c *fiber.Ctx) string {
+	path, _ := c.Locals(LocalsFilePath).(string)
+	return path
+}
+
+func fileSize(c *fiber.Ctx) (int64, bool) {
+	size, ok := c.Locals(LocalsFileSize).(int64)
+	return size, ok
+}
@@ -0,0 +1,44 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestSendFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "logger")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "app.js")
+	if err := ioutil.WriteFile(file, []byte("console.log(1)"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${filePath} ${fileSize}",
+		Output: buf,
+	}))
+	app.Get("/assets/app.js", func(ctx *fiber.Ctx) {
+		SendFile(ctx, file)
+	})
+
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/assets/app.js", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := file + " 14"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
@@ -0,0 +1,36 @@
+package logger
+
+import "sync/atomic"
+
+// Stats counts what the logger lost or failed to write, so operators can
+// alert on an overflowing queue or a failing output instead of finding
+// gaps in the logs later
+type Stats struct {
+	// Dropped is the number of entries dropped from the full Async queue
+	Dropped uint64 `json:"dropped"`
+	// WriteErrors is the number of failed writes over all outputs
+	WriteErrors int64 `json:"writeErrors"`
+	// BytesWritten is the number of bytes written over all outputs
+	BytesWritten int64 `json:"bytesWritten"`
+	// QueueDepth is the number of entries waiting in the Async queue
+	QueueDepth int `json:"queueDepth"`
+	// QueueSize is the capacity of the Async queue, 0 without Async
+	QueueSize int `json:"queueSize"`
+}
+
+// Stats returns the counters of the logger since it was created, per
+// output they are in Health
+func (l *Logger) Stats() Stats {
+	s := Stats{Dropped: atomic.LoadUint64(&l.dropped)}
+	for _, o := range l.outputs() {
+		o.mu.Lock()
+		s.WriteErrors += o.errors
+		s.BytesWritten += o.bytes
+		o.mu.Unlock()
+	}
+	if l.async != nil {
+		s.QueueDepth = l.async.queue.len()
+		s.QueueSize = l.cfg.AsyncQueueSize
+	}
+	return s
+}
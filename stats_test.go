@@ -0,0 +1,56 @@
+package logger
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestLogger_Stats(t *testing.T) {
+	out := &gatedWriter{open: make(chan struct{})}
+	l := NewLogger(Config{
+		Format:         "${path}\n",
+		Output:         out,
+		Async:          true,
+		AsyncQueueSize: 2,
+		OverflowPolicy: OverflowDropNewest,
+		Sinks:          []SinkConfig{{Output: failingWriter{}}},
+		Diagnostics:    ioutil.Discard,
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {})
+	for i, path := range []string{"/1", "/2", "/3", "/4"} {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, path, nil), 1000); err != nil {
+			t.Fatalf("Has: %+v, expected: nil", err)
+		}
+		for i == 0 && l.async.queue.len() > 0 {
+			time.Sleep(time.Millisecond)
+		}
+	}
+	if s := l.Stats(); s != (Stats{Dropped: 1, WriteErrors: 4, QueueDepth: 2, QueueSize: 2}) {
+		t.Errorf("Has: %+v, expected: a full queue and failing sink", s)
+	}
+	close(out.open)
+	l.Close()
+	if s := l.Stats(); s != (Stats{Dropped: 1, WriteErrors: 4, BytesWritten: 9, QueueSize: 2}) {
+		t.Errorf("Has: %+v, expected: 9 bytes written", s)
+	}
+}
+
+func TestLogger_StatsSync(t *testing.T) {
+	l := NewLogger(Config{Format: "${path}\n", Output: ioutil.Discard})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/abc", nil), 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	if s := l.Stats(); s != (Stats{BytesWritten: 5}) {
+		t.Errorf("Has: %+v, expected: 5 bytes written", s)
+	}
+}
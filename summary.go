@@ -0,0 +1,101 @@
+package logger
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// summaryInterval is how often the summaries of elapsed windows are checked
+// for, so they are written without waiting for a later entry
+var summaryInterval = time.Second
+
+// summaryWriter writes the summaries of windows like the aggregate lines
+type summaryWriter interface {
+	// writeSummary writes the summary e, its summary field names the kind.
+	// message is the summary as text
+	writeSummary(e *Entry, message string)
+}
+
+// textSummaries writes summaries as text lines after their time
+type textSummaries struct {
+	w          io.Writer
+	timeFormat string
+}
+
+func (t textSummaries) writeSummary(e *Entry, message string) {
+	fmt.Fprintf(t.w, "%s %s\n", e.Time.Format(t.timeFormat), message)
+}
+
+// writeSummary writes a summary to Output, as a text line after the time
+// for text formats and as an entry of the Encoder otherwise, so summaries
+// do not break structured streams. The text is its message field
+func (l *Logger) writeSummary(e *Entry, message string) {
+	if l.cfg.Encoder == nil {
+		textSummaries{l.out, l.cfg.TimeFormat}.writeSummary(e, message)
+		return
+	}
+	e.Fields = append(e.Fields, Field{"message", message})
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	if err := l.cfg.Encoder.Encode(buf, e); err != nil {
+		l.diag(LevelError, "encoding summary: %v", err)
+		return
+	}
+	l.out.writeEntry(e, buf.B)
+}
+
+// summarizer is a windowed summary like the aggregate lines, the first n
+// counts, budgets and the cache and throttle reports
+type summarizer interface {
+	tick(now time.Time, all bool, w summaryWriter)
+}
+
+// summarizers returns the windowed summaries of the logger
+func (l *Logger) summarizers() []summarizer {
+	var s []summarizer
+	if l.errs != nil {
+		s = append(s, l.errs)
+	}
+	if l.first != nil {
+		s = append(s, l.first)
+	}
+	for _, b := range l.budgets {
+		s = append(s, b)
+	}
+	if l.cache != nil {
+		s = append(s, l.cache)
+	}
+	if l.throttles != nil {
+		s = append(s, l.throttles)
+	}
+	return s
+}
+
+// summarize writes the summaries of elapsed windows every summaryInterval
+// until Close
+func (l *Logger) summarize(summaries []summarizer) {
+	ticker := time.NewTicker(summaryInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			now := l.cfg.Clock.Now()
+			for _, s := range summaries {
+				s.tick(now, false, l)
+			}
+		case <-l.done:
+			return
+		}
+	}
+}
+
+// flushSummaries writes the summaries of the current windows on Close
+func (l *Logger) flushSummaries() {
+	now := l.cfg.Clock.Now()
+	for _, s := range l.summarizers() {
+		s.tick(now, true, l)
+	}
+}
@@ -0,0 +1,135 @@
+package logger
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// manualClock is advanced by the test, the summaries read it concurrently
+type manualClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *manualClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *manualClock) advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// syncBuilder is a strings.Builder written by the summaries concurrently
+type syncBuilder struct {
+	mu sync.Mutex
+	b  strings.Builder
+}
+
+func (b *syncBuilder) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.Write(p)
+}
+
+func (b *syncBuilder) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.b.String()
+}
+
+func TestLogger_summarize(t *testing.T) {
+	defer func(interval time.Duration) { summaryInterval = interval }(summaryInterval)
+	summaryInterval = time.Millisecond
+	clock := &manualClock{now: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)}
+	out := &syncBuilder{}
+	l := NewLogger(Config{
+		Format:          "${path}\n",
+		Output:          out,
+		Clock:           clock,
+		AggregateErrors: true,
+	})
+	defer l.Close()
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(502)
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/api/foo", nil), 1000); err != nil {
+			t.Fatalf("Has: %+v, expected: nil", err)
+		}
+	}
+	// No request follows the window, the ticker writes its summary
+	clock.advance(time.Minute + time.Second)
+	expected := "/api/foo\n12:01:01 /* 502 x3 in last 1m0s\n"
+	for start := time.Now(); out.String() != expected && time.Since(start) < time.Second; {
+		time.Sleep(time.Millisecond)
+	}
+	if out.String() != expected {
+		t.Errorf("Has: %q, expected: %q", out.String(), expected)
+	}
+}
+
+func TestLogger_CloseSummaries(t *testing.T) {
+	out := &strings.Builder{}
+	l := NewLogger(Config{
+		Format:         "${path}\n",
+		Output:         out,
+		Clock:          &manualClock{now: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)},
+		ThrottleReport: time.Minute,
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(429)
+	})
+	if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+		t.Fatalf("Has: %+v, expected: nil", err)
+	}
+	l.Close()
+	if !strings.HasSuffix(out.String(), " 429 x1 in last 1m0s\n") {
+		t.Errorf("Has: %q, expected: the throttle report written on Close", out.String())
+	}
+}
+
+func TestLogger_summaryEncoded(t *testing.T) {
+	out := &strings.Builder{}
+	l := NewLogger(Config{
+		Format:          FormatJSON,
+		Output:          out,
+		Clock:           &manualClock{now: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)},
+		AggregateErrors: true,
+	})
+	app := fiber.New()
+	app.Use(l.Handle)
+	app.Get("/*", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(502)
+	})
+	for i := 0; i < 3; i++ {
+		if _, err := app.Test(httptest.NewRequest(http.MethodGet, "/", nil), 1000); err != nil {
+			t.Fatalf("Has: %+v, expected: nil", err)
+		}
+	}
+	l.Close()
+	lines := strings.Split(strings.TrimSuffix(out.String(), "\n"), "\n")
+	var summary struct {
+		Summary string `json:"summary"`
+		Count   int    `json:"count"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(lines[len(lines)-1]), &summary); err != nil ||
+		summary.Summary != "aggregate" || summary.Count != 3 || summary.Message != "/* 502 x3 in last 1m0s" {
+		t.Errorf("Has: %q, expected: the summary as a JSON entry", out.String())
+	}
+}
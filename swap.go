@@ -0,0 +1,35 @@
+package logger
+
+import (
+	"errors"
+	"io"
+)
+
+// Swap replaces the writer of the output or sink named name, "output" for
+// Output and "rollup" for RollupOutput, and returns the previous one. Writes
+// in flight finish on the previous writer before Swap returns and later ones
+// go to w, so no entry is lost or reordered when it is closed afterwards,
+// e.g. to rotate credentials or move to a new endpoint
+func (l *Logger) Swap(name string, w io.Writer) (io.Writer, error) {
+	if w == nil {
+		return nil, errors.New("logger: swap to a nil writer")
+	}
+	for _, o := range l.outputs() {
+		if o.name == name {
+			return o.replace(w, l.cfg.Colors), nil
+		}
+	}
+	return nil, errors.New("logger: no output named " + name)
+}
+
+// replace sets the writer of the output once the writes in flight are done
+// and returns the previous one
+func (o *output) replace(w io.Writer, colors bool) io.Writer {
+	o.swap.Lock()
+	defer o.swap.Unlock()
+	old := o.w
+	o.w = w
+	o.strip = colors && !isTerminal(w)
+	o.resetHeader()
+	return old
+}
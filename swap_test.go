@@ -0,0 +1,82 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/fasthttp"
+)
+
+// closableWriter counts lines and records writes after Close
+type closableWriter struct {
+	mu     sync.Mutex
+	lines  int
+	closed bool
+	late   int
+}
+
+func (w *closableWriter) Write(p []byte) (int, error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.closed {
+		w.late++
+	}
+	w.lines += strings.Count(string(p), "\n")
+	return len(p), nil
+}
+
+func (w *closableWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.closed = true
+	return nil
+}
+
+func TestLogger_Swap(t *testing.T) {
+	old, next := &closableWriter{}, &closableWriter{}
+	l := NewLogger(Config{
+		Format: "${status}\n",
+		Output: ioutil.Discard,
+		Sinks:  []SinkConfig{{Name: "archive", Output: old}},
+	})
+	// app.Test is not safe for concurrent use, each goroutine runs the
+	// handler on a context of its own
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			fctx := &fasthttp.RequestCtx{}
+			for j := 0; j < 25; j++ {
+				fctx.Request.Reset()
+				fctx.Response.Reset()
+				fctx.Request.SetRequestURI("/")
+				c := fiber.AcquireCtx(fctx)
+				l.Handle(c)
+				fiber.ReleaseCtx(c)
+			}
+		}()
+	}
+	previous, err := l.Swap("archive", next)
+	if err != nil {
+		t.Errorf("Has: %v, expected: nil", err)
+	}
+	previous.(*closableWriter).Close()
+	wg.Wait()
+
+	if previous != old {
+		t.Errorf("Has: %v, expected: the previous writer", previous)
+	}
+	if old.late != 0 {
+		t.Errorf("Has: %d writes after Close, expected: 0", old.late)
+	}
+	if n := old.lines + next.lines; n != 100 {
+		t.Errorf("Has: %d lines, expected: 100 across both writers", n)
+	}
+	if _, err := l.Swap("missing", next); err == nil {
+		t.Errorf("Has: nil, expected: an error for an unknown output")
+	}
+}
@@ -0,0 +1,267 @@
+package logger
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"sync"
+
+	"github.com/valyala/bytebufferpool"
+)
+
+// FormatSyslog as Format writes entries with a SyslogEncoder
+const FormatSyslog = "syslog"
+
+// syslogTimeFormat is the RFC 5424 timestamp with microseconds
+const syslogTimeFormat = "2006-01-02T15:04:05.000000Z07:00"
+
+// SyslogEncoder writes an entry as an RFC 5424 syslog message. The message
+// is the method, path and status, the standard keys and the tags in Fields
+// are parameters of a structured data element
+type SyslogEncoder struct {
+	// Facility of the messages, e.g. 16 for local0
+	// Optional. Default: 16 (local0)
+	Facility int
+	// Severity maps a status to a severity
+	// Optional. Default: 3 (error) for 5xx, 4 (warning) for 4xx, 6 (info)
+	Severity func(status int) int
+	// Host is the HOSTNAME of the messages
+	// Optional. Default: os.Hostname()
+	Host string
+	// AppName is the APP-NAME of the messages
+	// Optional. Default: the name of the executable
+	AppName string
+	// MsgID is the MSGID of the messages
+	// Optional. Default: "access"
+	MsgID string
+	// SDID is the id of the structured data element
+	// Optional. Default: "http@32473"
+	SDID string
+	// Fields are tags written as parameters, e.g. {"ua", "requestId"}
+	// Optional. Default: nil
+	Fields []string
+}
+
+// procID is the PROCID of syslog messages
+var procID = strconv.Itoa(os.Getpid())
+
+// Tags returns Fields, the tags the encoder writes
+func (s *SyslogEncoder) Tags() []string {
+	return s.Fields
+}
+
+// Encode writes e as a line of syslog, SyslogWriter drops the newline when
+// framing the message
+func (s *SyslogEncoder) Encode(buf *bytebufferpool.ByteBuffer, e *Entry) error {
+	facility, severity := s.Facility, statusSeverity
+	if facility == 0 {
+		facility = 16
+	}
+	if s.Severity != nil {
+		severity = s.Severity
+	}
+	buf.WriteString("<")
+	buf.B = strconv.AppendInt(buf.B, int64(facility*8+severity(e.Status)), 10)
+	buf.WriteString(">1 ")
+	buf.B = e.Time.AppendFormat(buf.B, syslogTimeFormat)
+	appendSyslogHeader(buf, s.Host, hostname, 255)
+	appendSyslogHeader(buf, s.AppName, filepath.Base(os.Args[0]), 48)
+	appendSyslogHeader(buf, procID, "", 128)
+	appendSyslogHeader(buf, s.MsgID, "access", 32)
+	sdid := s.SDID
+	if sdid == "" {
+		sdid = "http@32473"
+	}
+	buf.WriteString(" [" + sdid)
+	appendSyslogParam(buf, "method", e.Method)
+	appendSyslogParam(buf, "path", e.Path)
+	appendSyslogParam(buf, "route", e.Route)
+	appendSyslogParam(buf, "status", strconv.Itoa(e.Status))
+	appendSyslogParam(buf, "latency_ms", strconv.FormatFloat(milliseconds(e.Latency), 'f', -1, 64))
+	appendSyslogParam(buf, "ip", e.IP)
+	appendSyslogParam(buf, "request_id", e.ID)
+	if e.Err != nil {
+		appendSyslogParam(buf, "error", e.Err.Error())
+	}
+	for _, f := range e.Fields {
+		if knownTag(f.Key) && !contains(s.Fields, f.Key) {
+			continue
+		}
+		value, ok := f.Value.(string)
+		if !ok && f.Value != nil {
+			value = fmt.Sprint(f.Value)
+		}
+		appendSyslogParam(buf, f.Key, value)
+	}
+	buf.WriteString("] ")
+	foldEscaper.WriteString(buf, e.Method+" "+e.Path+" "+strconv.Itoa(e.Status))
+	buf.WriteString("\n")
+	return nil
+}
+
+// appendSyslogHeader writes a header field of at most max printable
+// characters, the nil value "-" when empty
+func appendSyslogHeader(buf *bytebufferpool.ByteBuffer, value, def string, max int) {
+	if value == "" {
+		value = def
+	}
+	buf.WriteString(" ")
+	if value == "" {
+		buf.WriteString("-")
+		return
+	}
+	for i := 0; i < len(value) && i < max; i++ {
+		if c := value[i]; c > ' ' && c < 0x7f {
+			buf.B = append(buf.B, c)
+		} else {
+			buf.B = append(buf.B, '_')
+		}
+	}
+}
+
+// appendSyslogParam writes a structured data parameter, leaving it out when
+// value is empty. Names are limited to 32 printable characters without
+// '=', ' ', ']' and '"', values escape '"', '\' and ']' and line breaks
+// as \n and \r to keep the message on one line
+func appendSyslogParam(buf *bytebufferpool.ByteBuffer, name, value string) {
+	if value == "" {
+		return
+	}
+	buf.WriteString(" ")
+	for i := 0; i < len(name) && i < 32; i++ {
+		switch c := name[i]; {
+		case c <= ' ' || c >= 0x7f || c == '=' || c == ']' || c == '"':
+			buf.B = append(buf.B, '_')
+		default:
+			buf.B = append(buf.B, c)
+		}
+	}
+	buf.WriteString(`="`)
+	for i := 0; i < len(value); i++ {
+		switch c := value[i]; c {
+		case '"', '\\', ']':
+			buf.B = append(buf.B, '\\', c)
+		case '\n':
+			buf.B = append(buf.B, '\\', 'n')
+		case '\r':
+			buf.B = append(buf.B, '\\', 'r')
+		default:
+			buf.B = append(buf.B, c)
+		}
+	}
+	buf.WriteString(`"`)
+}
+
+// syslogSockets are the local syslog sockets tried in order
+var syslogSockets = []string{"/dev/log", "/var/run/syslog", "/var/run/log"}
+
+// SyslogConfig configures a SyslogWriter
+type SyslogConfig struct {
+	// Network is "udp", "tcp" or "" for the local syslog socket
+	// Optional. Default: ""
+	Network string
+	// Address of the syslog server, e.g. "syslog:514"
+	// Optional. Default: the local socket with Network ""
+	Address string
+	// TLS enables TLS for "tcp" as in RFC 5425
+	// Optional. Default: nil
+	TLS *tls.Config
+}
+
+// SyslogWriter sends syslog messages, each write being one message. Over
+// TCP messages are framed by octet counting and the connection is redialed
+// after a failure
+type SyslogWriter struct {
+	cfg  SyslogConfig
+	mu   sync.Mutex
+	conn net.Conn
+}
+
+// NewSyslogWriter connects to the syslog server of cfg
+func NewSyslogWriter(cfg SyslogConfig) (*SyslogWriter, error) {
+	if cfg.Network != "" && cfg.Network != "udp" && cfg.Network != "tcp" {
+		return nil, errors.New("logger: syslog network must be udp, tcp or empty")
+	}
+	w := &SyslogWriter{cfg: cfg}
+	if err := w.dial(); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+func (w *SyslogWriter) dial() error {
+	if w.cfg.Network != "" {
+		conn, err := dial(w.cfg.Network, w.cfg.Address, w.cfg.TLS)
+		if err != nil {
+			return err
+		}
+		w.conn = conn
+		return nil
+	}
+	sockets := syslogSockets
+	if w.cfg.Address != "" {
+		sockets = []string{w.cfg.Address}
+	}
+	for _, socket := range sockets {
+		for _, network := range []string{"unixgram", "unix"} {
+			if conn, err := net.Dial(network, socket); err == nil {
+				w.conn = conn
+				return nil
+			}
+		}
+	}
+	return errors.New("logger: no local syslog socket")
+}
+
+// Write sends p as a single message, a trailing newline is dropped
+func (w *SyslogWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if n > 0 && p[n-1] == '\n' {
+		p = p[:n-1]
+	}
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		if err := w.dial(); err != nil {
+			return 0, err
+		}
+	}
+	var err error
+	if w.cfg.Network == "tcp" {
+		bufs := net.Buffers{[]byte(strconv.Itoa(len(p)) + " "), p}
+		_, err = bufs.WriteTo(w.conn)
+	} else {
+		_, err = w.conn.Write(p)
+	}
+	if err != nil {
+		if w.cfg.Network != "udp" {
+			w.conn.Close()
+			w.conn = nil
+		}
+		return 0, err
+	}
+	return n, nil
+}
+
+// Connected reports whether the writer holds a connection, for Health
+func (w *SyslogWriter) Connected() bool {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.conn != nil
+}
+
+// Close closes the connection
+func (w *SyslogWriter) Close() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.conn == nil {
+		return nil
+	}
+	err := w.conn.Close()
+	w.conn = nil
+	return err
+}
@@ -0,0 +1,108 @@
+package logger
+
+import (
+	"io"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+)
+
+func TestSyslogEncoder(t *testing.T) {
+	buf := &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Output: buf,
+		Clock:  &stepClock{now: time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC), step: 5 * time.Millisecond},
+		Encoder: &SyslogEncoder{
+			Facility: 1,
+			Host:     "web-1",
+			AppName:  "shop",
+			Fields:   []string{"header:X-Tenant"},
+		},
+	}))
+	app.Get("/users/:id", func(ctx *fiber.Ctx) {
+		ctx.SendStatus(404)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/users/1", nil)
+	req.Header.Set("X-Tenant", `a"b]`)
+	if _, err := app.Test(req, 1000); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+
+	expectedOutput := `<12>1 2020-01-01T12:00:00.015000Z web-1 shop ` + procID + ` access [http@32473 method="GET" path="/users/1" route="/users/:id" status="404" latency_ms="5" ip="0.0.0.0" header:X-Tenant="a\"b\]"] GET /users/1 404` + "\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
+
+func TestSyslogWriter_tcp(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	w, err := NewSyslogWriter(SyslogConfig{Network: "tcp", Address: ln.Addr().String()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	conn, err := ln.Accept()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	w.Write([]byte("<134>1 - - - - - - a\n"))
+	w.Write([]byte("<134>1 - - - - - - bc\n"))
+	expected := "20 <134>1 - - - - - - a21 <134>1 - - - - - - bc"
+	p := make([]byte, len(expected))
+	if _, err := io.ReadFull(conn, p); err != nil || string(p) != expected {
+		t.Errorf("Has: %q (%v), expected: %q", p, err, expected)
+	}
+}
+
+func TestSyslogWriter_local(t *testing.T) {
+	dir, err := ioutil.TempDir("", "syslog")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	socket := filepath.Join(dir, "log")
+	conn, err := net.ListenPacket("unixgram", socket)
+	if err != nil {
+		t.Skip("unixgram not supported")
+	}
+	defer conn.Close()
+	w, err := NewSyslogWriter(SyslogConfig{Address: socket})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+
+	w.Write([]byte("<134>1 - - - - - - a\n"))
+	p := make([]byte, 64)
+	n, _, err := conn.ReadFrom(p)
+	if err != nil || string(p[:n]) != "<134>1 - - - - - - a" {
+		t.Errorf("Has: %q (%v), expected: message without newline", p[:n], err)
+	}
+}
+
+func TestAppendSyslogParam(t *testing.T) {
+	buf := bytebufferpool.Get()
+	defer bytebufferpool.Put(buf)
+	appendSyslogParam(buf, "a b=c", "x\"]\\\r\ny")
+	expected := ` a_b_c="x\"\]\\\r\ny"`
+	if buf.String() != expected {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expected)
+	}
+}
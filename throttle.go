@@ -0,0 +1,71 @@
+package logger
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+)
+
+// throttleReport counts 429 responses per client and reports them once the
+// interval has elapsed
+type throttleReport struct {
+	mu       sync.Mutex
+	interval time.Duration
+	reset    time.Time
+	counts   map[string]int
+}
+
+func newThrottleReport(interval time.Duration) *throttleReport {
+	return &throttleReport{
+		interval: interval,
+		counts:   make(map[string]int),
+	}
+}
+
+// add counts a response. When a new interval starts the counts of the
+// previous one are written to w
+func (r *throttleReport) add(client string, status int, now time.Time, w summaryWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if now.After(r.reset) {
+		r.flush(w, now)
+		r.reset = now.Add(r.interval)
+	}
+	if status == 429 {
+		r.counts[client]++
+	}
+}
+
+// tick writes the counts of an interval that elapsed without a later
+// response, the next response starts a new interval. With all the current
+// one is written, as on Close
+func (r *throttleReport) tick(now time.Time, all bool, w summaryWriter) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if all || !r.reset.IsZero() && now.After(r.reset) {
+		r.flush(w, now)
+		r.reset = time.Time{}
+	}
+}
+
+// flush writes one line per throttled client, most throttled first, and
+// starts counting from zero
+func (r *throttleReport) flush(w summaryWriter, now time.Time) {
+	clients := make([]string, 0, len(r.counts))
+	for client := range r.counts {
+		clients = append(clients, client)
+	}
+	sort.Slice(clients, func(i, j int) bool {
+		if r.counts[clients[i]] != r.counts[clients[j]] {
+			return r.counts[clients[i]] > r.counts[clients[j]]
+		}
+		return clients[i] < clients[j]
+	})
+	for _, client := range clients {
+		w.writeSummary(&Entry{Time: now, IP: client, Fields: []Field{{"summary", "throttleReport"}, {"count", r.counts[client]},
+			{"window", r.interval.String()}}},
+			fmt.Sprintf("%s 429 x%d in last %s", client, r.counts[client], r.interval))
+	}
+	r.counts = make(map[string]int, len(r.counts))
+}
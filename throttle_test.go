@@ -0,0 +1,29 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestThrottleReport_add(t *testing.T) {
+	buf := &strings.Builder{}
+	r := newThrottleReport(time.Minute)
+	now := time.Date(2020, 1, 1, 12, 0, 0, 0, time.UTC)
+
+	r.add("10.0.0.1", 429, now, textSummaries{buf, "15:04:05"})
+	for i := 0; i < 3; i++ {
+		r.add("10.0.0.2", 429, now, textSummaries{buf, "15:04:05"})
+	}
+	r.add("10.0.0.3", 200, now, textSummaries{buf, "15:04:05"})
+	if buf.Len() != 0 {
+		t.Errorf("Has: %s, expected: empty", buf.String())
+	}
+
+	r.add("10.0.0.1", 200, now.Add(2*time.Minute), textSummaries{buf, "15:04:05"})
+	expectedOutput := "12:02:00 10.0.0.2 429 x3 in last 1m0s\n" +
+		"12:02:00 10.0.0.1 429 x1 in last 1m0s\n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+}
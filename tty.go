@@ -0,0 +1,36 @@
+package logger
+
+import (
+	"io"
+	"os"
+)
+
+// isTerminal reports whether w is a terminal
+func isTerminal(w io.Writer) bool {
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// statusSymbol returns a marker for scanning a busy terminal, followed by
+// a space
+func statusSymbol(status int, p Priority) string {
+	switch {
+	case status >= 500:
+		return "✗ "
+	case status == 429:
+		return "⏳ "
+	case status >= 400:
+		return "⚠ "
+	case p == PrioritySlow:
+		return "🐢 "
+	case status >= 300:
+		return "→ "
+	case status >= 200:
+		return "✓ "
+	}
+	return ""
+}
@@ -0,0 +1,24 @@
+package logger
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStatusSymbol(t *testing.T) {
+	if s := statusSymbol(200, PriorityOK); s != "✓ " {
+		t.Errorf("Has: %s, expected: ✓", s)
+	}
+	if s := statusSymbol(200, PrioritySlow); s != "🐢 " {
+		t.Errorf("Has: %s, expected: 🐢", s)
+	}
+	if s := statusSymbol(503, PriorityError); s != "✗ " {
+		t.Errorf("Has: %s, expected: ✗", s)
+	}
+	if s := statusSymbol(429, PriorityOK); s != "⏳ " {
+		t.Errorf("Has: %s, expected: ⏳", s)
+	}
+	if isTerminal(&strings.Builder{}) {
+		t.Errorf("Has: true, expected: false")
+	}
+}
@@ -0,0 +1,67 @@
+package logger
+
+import (
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+// Locals keys read by the upstream tags, proxy handlers can set them
+// directly or through SetUpstream
+const (
+	LocalsUpstreamAddr    = "logger.upstreamAddr"    // string
+	LocalsUpstreamStatus  = "logger.upstreamStatus"  // int
+	LocalsUpstreamLatency = "logger.upstreamLatency" // time.Duration
+	LocalsAttempts        = "logger.attempts"        // []string
+)
+
+// SetUpstream records the upstream a proxy handler forwarded the request to,
+// its response status and how long it took
+func SetUpstream(c *fiber.Ctx, addr string, status int, latency time.Duration) {
+	c.Locals(LocalsUpstreamAddr, addr)
+	c.Locals(LocalsUpstreamStatus, status)
+	c.Locals(LocalsUpstreamLatency, latency)
+}
+
+// AddAttempt records an attempt to reach upstream, proxy handlers call it
+// once per try so retries show up in ${attempts} and ${retriedUpstreams}
+func AddAttempt(c *fiber.Ctx, upstream string) {
+	attempts, _ := c.Locals(LocalsAttempts).([]string)
+	c.Locals(LocalsAttempts, append(attempts, upstream))
+}
+
+func upstreamAddr(c *fiber.Ctx) string {
+	addr, _ := c.Locals(LocalsUpstreamAddr).(string)
+	return addr
+}
+
+func upstreamStatus(c *fiber.Ctx) string {
+	if status, ok := c.Locals(LocalsUpstreamStatus).(int); ok {
+		return strconv.Itoa(status)
+	}
+	return ""
+}
+
+func upstreamLatency(c *fiber.Ctx) string {
+	if latency, ok := c.Locals(LocalsUpstreamLatency).(time.Duration); ok {
+		return latency.String()
+	}
+	return ""
+}
+
+func attempts(c *fiber.Ctx) string {
+	if attempts, ok := c.Locals(LocalsAttempts).([]string); ok {
+		return strconv.Itoa(len(attempts))
+	}
+	return ""
+}
+
+// retriedUpstreams lists all attempted upstreams but the last one
+func retriedUpstreams(c *fiber.Ctx) string {
+	if attempts, ok := c.Locals(LocalsAttempts).([]string); ok && len(attempts) > 1 {
+		return strings.Join(attempts[:len(attempts)-1], ",")
+	}
+	return ""
+}
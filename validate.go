@@ -0,0 +1,88 @@
+package logger
+
+import (
+	"errors"
+	"strconv"
+	"strings"
+
+	"github.com/gofiber/fiber"
+	"github.com/valyala/bytebufferpool"
+	"github.com/valyala/fasthttp"
+)
+
+// knownTag reports whether tag is a variable the logger can render
+func knownTag(tag string) bool {
+	for _, t := range tags {
+		if tag == t || (strings.HasSuffix(t, ":") && strings.HasPrefix(tag, t)) {
+			return true
+		}
+	}
+	return false
+}
+
+// Validate checks the configuration and writes a test entry for a synthetic
+// request to the output, so problems like an unknown tag or an unwritable
+// output show at startup rather than under traffic
+func (l *Logger) Validate() error {
+	var problems []string
+	for _, tag := range l.tags() {
+		if !knownTag(tag) && !(l.cfg.FiberCompat && contains(fiberColors, tag)) {
+			problems = append(problems, "unknown tag ${"+tag+"}")
+		}
+	}
+	for _, s := range l.sinks {
+		if s.SampleRate < 0 || s.SampleRate > 1 {
+			problems = append(problems, s.Name+": SampleRate must be between 0 and 1")
+		}
+	}
+	if l.cfg.Variant != "" && (l.cfg.Variant == strVariant || !knownTag(l.cfg.Variant)) {
+		problems = append(problems, "unknown Variant tag ${"+l.cfg.Variant+"}")
+	}
+	switch l.cfg.SchemaVersion {
+	case "", SchemaV1, SchemaV2:
+	default:
+		problems = append(problems, "unknown SchemaVersion "+strconv.Quote(l.cfg.SchemaVersion))
+	}
+	if l.cfg.FirstNSampleRate < 0 || l.cfg.FirstNSampleRate > 1 {
+		problems = append(problems, "FirstNSampleRate must be between 0 and 1")
+	}
+	for _, b := range l.budgets {
+		if b.Route == "" {
+			problems = append(problems, "budget without Route")
+		}
+	}
+	for _, cp := range l.captures {
+		if cp.Path == "" || cp.Token == "" || cp.Output == nil {
+			problems = append(problems, "capture without Path, Token or Output")
+		}
+	}
+	// Write a test entry
+	fctx := &fasthttp.RequestCtx{}
+	fctx.Request.SetRequestURI("/logger/validate")
+	fctx.Request.Header.SetUserAgent("logger-validate")
+	c := fiber.AcquireCtx(fctx)
+	defer fiber.ReleaseCtx(c)
+	now := l.cfg.Clock.Now()
+	buf := l.buffer()
+	defer bytebufferpool.Put(buf)
+	var e *Entry
+	if l.entries {
+		e = l.newEntry(c, now, now, PriorityOK)
+		defer releaseEntry(e)
+	}
+	l.encode(buf, l.tmpl, l.cfg.Encoder, c, e, now, now)
+	if _, err := l.out.writeEntry(e, buf.Bytes()); err != nil {
+		problems = append(problems, l.out.name+": "+err.Error())
+	}
+	for _, s := range l.sinks {
+		buf.Reset()
+		l.encode(buf, s.tmpl, s.Encoder, c, e, now, now)
+		if _, err := s.out.writeEntry(e, buf.Bytes()); err != nil {
+			problems = append(problems, s.out.name+": "+err.Error())
+		}
+	}
+	if len(problems) > 0 {
+		return errors.New("logger: " + strings.Join(problems, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,37 @@
+package logger
+
+import (
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func TestLogger_Validate(t *testing.T) {
+	buf := &strings.Builder{}
+	l := NewLogger(Config{
+		Format: "${method} ${path} ${ua} ${route} ${ipHostname} ${header:x-id}\n",
+		Output: buf,
+	})
+	if err := l.Validate(); err != nil {
+		t.Errorf("Has: %+v, expected: nil", err)
+	}
+	expectedOutput := "GET /logger/validate logger-validate /logger/validate 0.0.0.0 \n"
+	if buf.String() != expectedOutput {
+		t.Errorf("Has: %s, expected: %s", buf.String(), expectedOutput)
+	}
+
+	l = NewLogger(Config{
+		Format:      "${stauts}",
+		Output:      failingWriter{},
+		Diagnostics: ioutil.Discard,
+	})
+	expected := "logger: unknown tag ${stauts}; output: disk full"
+	if err := l.Validate(); err == nil || err.Error() != expected {
+		t.Errorf("Has: %v, expected: %s", err, expected)
+	}
+
+	l = NewLogger(Config{Format: FormatJSON, SchemaVersion: "2", Output: ioutil.Discard, Diagnostics: ioutil.Discard})
+	if err := l.Validate(); err == nil || !strings.Contains(err.Error(), `unknown SchemaVersion "2"`) {
+		t.Errorf("Has: %v, expected: an error for the schema version", err)
+	}
+}
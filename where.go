@@ -0,0 +1,341 @@
+package logger
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Expr is a compiled filter expression over the fields of an entry, like
+//
+//	status >= 500 || latency > 2s && path =~ "^/api"
+//
+// Comparisons take a field on the left and a literal on the right: a
+// number, a duration like 150ms, a quoted string, true or false. =~ and !~
+// match a regular expression. A field alone is true when it is not empty,
+// "0" or "false". Comparisons combine with !, &&, || and parentheses, &&
+// binding tighter than ||. Fields are status, latency, method, path, route,
+// ip, priority, error and the tags of the entry like ua or header:x-tenant
+type Expr struct {
+	src  string
+	root exprNode
+	tags []string
+}
+
+// exprNode is a node of the syntax tree of an expression
+type exprNode interface {
+	match(e *Entry) bool
+}
+
+type (
+	exprOr  struct{ l, r exprNode }
+	exprAnd struct{ l, r exprNode }
+	exprNot struct{ n exprNode }
+	// exprTruth is a field alone
+	exprTruth struct{ field string }
+	// exprCmp compares a field to the literal of its kind
+	exprCmp struct {
+		field string
+		op    string
+		kind  byte // 'n'umber, 'd'uration, 's'tring or 'b'ool
+		num   float64
+		dur   time.Duration
+		str   string
+		re    *regexp.Regexp
+	}
+)
+
+func (n exprOr) match(e *Entry) bool  { return n.l.match(e) || n.r.match(e) }
+func (n exprAnd) match(e *Entry) bool { return n.l.match(e) && n.r.match(e) }
+func (n exprNot) match(e *Entry) bool { return !n.n.match(e) }
+
+func (n exprTruth) match(e *Entry) bool {
+	switch v := exprValue(e, n.field); v {
+	case "", "0", "false":
+		return false
+	}
+	return true
+}
+
+func (n *exprCmp) match(e *Entry) bool {
+	switch n.kind {
+	case 'd':
+		d := e.Latency
+		if n.field != "latency" {
+			var err error
+			if d, err = time.ParseDuration(strings.TrimSpace(exprValue(e, n.field))); err != nil {
+				return false
+			}
+		}
+		return compare(n.op, float64(d), float64(n.dur))
+	case 'n':
+		v, err := strconv.ParseFloat(strings.TrimSpace(exprValue(e, n.field)), 64)
+		return err == nil && compare(n.op, v, n.num)
+	case 'b':
+		v, err := strconv.ParseBool(exprValue(e, n.field))
+		return err == nil && (v == (n.str == "true")) == (n.op == "==")
+	}
+	v := exprValue(e, n.field)
+	switch n.op {
+	case "=~":
+		return n.re.MatchString(v)
+	case "!~":
+		return !n.re.MatchString(v)
+	case "==":
+		return v == n.str
+	case "!=":
+		return v != n.str
+	case "<":
+		return v < n.str
+	case "<=":
+		return v <= n.str
+	case ">":
+		return v > n.str
+	}
+	return v >= n.str
+}
+
+// compare applies a numeric comparison operator
+func compare(op string, a, b float64) bool {
+	switch op {
+	case "==":
+		return a == b
+	case "!=":
+		return a != b
+	case "<":
+		return a < b
+	case "<=":
+		return a <= b
+	case ">":
+		return a > b
+	}
+	return a >= b
+}
+
+// exprCore are the fields taken from the entry rather than its tags
+var exprCore = map[string]bool{
+	"status": true, "latency": true, "method": true, "path": true,
+	"route": true, "ip": true, "priority": true, "error": true,
+}
+
+// exprValue returns the value of field in e as a string
+func exprValue(e *Entry, field string) string {
+	switch field {
+	case "latency":
+		return e.Latency.String()
+	case "path":
+		return e.Path
+	case "ip":
+		return e.IP
+	case "error":
+		if e.Err == nil {
+			return ""
+		}
+		return e.Err.Error()
+	}
+	return entryValue(e, field)
+}
+
+// Compile parses an expression, see Expr
+func Compile(src string) (*Expr, error) {
+	p := &exprParser{src: src}
+	p.next()
+	root, err := p.or()
+	if err == nil && p.tok != "" {
+		err = p.errorf("unexpected %q", p.tok)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &Expr{src: src, root: root, tags: p.tags}, nil
+}
+
+// MustCompile is like Compile but panics on errors
+func MustCompile(src string) *Expr {
+	x, err := Compile(src)
+	if err != nil {
+		panic(err)
+	}
+	return x
+}
+
+// Match reports whether e matches the expression
+func (x *Expr) Match(e *Entry) bool {
+	return x.root.match(e)
+}
+
+// Tags returns the tags the expression reads, so they are in the entry
+func (x *Expr) Tags() []string {
+	return x.tags
+}
+
+// String returns the source of the expression
+func (x *Expr) String() string {
+	return x.src
+}
+
+// Process keeps the entries matching the expression, so an Expr is a
+// Processor
+func (x *Expr) Process(e *Entry) bool {
+	return x.Match(e)
+}
+
+// Where returns a processor keeping the entries matching expr
+func Where(expr string) (Processor, error) {
+	return Compile(expr)
+}
+
+// where prepends the compiled expr to processors, panicking on an invalid
+// expression like other configuration errors
+func where(expr string, processors []Processor) []Processor {
+	if expr == "" {
+		return processors
+	}
+	return append([]Processor{MustCompile(expr)}, processors...)
+}
+
+// exprParser is a recursive descent parser, tok is the current token and
+// "" at the end
+type exprParser struct {
+	src  string
+	pos  int
+	tok  string
+	tags []string
+}
+
+// next reads the next token
+func (p *exprParser) next() {
+	s := p.src
+	for p.pos < len(s) && (s[p.pos] == ' ' || s[p.pos] == '\t' || s[p.pos] == '\n') {
+		p.pos++
+	}
+	start := p.pos
+	switch {
+	case p.pos == len(s):
+	case s[p.pos] == '"':
+		for p.pos++; p.pos < len(s) && s[p.pos] != '"'; p.pos++ {
+			if s[p.pos] == '\\' {
+				p.pos++
+			}
+		}
+		p.pos++
+	case strings.IndexByte("()", s[p.pos]) >= 0:
+		p.pos++
+	case strings.IndexByte("!=<>&|~", s[p.pos]) >= 0:
+		for p.pos++; p.pos < len(s) && strings.IndexByte("=&|~", s[p.pos]) >= 0; p.pos++ {
+		}
+	default:
+		for ; p.pos < len(s) && strings.IndexByte(" \t\n()!=<>&|~\"", s[p.pos]) < 0; p.pos++ {
+		}
+	}
+	if p.pos > len(s) {
+		p.pos = len(s)
+	}
+	p.tok = s[start:p.pos]
+}
+
+func (p *exprParser) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("logger: expression %q at %d: %s", p.src, p.pos, fmt.Sprintf(format, args...))
+}
+
+func (p *exprParser) or() (exprNode, error) {
+	n, err := p.and()
+	for err == nil && p.tok == "||" {
+		p.next()
+		var r exprNode
+		if r, err = p.and(); err == nil {
+			n = exprOr{n, r}
+		}
+	}
+	return n, err
+}
+
+func (p *exprParser) and() (exprNode, error) {
+	n, err := p.unary()
+	for err == nil && p.tok == "&&" {
+		p.next()
+		var r exprNode
+		if r, err = p.unary(); err == nil {
+			n = exprAnd{n, r}
+		}
+	}
+	return n, err
+}
+
+func (p *exprParser) unary() (exprNode, error) {
+	switch p.tok {
+	case "!":
+		p.next()
+		n, err := p.unary()
+		return exprNot{n}, err
+	case "(":
+		p.next()
+		n, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.tok != ")" {
+			return nil, p.errorf("expected )")
+		}
+		p.next()
+		return n, nil
+	}
+	return p.comparison()
+}
+
+func (p *exprParser) comparison() (exprNode, error) {
+	field := p.tok
+	if field == "" || strings.IndexByte("()!=<>&|~\"", field[0]) >= 0 || field[0] >= '0' && field[0] <= '9' {
+		return nil, p.errorf("expected a field, found %q", field)
+	}
+	if !exprCore[field] {
+		p.tags = append(p.tags, field)
+	}
+	p.next()
+	op := p.tok
+	switch op {
+	case "==", "!=", "<", "<=", ">", ">=", "=~", "!~":
+	default:
+		return exprTruth{field}, nil
+	}
+	p.next()
+	lit := p.tok
+	if lit == "" {
+		return nil, p.errorf("expected a value after %s", op)
+	}
+	p.next()
+	n := &exprCmp{field: field, op: op}
+	switch {
+	case lit[0] == '"':
+		s, err := strconv.Unquote(lit)
+		if err != nil {
+			return nil, p.errorf("invalid string %s", lit)
+		}
+		n.kind, n.str = 's', s
+		if op == "=~" || op == "!~" {
+			if n.re, err = regexp.Compile(s); err != nil {
+				return nil, p.errorf("%v", err)
+			}
+		}
+		return n, nil
+	case op == "=~" || op == "!~":
+		return nil, p.errorf("expected a quoted regular expression after %s", op)
+	case lit == "true" || lit == "false":
+		if op != "==" && op != "!=" {
+			return nil, p.errorf("%s compares no booleans", op)
+		}
+		n.kind, n.str = 'b', lit
+		return n, nil
+	}
+	if num, err := strconv.ParseFloat(lit, 64); err == nil {
+		n.kind, n.num = 'n', num
+		return n, nil
+	}
+	if d, err := time.ParseDuration(lit); err == nil {
+		n.kind, n.dur = 'd', d
+		return n, nil
+	}
+	return nil, p.errorf("invalid value %q, strings are quoted", lit)
+}
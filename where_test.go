@@ -0,0 +1,111 @@
+package logger
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber"
+)
+
+func TestCompile(t *testing.T) {
+	e := &Entry{Method: "GET", Path: "/api/users", Route: "/api/users", Status: 200,
+		Latency: 3 * time.Second, IP: "203.0.113.7", Priority: PrioritySlow,
+		Fields: []Field{{"ua", "curl/7.68"}, {"bytesSent", 1024}, {"header:x-debug", "true"}}}
+	for expr, expected := range map[string]bool{
+		`status >= 500 || latency > 2s && path =~ "^/api"`:   true,
+		`status >= 500 || latency > 5s && path =~ "^/api"`:   false,
+		`(status >= 500 || latency > 2s) && path !~ "^/api"`: false,
+		`method == "GET" && !(ip == "10.0.0.1")`:             true,
+		`priority == "slow"`:                                 true,
+		`bytesSent > 1000 && ua =~ "^curl/"`:                 true,
+		`bytesSent < 1000`:                                   false,
+		`header:x-debug == true`:                             true,
+		`header:x-debug`:                                     true,
+		`header:x-missing`:                                   false,
+		`error`:                                              false,
+		`status!=200||route=="/health"`:                      false,
+	} {
+		x, err := Compile(expr)
+		if err != nil {
+			t.Errorf("Has: %v, expected: nil for %s", err, expr)
+			continue
+		}
+		if x.Match(e) != expected {
+			t.Errorf("Has: %v, expected: %v for %s", !expected, expected, expr)
+		}
+	}
+	e.Err = errors.New("timeout")
+	if !MustCompile(`error =~ "timeout"`).Match(e) {
+		t.Errorf("Has: false, expected: error matched")
+	}
+}
+
+func TestCompile_errors(t *testing.T) {
+	for _, expr := range []string{
+		``,
+		`status >=`,
+		`status >= 500 ||`,
+		`(status >= 500`,
+		`status >= 500)`,
+		`path == /api`,
+		`path =~ "("`,
+		`path =~ 5`,
+		`ok < true`,
+		`500 == status`,
+	} {
+		if _, err := Compile(expr); err == nil {
+			t.Errorf("Has: nil, expected: an error for %s", expr)
+		}
+	}
+}
+
+func TestCompile_tags(t *testing.T) {
+	x := MustCompile(`status >= 500 && ua =~ "bot" || header:x-tenant == "a"`)
+	if tags := strings.Join(x.Tags(), ","); tags != "ua,header:x-tenant" {
+		t.Errorf("Has: %s, expected: ua,header:x-tenant", tags)
+	}
+}
+
+func TestLogger_Where(t *testing.T) {
+	access, debug := &strings.Builder{}, &strings.Builder{}
+	app := fiber.New()
+	app.Use(New(Config{
+		Format: "${path} ",
+		Output: access,
+		Where:  `path != "/health"`,
+		Sinks:  []SinkConfig{{Format: "${path} ", Output: debug, Where: `status >= 400 || header:x-debug`}},
+	}))
+	app.Get("/*", func(ctx *fiber.Ctx) {
+		if ctx.Path() == "/missing" {
+			ctx.SendStatus(404)
+		}
+	})
+	for _, path := range []string{"/api/a", "/health", "/missing", "/debug"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if path == "/debug" {
+			req.Header.Set("X-Debug", "1")
+		}
+		if _, err := app.Test(req, 1000); err != nil {
+			t.Errorf("Has: %+v, expected: nil", err)
+		}
+	}
+	if access.String() != "/api/a /missing /debug " {
+		t.Errorf("Has: %q, expected: all but /health", access.String())
+	}
+	if debug.String() != "/missing /debug " {
+		t.Errorf("Has: %q, expected: /missing /debug", debug.String())
+	}
+}
+
+func TestLogger_WhereInvalid(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Has: nil, expected: a panic")
+		}
+	}()
+	New(Config{Where: `status >>= 500`})
+}